@@ -0,0 +1,201 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"github.com/Fisher-Development/woman-app-backend/api"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+	"github.com/Fisher-Development/woman-app-backend/internal/validator"
+)
+
+var (
+	// ErrAPIKeyNotFound API ключ не найден или уже отозван.
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrScopeNotAllowed у пользователя нет роли, которую он пытается выдать ключу.
+	ErrScopeNotAllowed = errors.New("scope not allowed")
+	// ErrTooManyAPIKeys достигнут лимит действующих ключей пользователя.
+	ErrTooManyAPIKeys = errors.New("too many api keys")
+	// ErrInvalidExpiration срок действия ключа уже прошел.
+	ErrInvalidExpiration = errors.New("expiration must be in the future")
+)
+
+type IAPIKeyService interface {
+	CreateAPIKey(ctx context.Context, userID types.UserID, req CreateRequest) (*CreateResponse, error)
+	ListAPIKeys(ctx context.Context, userID types.UserID) ([]models.APIKey, error)
+	UpdateAPIKeyScopes(ctx context.Context, userID types.UserID, keyID string, req ScopesRequest) (*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID types.UserID, keyID string) error
+}
+
+// Структура для создания API ключа.
+type CreateRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+	// Scopes роли realm пользователя, которые получит ключ (пусто - без ролей).
+	Scopes    []string   `json:"scopes" validate:"dive,required"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// Структура с созданным API ключом. Key показывается только один раз.
+type CreateResponse struct {
+	APIKey models.APIKey `json:"apiKey"`
+	Key    string        `json:"key"`
+}
+
+// Структура для изменения ролей API ключа.
+type ScopesRequest struct {
+	Scopes []string `json:"scopes" validate:"dive,required"`
+}
+
+// Create хендлер для создания API ключа текущего пользователя.
+func Create(svc IAPIKeyService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		var req CreateRequest
+		if !decodeAndValidate(w, r, &req) {
+			return
+		}
+
+		created, err := svc.CreateAPIKey(r.Context(), userID, req)
+		if err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, created)
+	}
+}
+
+// List хендлер для получения действующих API ключей текущего пользователя.
+func List(svc IAPIKeyService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		keys, err := svc.ListAPIKeys(r.Context(), userID)
+		if err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+		if keys == nil {
+			keys = []models.APIKey{}
+		}
+
+		api.RespondOK(w, r, keys)
+	}
+}
+
+// UpdateScopes хендлер для изменения ролей API ключа.
+func UpdateScopes(svc IAPIKeyService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		var req ScopesRequest
+		if !decodeAndValidate(w, r, &req) {
+			return
+		}
+
+		key, err := svc.UpdateAPIKeyScopes(r.Context(), userID, chi.URLParam(r, "keyID"), req)
+		if err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		api.RespondOK(w, r, key)
+	}
+}
+
+// Revoke хендлер для отзыва API ключа.
+func Revoke(svc IAPIKeyService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		if err := svc.RevokeAPIKey(r.Context(), userID, chi.URLParam(r, "keyID")); err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		api.RespondOK(w, r, map[string]string{"status": "revoked"})
+	}
+}
+
+func currentUser(w http.ResponseWriter, r *http.Request) (types.UserID, bool) {
+	userID, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		api.RespondError(w, r, http.StatusUnauthorized, api.ErrorInfo{
+			Code:    api.ErrCodeUnauthorized,
+			Message: "User not found keycloak",
+		})
+	}
+	return userID, ok
+}
+
+func decodeAndValidate(w http.ResponseWriter, r *http.Request, req any) bool {
+	if err := render.DecodeJSON(r.Body, req); err != nil {
+		api.RespondError(w, r, http.StatusBadRequest, api.ErrorInfo{
+			Code:    api.ErrCodeBadRequest,
+			Message: "Invalid request",
+		})
+		return false
+	}
+
+	if err := validator.Validator.Struct(req); err != nil {
+		api.RespondError(w, r, http.StatusBadRequest, api.ErrorInfo{
+			Code:    api.ErrCodeValidationFailed,
+			Message: err.Error(),
+		})
+		return false
+	}
+
+	return true
+}
+
+// respondServiceError преобразует ошибки сервиса в HTTP ответ.
+func respondServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrAPIKeyNotFound):
+		api.RespondError(w, r, http.StatusNotFound, api.ErrorInfo{
+			Code:    api.ErrCodeNotFound,
+			Message: "API key not found",
+		})
+	case errors.Is(err, ErrScopeNotAllowed):
+		api.RespondError(w, r, http.StatusForbidden, api.ErrorInfo{
+			Code:    api.ErrCodeForbidden,
+			Message: "Scope is not granted to the user",
+		})
+	case errors.Is(err, ErrTooManyAPIKeys):
+		api.RespondError(w, r, http.StatusConflict, api.ErrorInfo{
+			Code:    api.ErrCodeConflict,
+			Message: "Too many API keys",
+		})
+	case errors.Is(err, ErrInvalidExpiration):
+		api.RespondError(w, r, http.StatusBadRequest, api.ErrorInfo{
+			Code:    api.ErrCodeValidationFailed,
+			Message: "Expiration must be in the future",
+		})
+	default:
+		api.RespondError(w, r, http.StatusInternalServerError, api.ErrorInfo{
+			Code:    api.ErrCodeInternalServer,
+			Message: "Internal server error",
+		})
+	}
+}
@@ -5,13 +5,23 @@ import (
 	"net/http"
 
 	"github.com/Fisher-Development/woman-app-backend/api"
-	"github.com/go-chi/render"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
 )
 
 type IAuthService interface {
 	RegisterUser(ctx context.Context, req RegisterRequest) error
 	LoginUser(ctx context.Context, req LoginRequest) (*LoginResponse, error)
+	StartAuthorization(ctx context.Context, req AuthorizeRequest) (*AuthorizeResponse, error)
+	CompleteAuthorization(ctx context.Context, req CallbackRequest) (*LoginResponse, error)
 	RefreshAccessToken(ctx context.Context, refreshToken string) (*LoginResponse, error)
+	Logout(ctx context.Context, refreshToken string) error
+	LogoutAll(ctx context.Context, userID types.UserID) error
+	ForgotPassword(ctx context.Context, req ForgotPasswordRequest) error
+	ChangePassword(ctx context.Context, userID types.UserID, req ChangePasswordRequest) error
+	SetTemporaryPassword(ctx context.Context, userID types.UserID, req TemporaryPasswordRequest) error
+	ResendVerificationEmail(ctx context.Context, userID types.UserID) error
+	ClearLoginLockout(ctx context.Context, actor types.UserID, req ClearLoginLockoutRequest) error
 }
 
 // Структура для регистрации.
@@ -26,6 +36,8 @@ type RegisterRequest struct {
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
+	// ClientIP адрес клиента для защиты от перебора, заполняется хендлером.
+	ClientIP string `json:"-"`
 }
 
 // Структура для ответа при логине.
@@ -35,25 +47,24 @@ type LoginResponse struct {
 	ExpiresIn    int    `json:"expiresIn"`
 }
 
+// Структура для ответа при входе в режиме cookie сессии: токены только в HttpOnly cookie.
+type SessionResponse struct {
+	Status    string `json:"status"`
+	ExpiresIn int    `json:"expiresIn"`
+	// CSRFToken нужно передавать в заголовке CSRF во всех небезопасных запросах.
+	CSRFToken string `json:"csrfToken,omitempty"`
+}
+
 // Register хендлер для регистрации.
 func Register(authService IAuthService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req RegisterRequest
-
-		if err := render.DecodeJSON(r.Body, &req); err != nil {
-			api.RespondError(w, r, http.StatusBadRequest, api.ErrorInfo{
-				Code:    api.ErrCodeBadRequest,
-				Message: err.Error(),
-			})
+		if !decodeAndValidate(w, r, &req) {
 			return
 		}
 
 		if err := authService.RegisterUser(r.Context(), req); err != nil {
-			// Handle different error types
-			api.RespondError(w, r, http.StatusBadRequest, api.ErrorInfo{
-				Code:    api.ErrCodeBadRequest,
-				Message: err.Error(),
-			})
+			respondServiceError(w, r, err)
 			return
 		}
 
@@ -61,28 +72,38 @@ func Register(authService IAuthService) http.HandlerFunc {
 	}
 }
 
-// Login хендлер для логина.
-func Login(authService IAuthService) http.HandlerFunc {
+// Login хендлер для логина. Если заданы cookies, токены устанавливаются в cookie сессии.
+func Login(authService IAuthService, cookies *middlewares.SessionCookies) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req LoginRequest
-
-		if err := render.DecodeJSON(r.Body, &req); err != nil {
-			api.RespondError(w, r, http.StatusBadRequest, api.ErrorInfo{
-				Code:    api.ErrCodeBadRequest,
-				Message: err.Error(),
-			})
+		if !decodeAndValidate(w, r, &req) {
 			return
 		}
+		req.ClientIP = clientIP(r)
 
 		response, err := authService.LoginUser(r.Context(), req)
 		if err != nil {
-			api.RespondError(w, r, http.StatusUnauthorized, api.ErrorInfo{
-				Code:    api.ErrCodeUnauthorized,
-				Message: err.Error(),
-			})
+			respondServiceError(w, r, err)
 			return
 		}
 
+		if cookies != nil {
+			csrfToken, err := startSession(w, cookies, response)
+			if err != nil {
+				respondServiceError(w, r, err)
+				return
+			}
+			api.RespondOK(w, r, SessionResponse{Status: "logged_in", ExpiresIn: response.ExpiresIn, CSRFToken: csrfToken})
+			return
+		}
 		api.RespondOK(w, r, response)
 	}
 }
+
+// startSession устанавливает cookie новой сессии и возвращает новый CSRF токен.
+func startSession(w http.ResponseWriter, cookies *middlewares.SessionCookies, tokens *LoginResponse) (string, error) {
+	if err := cookies.SetTokens(w, tokens.AccessToken, tokens.RefreshToken, tokens.ExpiresIn); err != nil {
+		return "", err
+	}
+	return cookies.IssueCSRFToken(w)
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/Fisher-Development/woman-app-backend/api"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+)
+
+// stateCookieName cookie, связывающая state запроса авторизации с браузером, который его начал.
+const stateCookieName = "auth_state"
+
+// TokenDelivery способ передачи токенов клиенту после входа через authorization code flow.
+type TokenDelivery string
+
+const (
+	// TokenDeliveryBody токены возвращаются в теле ответа, как при входе по паролю.
+	TokenDeliveryBody TokenDelivery = "body"
+	// TokenDeliveryCookie токены устанавливаются в зашифрованные cookie сессии.
+	TokenDeliveryCookie TokenDelivery = "cookie"
+)
+
+// AuthorizationCodeOptions настройки хендлеров authorization code flow.
+type AuthorizationCodeOptions struct {
+	TokenDelivery TokenDelivery
+	// Cookies cookie сессии, обязательны для TokenDeliveryCookie.
+	Cookies *middlewares.SessionCookies
+	// PostLoginRedirectURL куда вернуть браузер после входа в режиме cookie (пусто - ответить JSON).
+	PostLoginRedirectURL string
+}
+
+// Структура для начала входа через Keycloak.
+type AuthorizeRequest struct {
+	// LoginHint подставляется в форму входа Keycloak.
+	LoginHint string
+}
+
+// Структура с адресом страницы входа Keycloak.
+type AuthorizeResponse struct {
+	URL       string
+	State     string
+	ExpiresAt time.Time
+}
+
+// Структура с параметрами, с которыми Keycloak вернул пользователя.
+type CallbackRequest struct {
+	State            string
+	Code             string
+	Error            string
+	ErrorDescription string
+}
+
+// Authorize хендлер, перенаправляющий браузер на страницу входа Keycloak.
+func Authorize(authService IAuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorization, err := authService.StartAuthorization(r.Context(), AuthorizeRequest{
+			LoginHint: r.URL.Query().Get("login_hint"),
+		})
+		if err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		// SameSite=Lax: cookie должна прийти в callback при переходе со страницы Keycloak
+		http.SetCookie(w, &http.Cookie{
+			Name:     stateCookieName,
+			Value:    authorization.State,
+			Path:     "/",
+			Expires:  authorization.ExpiresAt,
+			Secure:   true,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, authorization.URL, http.StatusFound)
+	}
+}
+
+// Callback хендлер, принимающий пользователя после входа в Keycloak и обменивающий code на токены.
+func Callback(authService IAuthService, opts AuthorizationCodeOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		req := CallbackRequest{
+			State:            query.Get("state"),
+			Code:             query.Get("code"),
+			Error:            query.Get("error"),
+			ErrorDescription: query.Get("error_description"),
+		}
+
+		// state одноразовый, поэтому cookie больше не нужна при любом исходе
+		http.SetCookie(w, &http.Cookie{
+			Name:     stateCookieName,
+			Path:     "/",
+			MaxAge:   -1,
+			Secure:   true,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		// Callback должен прийти из того же браузера, что начал вход, иначе это подмена сессии (login CSRF)
+		cookie, err := r.Cookie(stateCookieName)
+		if err != nil || req.State == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(req.State)) != 1 {
+			respondServiceError(w, r, ErrInvalidAuthorizationState)
+			return
+		}
+
+		tokens, err := authService.CompleteAuthorization(r.Context(), req)
+		if err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		if opts.TokenDelivery != TokenDeliveryCookie || opts.Cookies == nil {
+			api.RespondOK(w, r, tokens)
+			return
+		}
+
+		csrfToken, err := startSession(w, opts.Cookies, tokens)
+		if err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+		if opts.PostLoginRedirectURL != "" {
+			http.Redirect(w, r, opts.PostLoginRedirectURL, http.StatusFound)
+			return
+		}
+		api.RespondOK(w, r, SessionResponse{Status: "logged_in", ExpiresIn: tokens.ExpiresIn, CSRFToken: csrfToken})
+	}
+}
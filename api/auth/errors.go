@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Fisher-Development/woman-app-backend/api"
+)
+
+var (
+	// ErrInvalidCurrentPassword текущий пароль указан неверно.
+	ErrInvalidCurrentPassword = errors.New("invalid current password")
+	// ErrUserNotFound пользователь не найден.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrEmailAlreadyVerified email пользователя уже подтвержден.
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+	// ErrInvalidCredentials неверный email или пароль.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrUserDisabled учетная запись пользователя отключена.
+	ErrUserDisabled = errors.New("user disabled")
+	// ErrUserAlreadyExists пользователь с таким email уже зарегистрирован.
+	ErrUserAlreadyExists = errors.New("user already exists")
+	// ErrInvalidRefreshToken refresh token недействителен, истек или отозван.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrAuthorizationCodeDisabled вход через authorization code flow не настроен.
+	ErrAuthorizationCodeDisabled = errors.New("authorization code flow disabled")
+	// ErrInvalidAuthorizationState state запроса авторизации неизвестен, истек, уже использован или пришел из другого браузера.
+	ErrInvalidAuthorizationState = errors.New("invalid authorization state")
+	// ErrInvalidAuthorizationCode authorization code недействителен или не прошел проверку PKCE.
+	ErrInvalidAuthorizationCode = errors.New("invalid authorization code")
+	// ErrAuthorizationDenied пользователь не вошел или отказался от входа на странице Keycloak.
+	ErrAuthorizationDenied = errors.New("authorization denied")
+	// ErrLoginProtectionDisabled защита входа от перебора не настроена.
+	ErrLoginProtectionDisabled = errors.New("login protection disabled")
+	// ErrServiceUnavailable сервер авторизации временно недоступен.
+	ErrServiceUnavailable = errors.New("authorization server unavailable")
+)
+
+// TooManyAttemptsError превышен лимит попыток.
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return fmt.Sprintf("too many attempts, retry after %s", e.RetryAfter)
+}
+
+// respondServiceError преобразует ошибки сервиса в HTTP ответ, не раскрывая детали Keycloak.
+func respondServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooManyAttempts *TooManyAttemptsError
+
+	switch {
+	case errors.As(err, &tooManyAttempts):
+		seconds := int(math.Ceil(tooManyAttempts.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		api.RespondError(w, r, http.StatusTooManyRequests, api.ErrorInfo{
+			Code:    api.ErrCodeTooManyRequests,
+			Message: "Too many attempts",
+		})
+	case errors.Is(err, ErrInvalidCurrentPassword):
+		api.RespondError(w, r, http.StatusForbidden, api.ErrorInfo{
+			Code:    api.ErrCodeForbidden,
+			Message: "Invalid current password",
+		})
+	case errors.Is(err, ErrEmailAlreadyVerified):
+		api.RespondError(w, r, http.StatusConflict, api.ErrorInfo{
+			Code:    api.ErrCodeConflict,
+			Message: "Email already verified",
+		})
+	case errors.Is(err, ErrUserNotFound):
+		api.RespondError(w, r, http.StatusNotFound, api.ErrorInfo{
+			Code:    api.ErrCodeNotFound,
+			Message: "User not found",
+		})
+	case errors.Is(err, ErrInvalidCredentials):
+		api.RespondError(w, r, http.StatusUnauthorized, api.ErrorInfo{
+			Code:    api.ErrCodeUnauthorized,
+			Message: "Invalid email or password",
+		})
+	case errors.Is(err, ErrInvalidRefreshToken):
+		api.RespondError(w, r, http.StatusUnauthorized, api.ErrorInfo{
+			Code:    api.ErrCodeUnauthorized,
+			Message: "Invalid refresh token",
+		})
+	case errors.Is(err, ErrUserDisabled):
+		api.RespondError(w, r, http.StatusForbidden, api.ErrorInfo{
+			Code:    api.ErrCodeUserDisabled,
+			Message: "User account is disabled",
+		})
+	case errors.Is(err, ErrUserAlreadyExists):
+		api.RespondError(w, r, http.StatusConflict, api.ErrorInfo{
+			Code:    api.ErrCodeConflict,
+			Message: "User already exists",
+		})
+	case errors.Is(err, ErrInvalidAuthorizationState):
+		api.RespondError(w, r, http.StatusBadRequest, api.ErrorInfo{
+			Code:    api.ErrCodeBadRequest,
+			Message: "Invalid or expired authorization state",
+		})
+	case errors.Is(err, ErrInvalidAuthorizationCode):
+		api.RespondError(w, r, http.StatusBadRequest, api.ErrorInfo{
+			Code:    api.ErrCodeBadRequest,
+			Message: "Invalid authorization code",
+		})
+	case errors.Is(err, ErrAuthorizationDenied):
+		api.RespondError(w, r, http.StatusUnauthorized, api.ErrorInfo{
+			Code:    api.ErrCodeUnauthorized,
+			Message: "Authorization denied",
+		})
+	case errors.Is(err, ErrAuthorizationCodeDisabled):
+		api.RespondError(w, r, http.StatusNotFound, api.ErrorInfo{
+			Code:    api.ErrCodeNotFound,
+			Message: "Authorization code flow is disabled",
+		})
+	case errors.Is(err, ErrLoginProtectionDisabled):
+		api.RespondError(w, r, http.StatusNotFound, api.ErrorInfo{
+			Code:    api.ErrCodeNotFound,
+			Message: "Login protection is disabled",
+		})
+	case errors.Is(err, ErrServiceUnavailable):
+		api.RespondError(w, r, http.StatusServiceUnavailable, api.ErrorInfo{
+			Code:    api.ErrCodeServiceUnavailable,
+			Message: "Service temporarily unavailable",
+		})
+	default:
+		api.RespondError(w, r, http.StatusInternalServerError, api.ErrorInfo{
+			Code:    api.ErrCodeInternalServer,
+			Message: "Internal server error",
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/Fisher-Development/woman-app-backend/api"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+)
+
+// Структура для снятия блокировки входа администратором.
+type ClearLoginLockoutRequest struct {
+	Email string `json:"email" validate:"required_without=IP,omitempty,email"`
+	IP    string `json:"ip" validate:"omitempty,ip"`
+}
+
+// ClearLoginLockout хендлер для снятия блокировки входа по email и (или) IP адресу (только для администраторов).
+func ClearLoginLockout(authService IAuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor, ok := middlewares.GetUserFromContext(r.Context())
+		if !ok {
+			api.RespondError(w, r, http.StatusUnauthorized, api.ErrorInfo{
+				Code:    api.ErrCodeUnauthorized,
+				Message: "User not found keycloak",
+			})
+			return
+		}
+
+		var req ClearLoginLockoutRequest
+		if !decodeAndValidate(w, r, &req) {
+			return
+		}
+
+		if err := authService.ClearLoginLockout(r.Context(), actor, req); err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		api.RespondOK(w, r, map[string]string{"status": "cleared"})
+	}
+}
+
+// clientIP возвращает IP адрес клиента. Адрес из X-Forwarded-For и X-Real-IP доверенного
+// прокси уже подставлен в RemoteAddr middleware middlewares.RealIP роутера.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
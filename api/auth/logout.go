@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Fisher-Development/woman-app-backend/api"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	"github.com/go-chi/render"
+)
+
+// Структура для выхода из сессии.
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+// Logout хендлер для завершения текущей сессии. Если заданы cookies и запрос пришел
+// с cookie сессии, refresh token берется из cookie, а cookie удаляются.
+func Logout(authService IAuthService, cookies *middlewares.SessionCookies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req LogoutRequest
+		session := cookies != nil && cookies.HasSession(r)
+		if session {
+			refreshToken, ok := sessionRefreshToken(w, r, cookies)
+			if !ok {
+				return
+			}
+			req.RefreshToken = refreshToken
+		} else if err := render.DecodeJSON(r.Body, &req); err != nil || req.RefreshToken == "" {
+			api.RespondError(w, r, http.StatusBadRequest, api.ErrorInfo{
+				Code:    api.ErrCodeBadRequest,
+				Message: "Invalid request",
+			})
+			return
+		}
+
+		if err := authService.Logout(r.Context(), req.RefreshToken); err != nil {
+			if session && !errors.Is(err, ErrServiceUnavailable) {
+				cookies.Clear(w)
+			}
+			respondServiceError(w, r, err)
+			return
+		}
+
+		if session {
+			cookies.Clear(w)
+		}
+		api.RespondOK(w, r, map[string]string{"status": "logged_out"})
+	}
+}
+
+// LogoutAll хендлер для завершения всех сессий пользователя.
+func LogoutAll(authService IAuthService, cookies *middlewares.SessionCookies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// получаем UUID из контекста
+		userID, ok := middlewares.GetUserFromContext(r.Context())
+		if !ok {
+			api.RespondError(w, r, http.StatusUnauthorized, api.ErrorInfo{
+				Code:    api.ErrCodeUnauthorized,
+				Message: "User not found keycloak",
+			})
+			return
+		}
+
+		if err := authService.LogoutAll(r.Context(), userID); err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		if cookies != nil && cookies.HasSession(r) {
+			cookies.Clear(w)
+		}
+
+		api.RespondOK(w, r, map[string]string{"status": "logged_out"})
+	}
+}
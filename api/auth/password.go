@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/Fisher-Development/woman-app-backend/api"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+	"github.com/Fisher-Development/woman-app-backend/internal/validator"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// Структура для запроса письма о сбросе пароля.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// Структура для смены пароля.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,min=8,nefield=CurrentPassword"`
+}
+
+// Структура для установки временного пароля администратором.
+type TemporaryPasswordRequest struct {
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+// ForgotPassword хендлер для отправки письма со ссылкой на сброс пароля.
+// Ответ не зависит от того, зарегистрирован ли email.
+func ForgotPassword(authService IAuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ForgotPasswordRequest
+		if !decodeAndValidate(w, r, &req) {
+			return
+		}
+
+		if err := authService.ForgotPassword(r.Context(), req); err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		render.Status(r, http.StatusAccepted)
+		render.JSON(w, r, map[string]string{"status": "accepted"})
+	}
+}
+
+// ChangePassword хендлер для смены пароля текущего пользователя.
+func ChangePassword(authService IAuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middlewares.GetUserFromContext(r.Context())
+		if !ok {
+			api.RespondError(w, r, http.StatusUnauthorized, api.ErrorInfo{
+				Code:    api.ErrCodeUnauthorized,
+				Message: "User not found keycloak",
+			})
+			return
+		}
+
+		var req ChangePasswordRequest
+		if !decodeAndValidate(w, r, &req) {
+			return
+		}
+
+		if err := authService.ChangePassword(r.Context(), userID, req); err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		api.RespondOK(w, r, map[string]string{"status": "password_changed"})
+	}
+}
+
+// SetTemporaryPassword хендлер для установки временного пароля пользователю (только для администраторов).
+func SetTemporaryPassword(authService IAuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := types.Parse[types.UserID](chi.URLParam(r, "userID"))
+		if err != nil {
+			api.RespondError(w, r, http.StatusBadRequest, api.ErrorInfo{
+				Code:    api.ErrCodeBadRequest,
+				Message: "Invalid user ID",
+			})
+			return
+		}
+
+		var req TemporaryPasswordRequest
+		if !decodeAndValidate(w, r, &req) {
+			return
+		}
+
+		if err := authService.SetTemporaryPassword(r.Context(), userID, req); err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		api.RespondOK(w, r, map[string]string{"status": "temporary_password_set"})
+	}
+}
+
+// decodeAndValidate декодирует тело запроса и проверяет его по тегам validate.
+func decodeAndValidate(w http.ResponseWriter, r *http.Request, req any) bool {
+	if err := render.DecodeJSON(r.Body, req); err != nil {
+		api.RespondError(w, r, http.StatusBadRequest, api.ErrorInfo{
+			Code:    api.ErrCodeBadRequest,
+			Message: "Invalid request",
+		})
+		return false
+	}
+
+	if err := validator.Validator.Struct(req); err != nil {
+		api.RespondError(w, r, http.StatusBadRequest, api.ErrorInfo{
+			Code:    api.ErrCodeValidationFailed,
+			Message: err.Error(),
+		})
+		return false
+	}
+
+	return true
+}
@@ -1,21 +1,31 @@
 package auth
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/Fisher-Development/woman-app-backend/api"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
 	"github.com/go-chi/render"
 )
 
-// RefreshToken.
-func RefreshToken(authService IAuthService) http.HandlerFunc {
+// RefreshToken. Если заданы cookies и запрос пришел с cookie сессии, refresh token
+// берется из cookie, а запрос должен содержать CSRF токен. Иначе refresh token передается в теле.
+func RefreshToken(authService IAuthService, cookies *middlewares.SessionCookies) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		type RefreshRequest struct {
 			RefreshToken string `json:"refreshToken"`
 		}
 
 		var req RefreshRequest
-		if err := render.DecodeJSON(r.Body, &req); err != nil {
+		session := cookies != nil && cookies.HasSession(r)
+		if session {
+			refreshToken, ok := sessionRefreshToken(w, r, cookies)
+			if !ok {
+				return
+			}
+			req.RefreshToken = refreshToken
+		} else if err := render.DecodeJSON(r.Body, &req); err != nil {
 			api.RespondError(w, r, http.StatusBadRequest, api.ErrorInfo{
 				Code:    api.ErrCodeBadRequest,
 				Message: "Invalid request",
@@ -26,13 +36,42 @@ func RefreshToken(authService IAuthService) http.HandlerFunc {
 		// Обновляем токен через Keycloak
 		tokens, err := authService.RefreshAccessToken(r.Context(), req.RefreshToken)
 		if err != nil {
-			api.RespondError(w, r, http.StatusUnauthorized, api.ErrorInfo{
-				Code:    api.ErrCodeUnauthorized,
-				Message: "Invalid refresh token",
-			})
+			if session && !errors.Is(err, ErrServiceUnavailable) {
+				cookies.Clear(w)
+			}
+			respondServiceError(w, r, err)
 			return
 		}
 
+		if session {
+			if err := cookies.SetTokens(w, tokens.AccessToken, tokens.RefreshToken, tokens.ExpiresIn); err != nil {
+				respondServiceError(w, r, err)
+				return
+			}
+			api.RespondOK(w, r, SessionResponse{Status: "refreshed", ExpiresIn: tokens.ExpiresIn})
+			return
+		}
 		api.RespondOK(w, r, tokens)
 	}
 }
+
+// sessionRefreshToken проверяет CSRF токен и читает refresh token из cookie сессии.
+func sessionRefreshToken(w http.ResponseWriter, r *http.Request, cookies *middlewares.SessionCookies) (string, bool) {
+	if err := cookies.CheckCSRF(r); err != nil {
+		api.RespondError(w, r, http.StatusForbidden, api.ErrorInfo{
+			Code:    api.ErrCodeForbidden,
+			Message: "Invalid CSRF token",
+		})
+		return "", false
+	}
+
+	refreshToken, err := cookies.RefreshToken(r)
+	if err != nil {
+		api.RespondError(w, r, http.StatusUnauthorized, api.ErrorInfo{
+			Code:    api.ErrCodeUnauthorized,
+			Message: "Missing or invalid session",
+		})
+		return "", false
+	}
+	return refreshToken, true
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/Fisher-Development/woman-app-backend/api"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	"github.com/go-chi/render"
+)
+
+// ResendVerificationEmail хендлер для повторной отправки письма подтверждения email.
+func ResendVerificationEmail(authService IAuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middlewares.GetUserFromContext(r.Context())
+		if !ok {
+			api.RespondError(w, r, http.StatusUnauthorized, api.ErrorInfo{
+				Code:    api.ErrCodeUnauthorized,
+				Message: "User not found keycloak",
+			})
+			return
+		}
+
+		if err := authService.ResendVerificationEmail(r.Context(), userID); err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		render.Status(r, http.StatusAccepted)
+		render.JSON(w, r, map[string]string{"status": "accepted"})
+	}
+}
@@ -7,12 +7,16 @@ import (
 )
 
 const (
-	ErrCodeValidationFailed = "VALIDATION_FAILED"
-	ErrCodeInternalServer   = "INTERNAL_SERVER_ERROR"
-	ErrCodeNotFound         = "NOT_FOUND"
-	ErrCodeConflict         = "CONFLICT"
-	ErrCodeUnauthorized     = "UNAUTHORIZED"
-	ErrCodeBadRequest       = "BAD_REQUEST"
+	ErrCodeValidationFailed   = "VALIDATION_FAILED"
+	ErrCodeInternalServer     = "INTERNAL_SERVER_ERROR"
+	ErrCodeNotFound           = "NOT_FOUND"
+	ErrCodeConflict           = "CONFLICT"
+	ErrCodeUnauthorized       = "UNAUTHORIZED"
+	ErrCodeBadRequest         = "BAD_REQUEST"
+	ErrCodeForbidden          = "FORBIDDEN"
+	ErrCodeTooManyRequests    = "TOO_MANY_REQUESTS"
+	ErrCodeUserDisabled       = "USER_DISABLED"
+	ErrCodeServiceUnavailable = "SERVICE_UNAVAILABLE"
 )
 
 type ErrorInfo struct {
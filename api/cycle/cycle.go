@@ -0,0 +1,270 @@
+package cycle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"github.com/Fisher-Development/woman-app-backend/api"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+	"github.com/Fisher-Development/woman-app-backend/internal/validator"
+)
+
+var (
+	// ErrCycleNotFound цикл не найден (или это прогнозный цикл, который нельзя изменять).
+	ErrCycleNotFound = errors.New("cycle not found")
+	// ErrCycleOverlap цикл пересекается с другим циклом пользователя.
+	ErrCycleOverlap = errors.New("cycle overlaps another cycle")
+	// ErrCycleClosed цикл уже закрыт.
+	ErrCycleClosed = errors.New("cycle is already closed")
+	// ErrInvalidCycle даты цикла противоречат друг другу или находятся в будущем.
+	ErrInvalidCycle = errors.New("invalid cycle")
+)
+
+type ICycleService interface {
+	CreateCycle(ctx context.Context, userID types.UserID, req CycleRequest) (*models.Cycle, error)
+	GetCycle(ctx context.Context, userID types.UserID, cycleID string) (*models.Cycle, error)
+	ListCycles(ctx context.Context, userID types.UserID, from, to models.Date) ([]models.Cycle, error)
+	UpdateCycle(ctx context.Context, userID types.UserID, cycleID string, req CycleRequest) (*models.Cycle, error)
+	CloseCycle(ctx context.Context, userID types.UserID, cycleID string, req CloseRequest) (*models.Cycle, error)
+	DeleteCycle(ctx context.Context, userID types.UserID, cycleID string) error
+}
+
+// Структура для создания и изменения цикла.
+type CycleRequest struct {
+	StartDate *models.Date `json:"startDate" validate:"required"`
+	// EndDate пустой у текущего (незакрытого) цикла.
+	EndDate       *models.Date `json:"endDate"`
+	PeriodEndDate *models.Date `json:"periodEndDate"`
+	Notes         string       `json:"notes" validate:"max=1000"`
+	// Today текущая дата на устройстве пользователя, без нее используется дата UTC.
+	Today *models.Date `json:"today"`
+}
+
+// Структура для закрытия цикла.
+type CloseRequest struct {
+	EndDate *models.Date `json:"endDate" validate:"required"`
+	// Today текущая дата на устройстве пользователя, без нее используется дата UTC.
+	Today *models.Date `json:"today"`
+}
+
+// Create хендлер для создания цикла текущего пользователя.
+func Create(svc ICycleService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		var req CycleRequest
+		if !decodeAndValidate(w, r, &req) {
+			return
+		}
+
+		cycle, err := svc.CreateCycle(r.Context(), userID, req)
+		if err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, cycle)
+	}
+}
+
+// Get хендлер для получения цикла.
+func Get(svc ICycleService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		cycle, err := svc.GetCycle(r.Context(), userID, chi.URLParam(r, "cycleID"))
+		if err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		api.RespondOK(w, r, cycle)
+	}
+}
+
+// List хендлер для получения циклов текущего пользователя за период ?from=YYYY-MM-DD&to=YYYY-MM-DD.
+// Границы периода необязательны.
+func List(svc ICycleService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		from, to, err := parseRange(r)
+		if err != nil {
+			api.RespondError(w, r, http.StatusBadRequest, api.ErrorInfo{
+				Code:    api.ErrCodeValidationFailed,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		cycles, err := svc.ListCycles(r.Context(), userID, from, to)
+		if err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+		if cycles == nil {
+			cycles = []models.Cycle{}
+		}
+
+		api.RespondOK(w, r, cycles)
+	}
+}
+
+// Update хендлер для изменения цикла.
+func Update(svc ICycleService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		var req CycleRequest
+		if !decodeAndValidate(w, r, &req) {
+			return
+		}
+
+		cycle, err := svc.UpdateCycle(r.Context(), userID, chi.URLParam(r, "cycleID"), req)
+		if err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		api.RespondOK(w, r, cycle)
+	}
+}
+
+// Close хендлер для закрытия текущего цикла.
+func Close(svc ICycleService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		var req CloseRequest
+		if !decodeAndValidate(w, r, &req) {
+			return
+		}
+
+		cycle, err := svc.CloseCycle(r.Context(), userID, chi.URLParam(r, "cycleID"), req)
+		if err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		api.RespondOK(w, r, cycle)
+	}
+}
+
+// Delete хендлер для удаления цикла.
+func Delete(svc ICycleService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		if err := svc.DeleteCycle(r.Context(), userID, chi.URLParam(r, "cycleID")); err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		api.RespondOK(w, r, map[string]string{"status": "deleted"})
+	}
+}
+
+func parseRange(r *http.Request) (from, to models.Date, err error) {
+	if s := r.URL.Query().Get("from"); s != "" {
+		if from, err = models.ParseDate(s); err != nil {
+			return models.Date{}, models.Date{}, err
+		}
+	}
+	if s := r.URL.Query().Get("to"); s != "" {
+		if to, err = models.ParseDate(s); err != nil {
+			return models.Date{}, models.Date{}, err
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from.Time) {
+		return models.Date{}, models.Date{}, errors.New("to must not be before from")
+	}
+	return from, to, nil
+}
+
+func currentUser(w http.ResponseWriter, r *http.Request) (types.UserID, bool) {
+	userID, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		api.RespondError(w, r, http.StatusUnauthorized, api.ErrorInfo{
+			Code:    api.ErrCodeUnauthorized,
+			Message: "User not found keycloak",
+		})
+	}
+	return userID, ok
+}
+
+func decodeAndValidate(w http.ResponseWriter, r *http.Request, req any) bool {
+	if err := render.DecodeJSON(r.Body, req); err != nil {
+		api.RespondError(w, r, http.StatusBadRequest, api.ErrorInfo{
+			Code:    api.ErrCodeBadRequest,
+			Message: "Invalid request",
+		})
+		return false
+	}
+
+	if err := validator.Validator.Struct(req); err != nil {
+		api.RespondError(w, r, http.StatusBadRequest, api.ErrorInfo{
+			Code:    api.ErrCodeValidationFailed,
+			Message: err.Error(),
+		})
+		return false
+	}
+
+	return true
+}
+
+// respondServiceError преобразует ошибки сервиса в HTTP ответ.
+func respondServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrCycleNotFound):
+		api.RespondError(w, r, http.StatusNotFound, api.ErrorInfo{
+			Code:    api.ErrCodeNotFound,
+			Message: "Cycle not found",
+		})
+	case errors.Is(err, ErrCycleOverlap):
+		api.RespondError(w, r, http.StatusConflict, api.ErrorInfo{
+			Code:    api.ErrCodeConflict,
+			Message: "Cycle overlaps another cycle",
+		})
+	case errors.Is(err, ErrCycleClosed):
+		api.RespondError(w, r, http.StatusConflict, api.ErrorInfo{
+			Code:    api.ErrCodeConflict,
+			Message: "Cycle is already closed",
+		})
+	case errors.Is(err, ErrInvalidCycle):
+		// Текст ошибки объясняет, какое правило нарушено
+		api.RespondError(w, r, http.StatusBadRequest, api.ErrorInfo{
+			Code:    api.ErrCodeValidationFailed,
+			Message: err.Error(),
+		})
+	default:
+		api.RespondError(w, r, http.StatusInternalServerError, api.ErrorInfo{
+			Code:    api.ErrCodeInternalServer,
+			Message: "Internal server error",
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package prediction
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Fisher-Development/woman-app-backend/api"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+// ErrNoCycles у пользователя нет отмеченных циклов, от которых можно строить прогноз.
+var ErrNoCycles = errors.New("no cycles recorded")
+
+type IPredictionService interface {
+	Forecast(ctx context.Context, userID types.UserID) (*Forecast, error)
+	Recompute(ctx context.Context, userID types.UserID) (*Forecast, error)
+}
+
+// DateRange период дат включительно.
+type DateRange struct {
+	From models.Date `json:"from"`
+	To   models.Date `json:"to"`
+}
+
+// LengthEstimate оценка длительности в днях с диапазоном достоверности.
+type LengthEstimate struct {
+	Days int `json:"days"`
+	Min  int `json:"min"`
+	Max  int `json:"max"`
+}
+
+// PredictedCycle прогноз одного будущего цикла.
+type PredictedCycle struct {
+	// PeriodStart ожидаемый первый день менструации и диапазон, в который он попадет.
+	PeriodStart      models.Date `json:"periodStart"`
+	PeriodStartRange DateRange   `json:"periodStartRange"`
+	// PeriodEnd ожидаемый последний день менструации.
+	PeriodEnd models.Date `json:"periodEnd"`
+	// Ovulation ожидаемый день овуляции и диапазон, в который она попадет.
+	Ovulation      models.Date `json:"ovulation"`
+	OvulationRange DateRange   `json:"ovulationRange"`
+	// FertileWindow дни, в которые вероятно зачатие.
+	FertileWindow DateRange `json:"fertileWindow"`
+}
+
+// Forecast прогноз циклов пользователя.
+type Forecast struct {
+	CycleLength  LengthEstimate `json:"cycleLength"`
+	PeriodLength LengthEstimate `json:"periodLength"`
+	// BasedOnCycles число циклов истории, учтенных в прогнозе (без отброшенных выбросов).
+	BasedOnCycles int `json:"basedOnCycles"`
+	// UsesProfileDefaults истории мало и прогноз опирается на средние значения из профиля.
+	UsesProfileDefaults bool             `json:"usesProfileDefaults"`
+	Cycles              []PredictedCycle `json:"cycles"`
+}
+
+// Get хендлер для получения прогноза циклов текущего пользователя.
+func Get(svc IPredictionService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		forecast, err := svc.Forecast(r.Context(), userID)
+		if err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		api.RespondOK(w, r, forecast)
+	}
+}
+
+// Recompute хендлер для пересчета прогнозных циклов текущего пользователя.
+func Recompute(svc IPredictionService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		forecast, err := svc.Recompute(r.Context(), userID)
+		if err != nil {
+			respondServiceError(w, r, err)
+			return
+		}
+
+		api.RespondOK(w, r, forecast)
+	}
+}
+
+func currentUser(w http.ResponseWriter, r *http.Request) (types.UserID, bool) {
+	userID, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		api.RespondError(w, r, http.StatusUnauthorized, api.ErrorInfo{
+			Code:    api.ErrCodeUnauthorized,
+			Message: "User not found keycloak",
+		})
+	}
+	return userID, ok
+}
+
+// respondServiceError преобразует ошибки сервиса в HTTP ответ.
+func respondServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrNoCycles):
+		api.RespondError(w, r, http.StatusNotFound, api.ErrorInfo{
+			Code:    api.ErrCodeNotFound,
+			Message: "No cycles recorded",
+		})
+	default:
+		api.RespondError(w, r, http.StatusInternalServerError, api.ErrorInfo{
+			Code:    api.ErrCodeInternalServer,
+			Message: "Internal server error",
+		})
+	}
+}
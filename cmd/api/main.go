@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/config"
+	"github.com/Fisher-Development/woman-app-backend/internal/logger"
+	"github.com/Fisher-Development/woman-app-backend/internal/loginguard"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	"github.com/Fisher-Development/woman-app-backend/internal/store"
+)
+
+var configPath = flag.String("config", "configs/config.yaml", "Path to config file")
+
+func main() {
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		_, _ = fmt.Fprintf(out, "Usage: %s [flags] [command]\n\nCommands:\n", os.Args[0])
+		_, _ = fmt.Fprintln(out, "  serve            run API and debug servers (default)")
+		_, _ = fmt.Fprintln(out, "  reconcile-users  sync Keycloak users with the local users table once")
+		_, _ = fmt.Fprintln(out, "  migrate          manage database schema: up | down [N] | goto VERSION | status | baseline VERSION")
+		_, _ = fmt.Fprintln(out, "\nFlags:")
+		flag.PrintDefaults()
+	}
+
+	if err := run(); err != nil {
+		log.Fatalf("run app: %v", err)
+	}
+}
+
+func run() error {
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg, err := config.ParseAndValidate(*configPath)
+	if err != nil {
+		return fmt.Errorf("parse and validate config %q: %v", *configPath, err)
+	}
+
+	if err := logger.InitLogger(cfg.Log.Level); err != nil {
+		return fmt.Errorf("init logger: %v", err)
+	}
+	defer logger.Sync()
+
+	switch command := flag.Arg(0); command {
+	case "", "serve":
+		return serve(ctx, cfg)
+	case "reconcile-users":
+		return reconcileUsers(ctx, cfg, flag.Args()[1:])
+	case "migrate":
+		return migrate(ctx, cfg, flag.Args()[1:])
+	default:
+		flag.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func newStorage(ctx context.Context, cfg config.StorageConfig) (*store.Storage, error) {
+	return store.NewStorage(ctx, store.NewOptions(
+		cfg.DBName,
+		cfg.DBUser,
+		cfg.DBPassword,
+		cfg.DBHost,
+		cfg.DBPort,
+		store.WithDbSSLMode(cfg.DBSSLMode),
+		store.WithDbSSLRootCert(cfg.DBSSLRootCert),
+		store.WithDbSSLCert(cfg.DBSSLCert),
+		store.WithDbSSLKey(cfg.DBSSLKey),
+		store.WithApplicationName(cfg.ApplicationName),
+		store.WithMaxConns(cfg.MaxConns),
+		store.WithMinConns(cfg.MinConns),
+		store.WithMaxConnLifetime(cfg.MaxConnLifetime),
+		store.WithMaxConnIdleTime(cfg.MaxConnIdleTime),
+		store.WithHealthCheckPeriod(cfg.HealthCheckPeriod),
+		store.WithStatementTimeout(cfg.StatementTimeout),
+	))
+}
+
+func newKeycloakClient(name string, cfg config.KeycloakConfig) (*keycloakclient.Client, error) {
+	return keycloakclient.New(keycloakclient.NewOptions(
+		cfg.BasePath,
+		cfg.Realm,
+		cfg.ClientID,
+		cfg.ClientSecret,
+		keycloakclient.WithDebugMode(cfg.DebugMode),
+		keycloakclient.WithName(name),
+		keycloakclient.WithTimeout(cfg.Timeout),
+		keycloakclient.WithMaxRetries(cfg.MaxRetries),
+		keycloakclient.WithRetryWaitMin(cfg.RetryWaitMin),
+		keycloakclient.WithRetryWaitMax(cfg.RetryWaitMax),
+		keycloakclient.WithBreakerThreshold(cfg.BreakerFailureThreshold),
+		keycloakclient.WithBreakerOpenTimeout(cfg.BreakerOpenTimeout),
+		keycloakclient.WithIssuer(cfg.Issuer),
+		keycloakclient.WithDiscoveryTTL(cfg.DiscoveryTTL),
+	))
+}
+
+// newSessionCookies создает cookie сессии или возвращает nil, если режим выключен.
+func newSessionCookies(cfg config.SessionCookiesConfig) (*middlewares.SessionCookies, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(cfg.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode encryption key: %v", err)
+	}
+
+	sameSite := http.SameSiteLaxMode
+	switch cfg.SameSite {
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+
+	return middlewares.NewSessionCookies(key,
+		middlewares.WithCookieNames(cfg.AccessTokenName, cfg.RefreshTokenName, cfg.CSRFCookieName),
+		middlewares.WithCSRFHeader(cfg.CSRFHeaderName),
+		middlewares.WithCookieScope(cfg.Domain, cfg.Path),
+		middlewares.WithCookieSameSite(sameSite),
+		middlewares.WithCookieLifetimes(cfg.AccessTokenLifetime, cfg.RefreshTokenLifetime),
+		middlewares.WithRefreshBefore(cfg.RefreshBefore),
+	)
+}
+
+// newLoginGuard создает защиту входа от перебора или возвращает nil, если она выключена.
+func newLoginGuard(cfg config.LoginProtectionConfig, storage *store.Storage) *loginguard.Guard {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	var attempts loginguard.Store = loginguard.NewMemoryStore()
+	if cfg.Store == "postgres" {
+		attempts = storage
+	}
+
+	return loginguard.New(attempts, storage,
+		loginguard.WithIPPolicy(cfg.IPMaxFailures, cfg.IPWindow),
+		loginguard.WithAccountPolicy(cfg.AccountMaxFailures, cfg.AccountWindow),
+		loginguard.WithDelays(cfg.DelayAfter, cfg.Delay, cfg.MaxDelay),
+		loginguard.WithLockout(cfg.Lockout),
+	)
+}
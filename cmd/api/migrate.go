@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/config"
+	"github.com/Fisher-Development/woman-app-backend/internal/store"
+)
+
+// migrate управляет схемой базы данных встроенными миграциями.
+func migrate(ctx context.Context, cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(fs.Output(), "Usage: migrate up | down [N] | goto VERSION | status | baseline VERSION")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	storage, err := newStorage(ctx, cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("init storage: %v", err)
+	}
+	defer storage.Close()
+
+	migrator, err := storage.Migrator()
+	if err != nil {
+		return fmt.Errorf("load migrations: %v", err)
+	}
+
+	var done []store.Migration
+	switch command := fs.Arg(0); command {
+	case "up":
+		done, err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if fs.NArg() > 1 {
+			if steps, err = strconv.Atoi(fs.Arg(1)); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", fs.Arg(1))
+			}
+		}
+		done, err = migrator.Down(ctx, steps)
+	case "goto", "baseline":
+		version, parseErr := strconv.ParseInt(fs.Arg(1), 10, 64)
+		if parseErr != nil || version < 0 {
+			fs.Usage()
+			return fmt.Errorf("invalid version %q", fs.Arg(1))
+		}
+		if command == "goto" {
+			done, err = migrator.Goto(ctx, version)
+		} else {
+			done, err = migrator.Baseline(ctx, version)
+		}
+	case "status":
+		return printMigrationStatus(ctx, migrator)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown migrate command %q", command)
+	}
+
+	for _, mig := range done {
+		_, _ = fmt.Fprintf(os.Stdout, "%s %03d_%s\n", fs.Arg(0), mig.Version, mig.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("migrate %s: %v", fs.Arg(0), err)
+	}
+	if len(done) == 0 {
+		_, _ = fmt.Fprintln(os.Stdout, "no changes")
+	}
+	return nil
+}
+
+// migrateUp применяет миграции при запуске сервера.
+func migrateUp(ctx context.Context, storage *store.Storage) error {
+	lg := zap.L().Named("migrate")
+
+	migrator, err := storage.Migrator()
+	if err != nil {
+		return fmt.Errorf("load migrations: %v", err)
+	}
+
+	done, err := migrator.Up(ctx)
+	for _, mig := range done {
+		lg.Info("Migration applied", zap.Int64("version", mig.Version), zap.String("name", mig.Name))
+	}
+	if errors.Is(err, store.ErrUnknownMigration) {
+		// База уже обновлена более новой версией приложения (например, во время выкладки)
+		lg.Warn("Database schema is newer than the application", zap.Error(err))
+		return nil
+	}
+	return err
+}
+
+func printMigrationStatus(ctx context.Context, migrator *store.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("migration status: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, st := range statuses {
+		state, appliedAt := "pending", ""
+		if st.Applied {
+			state, appliedAt = "applied", st.AppliedAt.Format(time.RFC3339)
+		}
+		switch {
+		case st.Unknown:
+			state = "unknown"
+		case st.Modified:
+			state = "modified"
+		}
+		_, _ = fmt.Fprintf(w, "%03d\t%s\t%s\t%s\n", st.Version, st.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/config"
+	usersync "github.com/Fisher-Development/woman-app-backend/internal/service/user-sync"
+)
+
+// reconcileUsers выполняет однократную сверку пользователей Keycloak и печатает отчет в stdout.
+func reconcileUsers(ctx context.Context, cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("reconcile-users", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Only report changes without applying them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	storage, err := newStorage(ctx, cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("init storage: %v", err)
+	}
+	defer storage.Close()
+
+	keycloakAdminClient, err := newKeycloakClient("keycloak_admin", cfg.Clients.KeycloakAdmin)
+	if err != nil {
+		return fmt.Errorf("init keycloak admin client: %v", err)
+	}
+
+	report, err := usersync.NewReconciler(keycloakAdminClient, storage, *dryRun).Reconcile(ctx)
+	if err != nil {
+		return fmt.Errorf("reconcile users: %v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
+
+	"github.com/Fisher-Development/woman-app-backend/api"
+	"github.com/Fisher-Development/woman-app-backend/api/apikey"
+	"github.com/Fisher-Development/woman-app-backend/api/auth"
+	"github.com/Fisher-Development/woman-app-backend/api/cycle"
+	"github.com/Fisher-Development/woman-app-backend/api/prediction"
+	"github.com/Fisher-Development/woman-app-backend/api/user"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+)
+
+// routerDeps зависимости, необходимые для построения роутера клиентского API.
+type routerDeps struct {
+	allowOrigins      []string
+	trustedProxies    []netip.Prefix // прокси, которым доверяется X-Forwarded-For
+	authService       auth.IAuthService
+	userService       user.IRegistryUser
+	apiKeyService     apikey.IAPIKeyService
+	cycleService      cycle.ICycleService
+	predictionService prediction.IPredictionService
+	authMiddleware    *middlewares.AuthMiddleware
+	// закрывать пользовательские эндпоинты до подтверждения email
+	requireVerifiedEmail bool
+	// вход по паролю через POST /auth/login
+	passwordLogin bool
+	// вход через страницу Keycloak (nil - выключен)
+	authorizationCode *auth.AuthorizationCodeOptions
+	// токены в cookie сессии вместо тела ответа (nil - выключено)
+	sessionCookies *middlewares.SessionCookies
+	// защита входа от перебора: включает эндпоинт снятия блокировки
+	loginProtection bool
+}
+
+// newRouter собирает Chi роутер клиентского API.
+func newRouter(deps routerDeps) http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(middleware.RequestID)
+	r.Use(middlewares.RealIP(deps.trustedProxies))
+	r.Use(middleware.Recoverer)
+	allowedHeaders := []string{"Accept", "Authorization", "Content-Type"}
+	if deps.sessionCookies != nil {
+		allowedHeaders = append(allowedHeaders, deps.sessionCookies.CSRFHeader())
+	}
+	r.Use(cors.Handler(cors.Options{
+		AllowedOrigins: deps.allowOrigins,
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
+		AllowedHeaders: allowedHeaders,
+		// Cookie сессии передаются только с явно перечисленных origin
+		AllowCredentials: deps.sessionCookies != nil,
+		MaxAge:           300,
+	}))
+
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		api.RespondOK(w, r, map[string]string{"status": "ok"})
+	})
+
+	r.Route("/api/v1", func(r chi.Router) {
+		// Эндпоинты авторизации (logout требует токен)
+		r.Route("/auth", func(r chi.Router) {
+			r.Post("/register", auth.Register(deps.authService))
+			if deps.passwordLogin {
+				r.Post("/login", auth.Login(deps.authService, deps.sessionCookies))
+			}
+			if deps.authorizationCode != nil {
+				r.Get("/authorize", auth.Authorize(deps.authService))
+				r.Get("/callback", auth.Callback(deps.authService, *deps.authorizationCode))
+			}
+			r.Post("/refresh", auth.RefreshToken(deps.authService, deps.sessionCookies))
+			r.Post("/password/forgot", auth.ForgotPassword(deps.authService))
+
+			r.Group(func(r chi.Router) {
+				r.Use(deps.authMiddleware.RequireAuth())
+				r.Use(middlewares.RequireUser())
+				r.Use(middlewares.DenyAPIKeys())
+				r.Post("/logout", auth.Logout(deps.authService, deps.sessionCookies))
+				r.Post("/logout-all", auth.LogoutAll(deps.authService, deps.sessionCookies))
+				r.Post("/password/change", auth.ChangePassword(deps.authService))
+				r.Post("/verify-email/resend", auth.ResendVerificationEmail(deps.authService))
+			})
+		})
+
+		// Эндпоинты, требующие авторизации пользователя (токены внутренних сервисов отклоняются)
+		r.Route("/user", func(r chi.Router) {
+			r.Use(deps.authMiddleware.RequireAuth())
+			r.Use(middlewares.RequireUser())
+			if deps.requireVerifiedEmail {
+				r.Use(middlewares.RequireVerifiedEmail())
+			}
+			r.Put("/update", user.Update(deps.userService))
+			r.Get("/dashboard", user.Dashboard(deps.userService))
+
+			// Персональные API ключи управляются только с токеном Keycloak
+			r.Route("/api-keys", func(r chi.Router) {
+				r.Use(middlewares.DenyAPIKeys())
+				r.Get("/", apikey.List(deps.apiKeyService))
+				r.Post("/", apikey.Create(deps.apiKeyService))
+				r.Put("/{keyID}/scopes", apikey.UpdateScopes(deps.apiKeyService))
+				r.Delete("/{keyID}", apikey.Revoke(deps.apiKeyService))
+			})
+		})
+
+		// Менструальные циклы пользователя
+		r.Route("/cycles", func(r chi.Router) {
+			r.Use(deps.authMiddleware.RequireAuth())
+			r.Use(middlewares.RequireUser())
+			if deps.requireVerifiedEmail {
+				r.Use(middlewares.RequireVerifiedEmail())
+			}
+			r.Get("/", cycle.List(deps.cycleService))
+			r.Post("/", cycle.Create(deps.cycleService))
+			r.Get("/forecast", prediction.Get(deps.predictionService))
+			r.Post("/forecast/recompute", prediction.Recompute(deps.predictionService))
+			r.Get("/{cycleID}", cycle.Get(deps.cycleService))
+			r.Put("/{cycleID}", cycle.Update(deps.cycleService))
+			r.Post("/{cycleID}/close", cycle.Close(deps.cycleService))
+			r.Delete("/{cycleID}", cycle.Delete(deps.cycleService))
+		})
+
+		// Административные эндпоинты
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(deps.authMiddleware.RequireAuth())
+			r.Use(middlewares.RequireUser())
+			r.Use(middlewares.DenyAPIKeys())
+			r.Use(middlewares.RequireRealmRole("admin"))
+			r.Put("/users/{userID}/temporary-password", auth.SetTemporaryPassword(deps.authService))
+			if deps.loginProtection {
+				r.Post("/login-lockouts/clear", auth.ClearLoginLockout(deps.authService))
+			}
+		})
+	})
+
+	return r
+}
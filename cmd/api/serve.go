@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"slices"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/Fisher-Development/woman-app-backend/api/auth"
+	"github.com/Fisher-Development/woman-app-backend/internal/config"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	serverclient "github.com/Fisher-Development/woman-app-backend/internal/server-client"
+	serverdebug "github.com/Fisher-Development/woman-app-backend/internal/server-debug"
+	"github.com/Fisher-Development/woman-app-backend/internal/service"
+	apikeyservice "github.com/Fisher-Development/woman-app-backend/internal/service/apikey-service"
+	authservice "github.com/Fisher-Development/woman-app-backend/internal/service/auth-service"
+	cycleservice "github.com/Fisher-Development/woman-app-backend/internal/service/cycle-service"
+	predictionservice "github.com/Fisher-Development/woman-app-backend/internal/service/prediction-service"
+	usersync "github.com/Fisher-Development/woman-app-backend/internal/service/user-sync"
+)
+
+// serve запускает клиентский и отладочный серверы вместе с фоновыми задачами.
+func serve(ctx context.Context, cfg config.Config) error {
+	lg := zap.L().Named("main")
+	lg.Info("Starting application", zap.String("env", cfg.Global.Env))
+
+	storage, err := newStorage(ctx, cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("init storage: %v", err)
+	}
+	defer storage.Close()
+
+	if cfg.Storage.MigrateOnStart {
+		if err := migrateUp(ctx, storage); err != nil {
+			return fmt.Errorf("migrate database: %v", err)
+		}
+	}
+
+	keycloakClient, err := newKeycloakClient("keycloak", cfg.Clients.Keycloak)
+	if err != nil {
+		return fmt.Errorf("init keycloak client: %v", err)
+	}
+
+	keycloakAdminClient, err := newKeycloakClient("keycloak_admin", cfg.Clients.KeycloakAdmin)
+	if err != nil {
+		return fmt.Errorf("init keycloak admin client: %v", err)
+	}
+
+	trustedProxies, err := middlewares.ParseTrustedProxies(cfg.Servers.Client.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("servers.client.trusted_proxies: %v", err)
+	}
+
+	sessionCookies, err := newSessionCookies(cfg.Auth.SessionCookies)
+	if err != nil {
+		return fmt.Errorf("init session cookies: %v", err)
+	}
+	if sessionCookies != nil && slices.Contains(cfg.Servers.Client.AllowOrigins, "*") {
+		return errors.New("auth.session_cookies requires explicit servers.client.allow_origins")
+	}
+
+	revocations := middlewares.NewRevocationList(cfg.Auth.AccessTokenLifetime)
+	authOpts := []authservice.AuthServiceOption{
+		authservice.WithPasswordAttempts(cfg.Auth.PasswordAttempts, cfg.Auth.PasswordAttemptsWindow),
+	}
+	loginGuard := newLoginGuard(cfg.Auth.LoginProtection, storage)
+	if loginGuard != nil {
+		authOpts = append(authOpts, authservice.WithLoginGuard(loginGuard))
+	}
+	var authorizationCode *auth.AuthorizationCodeOptions
+	if codeCfg := cfg.Auth.AuthorizationCode; codeCfg.Enabled {
+		authOpts = append(authOpts,
+			authservice.WithAuthorizationCode(keycloakClient, codeCfg.RedirectURL, codeCfg.Scopes, codeCfg.StateTTL),
+			authservice.WithMaxPendingAuthorizations(codeCfg.MaxPendingStates))
+		authorizationCode = &auth.AuthorizationCodeOptions{
+			TokenDelivery:        auth.TokenDelivery(codeCfg.TokenDelivery),
+			Cookies:              sessionCookies,
+			PostLoginRedirectURL: codeCfg.PostLoginRedirectURL,
+		}
+		if authorizationCode.TokenDelivery == auth.TokenDeliveryCookie && sessionCookies == nil {
+			return errors.New("auth.authorization_code.token_delivery=cookie requires auth.session_cookies")
+		}
+	}
+	authService := authservice.NewAuthService(storage, keycloakClient, keycloakAdminClient, revocations, authOpts...)
+	userService := service.NewRegistryUser(storage)
+	apiKeyService := apikeyservice.NewAPIKeyService(storage, keycloakAdminClient)
+	predictionService := predictionservice.NewPredictionService(storage)
+	cycleService := cycleservice.NewCycleService(storage, cycleservice.WithPredictor(predictionService))
+	orphanCleaner := authservice.NewOrphanCleaner(storage, keycloakAdminClient, cfg.Jobs.OrphanCleanupInterval)
+	tokenVerifier := middlewares.NewJWKSVerifier(keycloakClient, cfg.Auth.Audience, cfg.Auth.JWKSRefreshInterval)
+	introspectionCache := middlewares.NewCachingKeycloakClient(keycloakClient,
+		cfg.Auth.IntrospectionCacheTTL,
+		cfg.Auth.IntrospectionNegativeCacheTTL,
+	)
+	expvar.Publish("storage_pool", expvar.Func(func() any { return storage.Stats() }))
+	expvar.Publish("introspection_cache", expvar.Func(func() any { return introspectionCache.Stats() }))
+	expvar.Publish("keycloak_client", expvar.Func(func() any { return keycloakClient.Stats() }))
+	expvar.Publish("keycloak_admin_client", expvar.Func(func() any { return keycloakAdminClient.Stats() }))
+
+	authMiddlewareOpts := []middlewares.AuthMiddlewareOption{
+		middlewares.WithTokenVerifier(tokenVerifier),
+		middlewares.WithRevocationCheck(cfg.Auth.CheckRevocation),
+		middlewares.WithRevocationList(revocations),
+		middlewares.WithServiceAccounts(middlewares.ServiceAccounts{
+			Clients: cfg.Auth.ServiceAccounts.Clients,
+			Role:    middlewares.ParseRole(cfg.Auth.ServiceAccounts.Role),
+		}),
+		middlewares.WithAPIKeys(apiKeyService),
+	}
+	if sessionCookies != nil {
+		authMiddlewareOpts = append(authMiddlewareOpts, middlewares.WithSessionCookies(sessionCookies, keycloakClient))
+	}
+	authMiddleware := middlewares.NewAuthMiddleware(introspectionCache, authMiddlewareOpts...)
+
+	router := newRouter(routerDeps{
+		allowOrigins:         cfg.Servers.Client.AllowOrigins,
+		trustedProxies:       trustedProxies,
+		authService:          authService,
+		userService:          userService,
+		apiKeyService:        apiKeyService,
+		cycleService:         cycleService,
+		predictionService:    predictionService,
+		authMiddleware:       authMiddleware,
+		requireVerifiedEmail: cfg.Auth.RequireVerifiedEmail,
+		passwordLogin:        !cfg.Auth.DisablePasswordLogin,
+		authorizationCode:    authorizationCode,
+		sessionCookies:       sessionCookies,
+		loginProtection:      loginGuard != nil,
+	})
+
+	srvClient, err := serverclient.New(serverclient.NewOptions(cfg.Servers.Client.Addr, router))
+	if err != nil {
+		return fmt.Errorf("init client server: %v", err)
+	}
+
+	srvDebug, err := serverdebug.New(serverdebug.NewOptions(cfg.Servers.Debug.Addr))
+	if err != nil {
+		return fmt.Errorf("init debug server: %v", err)
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+
+	// Запускаем серверы; при ошибке одного из них errgroup отменит контекст и остановит остальные.
+	eg.Go(func() error { return srvClient.Run(ctx) })
+	eg.Go(func() error { return srvDebug.Run(ctx) })
+	eg.Go(func() error { return keycloakAdminClient.Run(ctx) })
+	eg.Go(func() error { return tokenVerifier.Run(ctx) })
+	eg.Go(func() error { return introspectionCache.Run(ctx) })
+	eg.Go(func() error { return revocations.Run(ctx) })
+	eg.Go(func() error { return authService.Run(ctx) })
+	eg.Go(func() error { return apiKeyService.Run(ctx) })
+	eg.Go(func() error { return orphanCleaner.Run(ctx) })
+	if loginGuard != nil {
+		eg.Go(func() error { return loginGuard.Run(ctx) })
+	}
+
+	// Сверка пользователей с Keycloak по расписанию (выключена при нулевом интервале)
+	if cfg.Jobs.UserReconcileInterval > 0 {
+		reconciler := usersync.NewReconciler(keycloakAdminClient, storage, false)
+		eg.Go(func() error { return reconciler.Run(ctx, cfg.Jobs.UserReconcileInterval) })
+	}
+
+	if err := eg.Wait(); err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("wait app stop: %v", err)
+	}
+
+	lg.Info("Application stopped")
+	return nil
+}
@@ -0,0 +1,265 @@
+package keycloakclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"go.uber.org/zap"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/logger"
+)
+
+const (
+	// За сколько до истечения токен перестает выдаваться из кэша.
+	adminTokenExpirySkew = 10 * time.Second
+	// Доля времени жизни токена, после которой он обновляется в фоне.
+	adminTokenRefreshRatio = 0.75
+	// Пауза перед повторным фоновым обновлением после ошибки.
+	adminTokenRetryInterval = 5 * time.Second
+	// Таймаут получения токена, не зависящий от отмены контекста вызывающего.
+	adminTokenFetchTimeout = 10 * time.Second
+	// Время жизни токена, если Keycloak не вернул expires_in.
+	defaultAdminTokenLifetime = time.Minute
+)
+
+// adminToken токен Admin API и время его получения.
+type adminToken struct {
+	value     string
+	issuedAt  time.Time
+	expiresAt time.Time
+}
+
+// tokenCall выполняющееся получение токена, которого ждут конкурентные вызовы.
+type tokenCall struct {
+	done  chan struct{}
+	token adminToken
+	err   error
+}
+
+// adminTokenSource потокобезопасный кэш токена client_credentials для Admin API.
+// Конкурентные обновления схлопываются в один запрос к Keycloak.
+type adminTokenSource struct {
+	fetch func(ctx context.Context) (adminToken, error)
+	now   func() time.Time
+
+	mu       sync.Mutex
+	token    adminToken
+	inflight *tokenCall
+	updated  chan struct{} // закрывается и пересоздается при каждом новом токене
+}
+
+func newAdminTokenSource(fetch func(ctx context.Context) (adminToken, error)) *adminTokenSource {
+	return &adminTokenSource{
+		fetch:   fetch,
+		now:     time.Now,
+		updated: make(chan struct{}),
+	}
+}
+
+// Token возвращает действующий токен из кэша или получает новый.
+func (s *adminTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	if s.valid(s.token) {
+		token := s.token.value
+		s.mu.Unlock()
+		return token, nil
+	}
+	call := s.startRefresh()
+	s.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return "", call.err
+		}
+		return call.token.value, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Invalidate сбрасывает токен, если он все еще в кэше (например, после ответа 401).
+func (s *adminTokenSource) Invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.value == token {
+		s.token = adminToken{}
+	}
+}
+
+// Run заранее обновляет токен в фоне, чтобы запросы не ждали client_credentials grant.
+func (s *adminTokenSource) Run(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		wait := s.refreshIn()
+		updated := s.updated
+		s.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-updated:
+			// Токен обновили по запросу: пересчитываем время следующего обновления
+			timer.Stop()
+			continue
+		case <-timer.C:
+		}
+
+		s.mu.Lock()
+		call := s.startRefresh()
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-call.done:
+		}
+
+		if call.err != nil {
+			logger.GetLogger().Warn("Failed to refresh admin token in background", zap.Error(call.err))
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(adminTokenRetryInterval):
+			}
+		}
+	}
+}
+
+// valid проверяет, что токен можно отдавать из кэша. Вызывается под mu.
+func (s *adminTokenSource) valid(token adminToken) bool {
+	if token.value == "" {
+		return false
+	}
+	skew := adminTokenExpirySkew
+	if lifetime := token.expiresAt.Sub(token.issuedAt); lifetime < 2*skew {
+		skew = lifetime / 2
+	}
+	return s.now().Before(token.expiresAt.Add(-skew))
+}
+
+// refreshIn возвращает время до планового фонового обновления. Вызывается под mu.
+func (s *adminTokenSource) refreshIn() time.Duration {
+	if s.token.value == "" {
+		return 0
+	}
+	lifetime := s.token.expiresAt.Sub(s.token.issuedAt)
+	refreshAt := s.token.issuedAt.Add(time.Duration(float64(lifetime) * adminTokenRefreshRatio))
+	return max(refreshAt.Sub(s.now()), 0)
+}
+
+// startRefresh запускает получение токена или присоединяется к уже идущему. Вызывается под mu.
+func (s *adminTokenSource) startRefresh() *tokenCall {
+	if s.inflight != nil {
+		return s.inflight
+	}
+
+	call := &tokenCall{done: make(chan struct{})}
+	s.inflight = call
+
+	go func() {
+		// Не зависим от контекста первого вызывающего: результат нужен всем ожидающим
+		ctx, cancel := context.WithTimeout(context.Background(), adminTokenFetchTimeout)
+		defer cancel()
+
+		call.token, call.err = s.fetch(ctx)
+
+		s.mu.Lock()
+		s.inflight = nil
+		if call.err == nil {
+			s.token = call.token
+			close(s.updated)
+			s.updated = make(chan struct{})
+		}
+		s.mu.Unlock()
+
+		close(call.done)
+	}()
+
+	return call
+}
+
+// Run обновляет токен Admin API в фоне до отмены контекста.
+func (c *Client) Run(ctx context.Context) error {
+	return c.adminToken.Run(ctx)
+}
+
+// adminRequest выполняет запрос к Admin API с токеном из кэша.
+// При ответе 401 токен сбрасывается и запрос повторяется один раз с новым токеном.
+func (c *Client) adminRequest(
+	ctx context.Context,
+	send func(r *resty.Request) (*resty.Response, error),
+) (*resty.Response, error) {
+	for attempt := 0; ; attempt++ {
+		token, err := c.adminToken.Token(ctx)
+		if err != nil {
+			logger.GetLogger().Error("Failed to get admin token", zap.Error(err))
+			return nil, fmt.Errorf("get admin token: %w", err)
+		}
+
+		resp, err := send(c.cli.R().SetContext(ctx).SetAuthToken(token))
+		if err != nil {
+			return nil, unavailableError("send admin request", err)
+		}
+		if resp.StatusCode() == http.StatusUnauthorized && attempt == 0 {
+			logger.GetLogger().Warn("Admin token rejected, retrying with a new token")
+			c.adminToken.Invalidate(token)
+			continue
+		}
+		return resp, nil
+	}
+}
+
+// fetchAdminToken получает токен Admin API через client_credentials grant.
+func (c *Client) fetchAdminToken(ctx context.Context) (adminToken, error) {
+	url, err := c.tokenEndpoint(ctx, "admin token")
+	if err != nil {
+		return adminToken{}, err
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+
+	issuedAt := time.Now()
+	resp, err := c.cli.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/x-www-form-urlencoded").
+		SetFormData(map[string]string{
+			"grant_type":    "client_credentials",
+			"client_id":     c.clientID,
+			"client_secret": c.clientSecret,
+		}).
+		SetResult(&tokenResp).
+		Post(url)
+	if err != nil {
+		return adminToken{}, unavailableError("admin token", err)
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		logger.GetLogger().Error("Failed to get admin token",
+			zap.Int("status_code", resp.StatusCode()),
+			zap.String("response", string(resp.Body())))
+		return adminToken{}, newAPIError("admin token", resp)
+	}
+
+	lifetime := time.Duration(tokenResp.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = defaultAdminTokenLifetime
+	}
+
+	return adminToken{
+		value:     tokenResp.AccessToken,
+		issuedAt:  issuedAt,
+		expiresAt: issuedAt.Add(lifetime),
+	}, nil
+}
@@ -0,0 +1,110 @@
+package keycloakclient_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/testingh/fakekeycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+func TestAdminTokenIsCached(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t)
+
+	for i := 0; i < 3; i++ {
+		_, err := client.ListUsers(ctx, 0, 10)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 1, kc.Requests(fakekeycloak.EndpointToken))
+	assert.Equal(t, 3, kc.Requests(fakekeycloak.EndpointAdminUsers))
+}
+
+func TestAdminTokenConcurrentRefreshIsCollapsed(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t)
+	// Задержка, чтобы все вызовы успели дождаться одного запроса токена
+	kc.SetLatency(50 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.ListUsers(ctx, 0, 10)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, kc.Requests(fakekeycloak.EndpointToken))
+}
+
+func TestAdminTokenRetryOnUnauthorized(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t)
+
+	_, err := client.ListUsers(ctx, 0, 10)
+	require.NoError(t, err)
+
+	t.Run("token is renewed once", func(t *testing.T) {
+		kc.Fail(fakekeycloak.EndpointAdminUsers, fakekeycloak.Fault{Status: http.StatusUnauthorized, Times: 1})
+
+		_, err := client.ListUsers(ctx, 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 2, kc.Requests(fakekeycloak.EndpointToken))
+	})
+
+	t.Run("second 401 is returned", func(t *testing.T) {
+		kc.Fail(fakekeycloak.EndpointAdminUsers, fakekeycloak.Fault{Status: http.StatusUnauthorized, Times: 2})
+
+		require.Error(t, client.DeleteUser(ctx, types.NewUserID()))
+		assert.Equal(t, 3, kc.Requests(fakekeycloak.EndpointToken))
+	})
+}
+
+func TestAdminTokenExpiry(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t, fakekeycloak.WithTokenLifetime(time.Second))
+	client := kc.NewClient(t)
+
+	_, err := client.ListUsers(ctx, 0, 10)
+	require.NoError(t, err)
+
+	// Короткоживущий токен перестает отдаваться из кэша с запасом до истечения
+	time.Sleep(600 * time.Millisecond)
+
+	_, err = client.ListUsers(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, kc.Requests(fakekeycloak.EndpointToken))
+}
+
+func TestAdminTokenBackgroundRefresh(t *testing.T) {
+	kc := fakekeycloak.New(t, fakekeycloak.WithTokenLifetime(time.Second))
+	client := kc.NewClient(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- client.Run(ctx) }()
+
+	// Первый токен запрашивается сразу, следующие - на 3/4 времени жизни
+	assert.Eventually(t, func() bool {
+		return kc.Requests(fakekeycloak.EndpointToken) >= 3
+	}, 3*time.Second, 50*time.Millisecond)
+
+	_, err := client.ListUsers(ctx, 0, 10)
+	require.NoError(t, err)
+
+	cancel()
+	require.NoError(t, <-done)
+}
@@ -4,7 +4,6 @@ package keycloakclient
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 )
 
@@ -20,7 +19,10 @@ type RPT struct {
 }
 
 func (c *Client) Auth(ctx context.Context, username, password string) (*RPT, error) {
-	url := fmt.Sprintf("realms/%s/protocol/openid-connect/token", c.realm)
+	url, err := c.tokenEndpoint(ctx, "auth")
+	if err != nil {
+		return nil, err
+	}
 
 	var token RPT
 
@@ -34,10 +36,10 @@ func (c *Client) Auth(ctx context.Context, username, password string) (*RPT, err
 		SetResult(&token).
 		Post(url)
 	if err != nil {
-		return nil, fmt.Errorf("send request to keycloak: %v", err)
+		return nil, unavailableError("auth", err)
 	}
 	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("errored keycloak response: %v", resp.Status())
+		return nil, newAPIError("auth", resp)
 	}
 
 	return &token, nil
@@ -0,0 +1,94 @@
+package keycloakclient_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/testingh/fakekeycloak"
+)
+
+const testRedirectURI = "https://app.example.com/api/v1/auth/callback"
+
+func TestCodeChallenge(t *testing.T) {
+	// Пример из RFC 7636, приложение B
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		keycloakclient.CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+
+	verifier, err := keycloakclient.NewCodeVerifier()
+	require.NoError(t, err)
+	assert.Len(t, verifier, 43)
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t)
+	user := kc.AddUser(fakekeycloak.User{Email: "jane@example.com", Password: "password123", Enabled: true})
+
+	verifier, err := keycloakclient.NewCodeVerifier()
+	require.NoError(t, err)
+
+	authURL, err := client.AuthorizationURL(ctx, keycloakclient.AuthorizationRequest{
+		RedirectURI:   testRedirectURI,
+		State:         "state-1",
+		CodeChallenge: keycloakclient.CodeChallenge(verifier),
+		Scopes:        []string{"openid", "email"},
+		LoginHint:     user.Email,
+	})
+	require.NoError(t, err)
+
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, kc.Issuer()+"/protocol/openid-connect/auth", u.Scheme+"://"+u.Host+u.Path)
+	assert.Equal(t, "openid email", u.Query().Get("scope"))
+	assert.Equal(t, keycloakclient.CodeChallengeMethodS256, u.Query().Get("code_challenge_method"))
+
+	callback := authorize(t, authURL)
+	assert.Equal(t, "state-1", callback.Get("state"))
+	code := callback.Get("code")
+	require.NotEmpty(t, code)
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		otherCallback := authorize(t, authURL)
+
+		_, err := client.ExchangeCode(ctx, otherCallback.Get("code"), testRedirectURI, "wrong-verifier")
+		require.ErrorIs(t, err, keycloakclient.ErrInvalidGrant)
+	})
+
+	tokens, err := client.ExchangeCode(ctx, code, testRedirectURI, verifier)
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	info, err := client.UserInfo(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, info.Subject)
+
+	// Код одноразовый
+	_, err = client.ExchangeCode(ctx, code, testRedirectURI, verifier)
+	require.ErrorIs(t, err, keycloakclient.ErrInvalidGrant)
+}
+
+// authorize проходит страницу входа fake Keycloak и возвращает параметры редиректа.
+func authorize(t *testing.T, authURL string) url.Values {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, testRedirectURI, location.Scheme+"://"+location.Host+location.Path)
+	return location.Query()
+}
@@ -61,7 +61,10 @@ func (r *IntrospectTokenResult) UnmarshalJSON(data []byte) error {
 // IntrospectToken implements
 // https://www.keycloak.org/docs/latest/authorization_services/index.html#obtaining-information-about-an-rpt
 func (c *Client) IntrospectToken(ctx context.Context, token string) (*IntrospectTokenResult, error) {
-	url := fmt.Sprintf("%s/realms/%s/protocol/openid-connect/token/introspect", c.basePath, c.realm)
+	url, err := c.endpoint(ctx, "introspect token", func(o *OpenIDConfiguration) string { return o.IntrospectionEndpoint })
+	if err != nil {
+		return nil, err
+	}
 
 	resp, err := c.auth(ctx).
 		SetFormData(map[string]string{
@@ -70,10 +73,10 @@ func (c *Client) IntrospectToken(ctx context.Context, token string) (*Introspect
 		}).
 		Post(url)
 	if err != nil {
-		return nil, fmt.Errorf("send request to keycloak: %v", err)
+		return nil, unavailableError("introspect token", err)
 	}
 	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("errored keycloak response: %v", resp.Status())
+		return nil, newAPIError("introspect token", resp)
 	}
 
 	var result IntrospectTokenResult
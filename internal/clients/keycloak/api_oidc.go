@@ -0,0 +1,127 @@
+package keycloakclient
+
+import (
+	"context"
+	"net/http"
+)
+
+// OpenIDConfiguration часть discovery документа realm, которая нам нужна.
+type OpenIDConfiguration struct {
+	Issuer                           string   `json:"issuer"`
+	AuthorizationEndpoint            string   `json:"authorization_endpoint"`
+	TokenEndpoint                    string   `json:"token_endpoint"`
+	IntrospectionEndpoint            string   `json:"introspection_endpoint"`
+	RevocationEndpoint               string   `json:"revocation_endpoint"`
+	EndSessionEndpoint               string   `json:"end_session_endpoint"`
+	UserinfoEndpoint                 string   `json:"userinfo_endpoint"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// JSONWebKey публичный ключ из JWKS.
+type JSONWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet набор ключей realm.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS загружает набор публичных ключей по jwks_uri из discovery документа.
+func (c *Client) JWKS(ctx context.Context, jwksURI string) (*JSONWebKeySet, error) {
+	var result JSONWebKeySet
+	resp, err := c.cli.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(jwksURI)
+	if err != nil {
+		return nil, unavailableError("jwks", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newAPIError("jwks", resp)
+	}
+
+	return &result, nil
+}
+
+// UserInfo ответ userinfo эндпоинта.
+type UserInfo struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+	GivenName         string `json:"given_name"`
+	FamilyName        string `json:"family_name"`
+}
+
+// Типы токенов для RevokeToken.
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// RevokeToken отзывает access или refresh token (RFC 7009).
+// Пустой tokenTypeHint оставляет определение типа провайдеру.
+func (c *Client) RevokeToken(ctx context.Context, token, tokenTypeHint string) error {
+	url, err := c.endpoint(ctx, "revoke token", func(o *OpenIDConfiguration) string { return o.RevocationEndpoint })
+	if err != nil {
+		return err
+	}
+
+	form := map[string]string{
+		"token":         token,
+		"client_id":     c.clientID,
+		"client_secret": c.clientSecret,
+	}
+	if tokenTypeHint != "" {
+		form["token_type_hint"] = tokenTypeHint
+	}
+
+	resp, err := c.cli.R().
+		SetContext(ctx).
+		SetFormData(form).
+		Post(url)
+	if err != nil {
+		return unavailableError("revoke token", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return newAPIError("revoke token", resp)
+	}
+
+	return nil
+}
+
+// UserInfo возвращает сведения о владельце access token.
+func (c *Client) UserInfo(ctx context.Context, accessToken string) (*UserInfo, error) {
+	url, err := c.endpoint(ctx, "userinfo", func(o *OpenIDConfiguration) string { return o.UserinfoEndpoint })
+	if err != nil {
+		return nil, err
+	}
+
+	var result UserInfo
+	resp, err := c.cli.R().
+		SetContext(ctx).
+		SetAuthToken(accessToken).
+		SetResult(&result).
+		Get(url)
+	if err != nil {
+		return nil, unavailableError("userinfo", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newAPIError("userinfo", resp)
+	}
+
+	return &result, nil
+}
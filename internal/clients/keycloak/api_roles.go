@@ -0,0 +1,254 @@
+package keycloakclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+// RoleRepresentation роль realm или клиента.
+type RoleRepresentation struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Composite   bool   `json:"composite"`
+	ClientRole  bool   `json:"clientRole"`
+	// ContainerID ID realm или внутренний ID клиента, которому принадлежит роль
+	ContainerID string `json:"containerId,omitempty"`
+}
+
+// ClientRepresentation клиент realm.
+type ClientRepresentation struct {
+	// ID внутренний идентификатор клиента, используется в путях Admin API
+	ID       string `json:"id"`
+	ClientID string `json:"clientId"`
+	Name     string `json:"name,omitempty"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// GetClient возвращает клиент realm по его clientId.
+func (c *Client) GetClient(ctx context.Context, clientID string) (*ClientRepresentation, error) {
+	url := fmt.Sprintf("%s/admin/realms/%s/clients", c.basePath, c.realm)
+
+	var clients []ClientRepresentation
+	resp, err := c.adminRequest(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetQueryParam("clientId", clientID).
+			SetResult(&clients).
+			Get(url)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get client: %w", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newAPIError("get client", resp)
+	}
+
+	for i := range clients {
+		if clients[i].ClientID == clientID {
+			return &clients[i], nil
+		}
+	}
+	return nil, ErrClientNotFound
+}
+
+// GetRealmRole возвращает роль realm по имени.
+func (c *Client) GetRealmRole(ctx context.Context, name string) (*RoleRepresentation, error) {
+	role := url.PathEscape(name)
+	return c.getRole(ctx, "get realm role", fmt.Sprintf("%s/admin/realms/%s/roles/%s", c.basePath, c.realm, role))
+}
+
+// GetClientRole возвращает роль клиента по имени.
+func (c *Client) GetClientRole(ctx context.Context, clientID, name string) (*RoleRepresentation, error) {
+	clientUUID, err := c.clientUUID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	role := url.PathEscape(name)
+	return c.getRole(ctx, "get client role",
+		fmt.Sprintf("%s/admin/realms/%s/clients/%s/roles/%s", c.basePath, c.realm, clientUUID, role))
+}
+
+// GetRealmRoleMappings возвращает роли realm, назначенные пользователю напрямую.
+func (c *Client) GetRealmRoleMappings(ctx context.Context, userID types.UserID) ([]RoleRepresentation, error) {
+	return c.getRoleMappings(ctx, "get realm role mappings", c.realmRoleMappingsURL(userID))
+}
+
+// GetEffectiveRealmRoles возвращает все роли realm пользователя, включая полученные через составные роли.
+// Именно они попадают в realm_access.roles токена.
+func (c *Client) GetEffectiveRealmRoles(ctx context.Context, userID types.UserID) ([]RoleRepresentation, error) {
+	return c.getRoleMappings(ctx, "get effective realm roles", c.realmRoleMappingsURL(userID)+"/composite")
+}
+
+// AddRealmRoles назначает пользователю роли realm.
+func (c *Client) AddRealmRoles(ctx context.Context, userID types.UserID, roles ...string) error {
+	resolved, err := c.resolveRoles(ctx, roles, c.GetRealmRole)
+	if err != nil {
+		return err
+	}
+	return c.changeRoleMappings(ctx, "add realm roles", http.MethodPost, c.realmRoleMappingsURL(userID), resolved)
+}
+
+// RemoveRealmRoles снимает с пользователя роли realm.
+func (c *Client) RemoveRealmRoles(ctx context.Context, userID types.UserID, roles ...string) error {
+	resolved, err := c.resolveRoles(ctx, roles, c.GetRealmRole)
+	if err != nil {
+		return err
+	}
+	return c.changeRoleMappings(ctx, "remove realm roles", http.MethodDelete, c.realmRoleMappingsURL(userID), resolved)
+}
+
+// GetClientRoleMappings возвращает роли клиента clientID, назначенные пользователю напрямую.
+func (c *Client) GetClientRoleMappings(ctx context.Context, userID types.UserID, clientID string) ([]RoleRepresentation, error) {
+	url, err := c.clientRoleMappingsURL(ctx, userID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	return c.getRoleMappings(ctx, "get client role mappings", url)
+}
+
+// AddClientRoles назначает пользователю роли клиента clientID.
+func (c *Client) AddClientRoles(ctx context.Context, userID types.UserID, clientID string, roles ...string) error {
+	url, err := c.clientRoleMappingsURL(ctx, userID, clientID)
+	if err != nil {
+		return err
+	}
+	resolved, err := c.resolveRoles(ctx, roles, func(ctx context.Context, name string) (*RoleRepresentation, error) {
+		return c.GetClientRole(ctx, clientID, name)
+	})
+	if err != nil {
+		return err
+	}
+	return c.changeRoleMappings(ctx, "add client roles", http.MethodPost, url, resolved)
+}
+
+// RemoveClientRoles снимает с пользователя роли клиента clientID.
+func (c *Client) RemoveClientRoles(ctx context.Context, userID types.UserID, clientID string, roles ...string) error {
+	url, err := c.clientRoleMappingsURL(ctx, userID, clientID)
+	if err != nil {
+		return err
+	}
+	resolved, err := c.resolveRoles(ctx, roles, func(ctx context.Context, name string) (*RoleRepresentation, error) {
+		return c.GetClientRole(ctx, clientID, name)
+	})
+	if err != nil {
+		return err
+	}
+	return c.changeRoleMappings(ctx, "remove client roles", http.MethodDelete, url, resolved)
+}
+
+func (c *Client) realmRoleMappingsURL(userID types.UserID) string {
+	return fmt.Sprintf("%s/admin/realms/%s/users/%s/role-mappings/realm", c.basePath, c.realm, userID.String())
+}
+
+func (c *Client) clientRoleMappingsURL(ctx context.Context, userID types.UserID, clientID string) (string, error) {
+	clientUUID, err := c.clientUUID(ctx, clientID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/admin/realms/%s/users/%s/role-mappings/clients/%s",
+		c.basePath, c.realm, userID.String(), clientUUID), nil
+}
+
+// clientUUID возвращает внутренний ID клиента. ID не меняется за время жизни клиента, поэтому кэшируется.
+func (c *Client) clientUUID(ctx context.Context, clientID string) (string, error) {
+	if id, ok := c.clientUUIDs.Load(clientID); ok {
+		return id.(string), nil
+	}
+
+	client, err := c.GetClient(ctx, clientID)
+	if err != nil {
+		return "", err
+	}
+	c.clientUUIDs.Store(clientID, client.ID)
+	return client.ID, nil
+}
+
+func (c *Client) getRole(ctx context.Context, op, url string) (*RoleRepresentation, error) {
+	var role RoleRepresentation
+	resp, err := c.adminRequest(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetResult(&role).
+			Get(url)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusOK:
+		return &role, nil
+	case http.StatusNotFound:
+		return nil, ErrRoleNotFound
+	default:
+		return nil, newAPIError(op, resp)
+	}
+}
+
+// resolveRoles загружает представления ролей по именам: Admin API назначает роли по ID.
+func (c *Client) resolveRoles(
+	ctx context.Context,
+	names []string,
+	get func(ctx context.Context, name string) (*RoleRepresentation, error),
+) ([]RoleRepresentation, error) {
+	roles := make([]RoleRepresentation, 0, len(names))
+	for _, name := range names {
+		role, err := get(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("resolve role %q: %w", name, err)
+		}
+		roles = append(roles, *role)
+	}
+	return roles, nil
+}
+
+func (c *Client) getRoleMappings(ctx context.Context, op, url string) ([]RoleRepresentation, error) {
+	var roles []RoleRepresentation
+	resp, err := c.adminRequest(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetResult(&roles).
+			Get(url)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusOK:
+		return roles, nil
+	case http.StatusNotFound:
+		return nil, ErrUserNotFound
+	default:
+		return nil, newAPIError(op, resp)
+	}
+}
+
+func (c *Client) changeRoleMappings(ctx context.Context, op, method, url string, roles []RoleRepresentation) error {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	resp, err := c.adminRequest(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetBody(roles).
+			Execute(method, url)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusNoContent, http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrUserNotFound
+	default:
+		return newAPIError(op, resp)
+	}
+}
@@ -0,0 +1,85 @@
+package keycloakclient_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/testingh/fakekeycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+func TestRoleMappings(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t,
+		fakekeycloak.WithRealmRoles("admin", "support"),
+		fakekeycloak.WithClientRoles("reports", "viewer", "editor"),
+	)
+	client := kc.NewClient(t)
+	user := kc.AddUser(fakekeycloak.User{Email: "jane@example.com", Enabled: true})
+	userID := types.MustParse[types.UserID](user.ID)
+
+	roleNames := func(roles []keycloakclient.RoleRepresentation) []string {
+		names := make([]string, 0, len(roles))
+		for _, role := range roles {
+			names = append(names, role.Name)
+		}
+		return names
+	}
+
+	t.Run("realm roles", func(t *testing.T) {
+		require.NoError(t, client.AddRealmRoles(ctx, userID, "admin", "support"))
+		roles, err := client.GetRealmRoleMappings(ctx, userID)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"admin", "support"}, roleNames(roles))
+
+		require.NoError(t, client.RemoveRealmRoles(ctx, userID, "admin"))
+		roles, err = client.GetRealmRoleMappings(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"support"}, roleNames(roles))
+
+		roles, err = client.GetEffectiveRealmRoles(ctx, userID)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"offline_access", "support"}, roleNames(roles))
+	})
+
+	t.Run("client roles", func(t *testing.T) {
+		require.NoError(t, client.AddClientRoles(ctx, userID, "reports", "viewer", "editor"))
+		roles, err := client.GetClientRoleMappings(ctx, userID, "reports")
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"viewer", "editor"}, roleNames(roles))
+		for _, role := range roles {
+			assert.True(t, role.ClientRole)
+		}
+
+		require.NoError(t, client.RemoveClientRoles(ctx, userID, "reports", "editor"))
+		roles, err = client.GetClientRoleMappings(ctx, userID, "reports")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"viewer"}, roleNames(roles))
+
+		stored, _ := kc.User(user.ID)
+		assert.Equal(t, []string{"viewer"}, stored.ClientRoles["reports"])
+	})
+
+	t.Run("unknown role or client", func(t *testing.T) {
+		err := client.AddRealmRoles(ctx, userID, "missing")
+		require.ErrorIs(t, err, keycloakclient.ErrRoleNotFound)
+
+		err = client.AddClientRoles(ctx, userID, "reports", "missing")
+		require.ErrorIs(t, err, keycloakclient.ErrRoleNotFound)
+
+		_, err = client.GetClientRoleMappings(ctx, userID, "missing-client")
+		require.ErrorIs(t, err, keycloakclient.ErrClientNotFound)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		_, err := client.GetRealmRoleMappings(ctx, types.NewUserID())
+		require.ErrorIs(t, err, keycloakclient.ErrUserNotFound)
+
+		_, err = client.GetEffectiveRealmRoles(ctx, types.NewUserID())
+		require.ErrorIs(t, err, keycloakclient.ErrUserNotFound)
+	})
+}
@@ -5,10 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Fisher-Development/woman-app-backend/internal/logger"
 	"github.com/Fisher-Development/woman-app-backend/internal/types"
+	"github.com/go-resty/resty/v2"
 	"go.uber.org/zap"
 )
 
@@ -38,6 +41,52 @@ type CreateUserResponse struct {
 	LastName  string       `json:"lastName"`
 }
 
+// Обязательные действия пользователя Keycloak.
+const (
+	RequiredActionUpdatePassword = "UPDATE_PASSWORD"
+	RequiredActionVerifyEmail    = "VERIFY_EMAIL"
+)
+
+// UserRepresentation пользователь в ответах Admin API.
+type UserRepresentation struct {
+	ID               string              `json:"id"`
+	Username         string              `json:"username"`
+	Email            string              `json:"email"`
+	FirstName        string              `json:"firstName"`
+	LastName         string              `json:"lastName"`
+	Enabled          bool                `json:"enabled"`
+	EmailVerified    bool                `json:"emailVerified"`
+	CreatedTimestamp int64               `json:"createdTimestamp"`
+	RequiredActions  []string            `json:"requiredActions,omitempty"`
+	Attributes       map[string][]string `json:"attributes,omitempty"`
+}
+
+// UpdateUserRequest изменение пользователя. Keycloak меняет только переданные поля,
+// поэтому незаданные (nil) поля не отправляются.
+type UpdateUserRequest struct {
+	Email           *string             `json:"email,omitempty"`
+	FirstName       *string             `json:"firstName,omitempty"`
+	LastName        *string             `json:"lastName,omitempty"`
+	Enabled         *bool               `json:"enabled,omitempty"`
+	EmailVerified   *bool               `json:"emailVerified,omitempty"`
+	RequiredActions []string            `json:"requiredActions,omitempty"`
+	Attributes      map[string][]string `json:"attributes,omitempty"`
+}
+
+// UserSessionRepresentation активная сессия пользователя.
+type UserSessionRepresentation struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	UserID   string `json:"userId"`
+	// IPAddress адрес, с которого была начата сессия
+	IPAddress string `json:"ipAddress"`
+	// Start и LastAccess время в миллисекундах Unix
+	Start      int64 `json:"start"`
+	LastAccess int64 `json:"lastAccess"`
+	// Clients клиенты сессии: внутренний ID клиента -> clientId
+	Clients map[string]string `json:"clients"`
+}
+
 // TokenResponse структура ответа при получении токена.
 type TokenResponse struct {
 	AccessToken      string `json:"access_token"`
@@ -52,25 +101,18 @@ type TokenResponse struct {
 
 // CreateUser создает нового пользователя в Keycloak.
 func (c *Client) CreateUser(ctx context.Context, req CreateUserRequest) (*CreateUserResponse, error) {
-	// Сначала получаем admin токен
-	adminToken, err := c.getAdminToken(ctx)
-	if err != nil {
-		logger.GetLogger().Error("Failed to get admin token", zap.Error(err))
-		return nil, fmt.Errorf("get admin token: %w", err)
-	}
-
 	url := fmt.Sprintf("%s/admin/realms/%s/users", c.basePath, c.realm)
 
-	resp, err := c.cli.R().
-		SetContext(ctx).
-		SetAuthToken(adminToken). // Используем Bearer токен
-		SetBody(req).
-		Post(url)
+	resp, err := c.adminRequest(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetBody(req).
+			Post(url)
+	})
 	if err != nil {
 		logger.GetLogger().Error("Failed to create user",
 			zap.String("url", url),
 			zap.Error(err))
-		return nil, fmt.Errorf("create user request failed: %w", err)
+		return nil, fmt.Errorf("create user: %w", err)
 	}
 
 	// Проверяем статус код
@@ -78,7 +120,7 @@ func (c *Client) CreateUser(ctx context.Context, req CreateUserRequest) (*Create
 		logger.GetLogger().Error("Create user failed with status",
 			zap.Int("status_code", resp.StatusCode()),
 			zap.String("response", string(resp.Body())))
-		return nil, fmt.Errorf("create user failed with status %d: %s", resp.StatusCode(), string(resp.Body()))
+		return nil, newAPIError("create user", resp)
 	}
 
 	// Keycloak возвращает Location header с ID пользователя
@@ -119,9 +161,214 @@ func (c *Client) CreateUser(ctx context.Context, req CreateUserRequest) (*Create
 	}, nil
 }
 
+// DeleteUser удаляет пользователя из Keycloak.
+// Возвращает ErrUserNotFound, если пользователя уже нет.
+func (c *Client) DeleteUser(ctx context.Context, userID types.UserID) error {
+	url := fmt.Sprintf("%s/admin/realms/%s/users/%s", c.basePath, c.realm, userID.String())
+
+	resp, err := c.adminRequest(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			Delete(url)
+	})
+	if err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusNoContent, http.StatusOK:
+		logger.GetLogger().Info("User deleted from Keycloak", zap.String("user_id", userID.String()))
+		return nil
+	case http.StatusNotFound:
+		return ErrUserNotFound
+	default:
+		return newAPIError("delete user", resp)
+	}
+}
+
+// ListUsers возвращает страницу пользователей realm, начиная с позиции first.
+func (c *Client) ListUsers(ctx context.Context, first, limit int) ([]UserRepresentation, error) {
+	url := fmt.Sprintf("%s/admin/realms/%s/users", c.basePath, c.realm)
+
+	var users []UserRepresentation
+	resp, err := c.adminRequest(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return setPage(r, first, limit).
+			SetResult(&users).
+			Get(url)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newAPIError("list users", resp)
+	}
+
+	return users, nil
+}
+
+// GetUser возвращает пользователя realm по ID.
+func (c *Client) GetUser(ctx context.Context, userID types.UserID) (*UserRepresentation, error) {
+	url := fmt.Sprintf("%s/admin/realms/%s/users/%s", c.basePath, c.realm, userID.String())
+
+	var user UserRepresentation
+	resp, err := c.adminRequest(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetResult(&user).
+			Get(url)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusOK:
+		return &user, nil
+	case http.StatusNotFound:
+		return nil, ErrUserNotFound
+	default:
+		return nil, newAPIError("get user", resp)
+	}
+}
+
+// UpdateUser изменяет профиль пользователя.
+func (c *Client) UpdateUser(ctx context.Context, userID types.UserID, req UpdateUserRequest) error {
+	url := fmt.Sprintf("%s/admin/realms/%s/users/%s", c.basePath, c.realm, userID.String())
+
+	resp, err := c.adminRequest(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetBody(req).
+			Put(url)
+	})
+	if err != nil {
+		return fmt.Errorf("update user: %w", err)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusNoContent, http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrUserNotFound
+	default:
+		return newAPIError("update user", resp)
+	}
+}
+
+// SetEnabled включает или отключает учетную запись пользователя.
+// Отключенный пользователь не может войти, но его активные сессии не завершаются.
+func (c *Client) SetEnabled(ctx context.Context, userID types.UserID, enabled bool) error {
+	return c.UpdateUser(ctx, userID, UpdateUserRequest{Enabled: &enabled})
+}
+
+// FindUsersByEmail ищет пользователей realm с точным совпадением email.
+func (c *Client) FindUsersByEmail(ctx context.Context, email string) ([]UserRepresentation, error) {
+	url := fmt.Sprintf("%s/admin/realms/%s/users", c.basePath, c.realm)
+
+	var users []UserRepresentation
+	resp, err := c.adminRequest(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetQueryParam("email", email).
+			SetQueryParam("exact", "true").
+			SetResult(&users).
+			Get(url)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("find users: %w", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newAPIError("find users", resp)
+	}
+
+	return users, nil
+}
+
+// ResetPassword устанавливает пользователю новый пароль.
+// При temporary=true Keycloak потребует сменить пароль при следующем входе.
+func (c *Client) ResetPassword(ctx context.Context, userID types.UserID, password string, temporary bool) error {
+	url := fmt.Sprintf("%s/admin/realms/%s/users/%s/reset-password", c.basePath, c.realm, userID.String())
+
+	resp, err := c.adminRequest(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetBody(UserCredentialRepresentation{
+				Type:      "password",
+				Value:     password,
+				Temporary: temporary,
+			}).
+			Put(url)
+	})
+	if err != nil {
+		return fmt.Errorf("reset password: %w", err)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusNoContent, http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrUserNotFound
+	default:
+		return newAPIError("reset password", resp)
+	}
+}
+
+// ExecuteActionsEmail отправляет пользователю письмо с обязательными действиями
+// (например, UPDATE_PASSWORD). lifespan - время жизни ссылки, 0 - значение realm по умолчанию.
+func (c *Client) ExecuteActionsEmail(
+	ctx context.Context,
+	userID types.UserID,
+	actions []string,
+	lifespan time.Duration,
+) error {
+	url := fmt.Sprintf("%s/admin/realms/%s/users/%s/execute-actions-email", c.basePath, c.realm, userID.String())
+
+	resp, err := c.adminRequest(ctx, func(r *resty.Request) (*resty.Response, error) {
+		r.SetBody(actions)
+		if lifespan > 0 {
+			r.SetQueryParam("lifespan", strconv.Itoa(int(lifespan.Seconds())))
+		}
+		return r.Put(url)
+	})
+	if err != nil {
+		return fmt.Errorf("execute actions email: %w", err)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusNoContent, http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrUserNotFound
+	default:
+		return newAPIError("execute actions email", resp)
+	}
+}
+
+// ListUserSessions возвращает активные сессии пользователя.
+func (c *Client) ListUserSessions(ctx context.Context, userID types.UserID) ([]UserSessionRepresentation, error) {
+	url := fmt.Sprintf("%s/admin/realms/%s/users/%s/sessions", c.basePath, c.realm, userID.String())
+
+	var sessions []UserSessionRepresentation
+	resp, err := c.adminRequest(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetResult(&sessions).
+			Get(url)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list user sessions: %w", err)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusOK:
+		return sessions, nil
+	case http.StatusNotFound:
+		return nil, ErrUserNotFound
+	default:
+		return nil, newAPIError("list user sessions", resp)
+	}
+}
+
 // LoginUser аутентифицирует пользователя.
 func (c *Client) LoginUser(ctx context.Context, email, password string) (*TokenResponse, error) {
-	url := fmt.Sprintf("%s/realms/%s/protocol/openid-connect/token", c.basePath, c.realm)
+	url, err := c.tokenEndpoint(ctx, "login")
+	if err != nil {
+		return nil, err
+	}
 
 	var tokenResp TokenResponse
 
@@ -138,11 +385,11 @@ func (c *Client) LoginUser(ctx context.Context, email, password string) (*TokenR
 		SetResult(&tokenResp).
 		Post(url)
 	if err != nil {
-		return nil, fmt.Errorf("send login request: %v", err)
+		return nil, unavailableError("login", err)
 	}
 
 	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("login failed: %v - %s", resp.Status(), resp.String())
+		return nil, newAPIError("login", resp)
 	}
 
 	return &tokenResp, nil
@@ -150,7 +397,10 @@ func (c *Client) LoginUser(ctx context.Context, email, password string) (*TokenR
 
 // RefreshAccessToken обновляет access token используя refresh token.
 func (c *Client) RefreshAccessToken(ctx context.Context, refreshToken string) (*TokenResponse, error) {
-	url := fmt.Sprintf("%s/realms/%s/protocol/openid-connect/token", c.basePath, c.realm)
+	url, err := c.tokenEndpoint(ctx, "refresh token")
+	if err != nil {
+		return nil, err
+	}
 
 	var tokenResp TokenResponse
 
@@ -166,48 +416,61 @@ func (c *Client) RefreshAccessToken(ctx context.Context, refreshToken string) (*
 		SetResult(&tokenResp).
 		Post(url)
 	if err != nil {
-		return nil, fmt.Errorf("refresh token request: %v", err)
+		return nil, unavailableError("refresh token", err)
 	}
 
 	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("refresh failed: %v - %s", resp.Status(), resp.String())
+		return nil, newAPIError("refresh token", resp)
 	}
 
 	return &tokenResp, nil
 }
 
-// getAdminToken получает токен для Admin API.
-func (c *Client) getAdminToken(ctx context.Context) (string, error) {
-	url := fmt.Sprintf("%s/realms/%s/protocol/openid-connect/token", c.basePath, c.realm)
-
-	type AdminTokenResponse struct {
-		AccessToken string `json:"access_token"`
-		TokenType   string `json:"token_type"`
-		ExpiresIn   int    `json:"expires_in"`
+// Logout завершает сессию пользователя в Keycloak, отзывая refresh token.
+func (c *Client) Logout(ctx context.Context, refreshToken string) error {
+	url, err := c.endpoint(ctx, "logout", func(o *OpenIDConfiguration) string { return o.EndSessionEndpoint })
+	if err != nil {
+		return err
 	}
 
-	var tokenResp AdminTokenResponse
-
 	resp, err := c.cli.R().
 		SetContext(ctx).
 		SetHeader("Content-Type", "application/x-www-form-urlencoded").
 		SetFormData(map[string]string{
-			"grant_type":    "client_credentials",
+			"refresh_token": refreshToken,
 			"client_id":     c.clientID,
 			"client_secret": c.clientSecret,
 		}).
-		SetResult(&tokenResp).
 		Post(url)
 	if err != nil {
-		return "", fmt.Errorf("send token request: %v", err)
+		return unavailableError("logout", err)
 	}
 
-	if resp.StatusCode() != http.StatusOK {
-		logger.GetLogger().Error("Failed to get admin token",
-			zap.Int("status_code", resp.StatusCode()),
-			zap.String("response", string(resp.Body())))
-		return "", fmt.Errorf("token request failed: %v - %s", resp.Status(), resp.String())
+	if resp.StatusCode() != http.StatusNoContent && resp.StatusCode() != http.StatusOK {
+		return newAPIError("logout", resp)
 	}
 
-	return tokenResp.AccessToken, nil
+	return nil
+}
+
+// LogoutUser завершает все сессии пользователя через Admin API.
+func (c *Client) LogoutUser(ctx context.Context, userID types.UserID) error {
+	url := fmt.Sprintf("%s/admin/realms/%s/users/%s/logout", c.basePath, c.realm, userID.String())
+
+	resp, err := c.adminRequest(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			Post(url)
+	})
+	if err != nil {
+		return fmt.Errorf("logout user: %w", err)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusNoContent, http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrUserNotFound
+	default:
+		return newAPIError("logout user", resp)
+	}
 }
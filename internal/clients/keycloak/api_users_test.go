@@ -0,0 +1,98 @@
+package keycloakclient_test
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/testingh/fakekeycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+func TestAdminUserManagement(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t)
+
+	created, err := client.CreateUser(ctx, keycloakclient.CreateUserRequest{
+		Username: "jane@example.com",
+		Email:    "jane@example.com",
+		Enabled:  true,
+		Credentials: []keycloakclient.UserCredentialRepresentation{
+			{Type: "password", Value: "password"},
+		},
+	})
+	require.NoError(t, err)
+	userID := created.UserID
+
+	t.Run("get and find", func(t *testing.T) {
+		user, err := client.GetUser(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, "jane@example.com", user.Email)
+
+		found, err := client.FindUsersByEmail(ctx, "JANE@example.com")
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		assert.Equal(t, userID.String(), found[0].ID)
+	})
+
+	t.Run("update only given fields", func(t *testing.T) {
+		firstName := "Jane"
+		require.NoError(t, client.UpdateUser(ctx, userID, keycloakclient.UpdateUserRequest{FirstName: &firstName}))
+
+		user, err := client.GetUser(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, "Jane", user.FirstName)
+		assert.Equal(t, "jane@example.com", user.Email)
+		assert.True(t, user.Enabled)
+	})
+
+	t.Run("disable and enable", func(t *testing.T) {
+		require.NoError(t, client.SetEnabled(ctx, userID, false))
+		_, err := client.LoginUser(ctx, "jane@example.com", "password")
+		require.ErrorIs(t, err, keycloakclient.ErrUserDisabled)
+
+		require.NoError(t, client.SetEnabled(ctx, userID, true))
+		_, err = client.LoginUser(ctx, "jane@example.com", "password")
+		require.NoError(t, err)
+	})
+
+	t.Run("sessions and logout", func(t *testing.T) {
+		require.NoError(t, client.LogoutUser(ctx, userID))
+		for i := 0; i < 2; i++ {
+			_, err := client.LoginUser(ctx, "jane@example.com", "password")
+			require.NoError(t, err)
+		}
+
+		sessions, err := client.ListUserSessions(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, sessions, 2)
+		assert.Equal(t, userID.String(), sessions[0].UserID)
+		assert.Contains(t, slices.Collect(maps.Values(sessions[0].Clients)), kc.ClientID())
+
+		require.NoError(t, client.LogoutUser(ctx, userID))
+		sessions, err = client.ListUserSessions(ctx, userID)
+		require.NoError(t, err)
+		assert.Empty(t, sessions)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, client.DeleteUser(ctx, userID))
+		_, err := client.GetUser(ctx, userID)
+		require.ErrorIs(t, err, keycloakclient.ErrUserNotFound)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		unknown := types.NewUserID()
+		enabled := true
+		assert.ErrorIs(t, client.UpdateUser(ctx, unknown, keycloakclient.UpdateUserRequest{Enabled: &enabled}),
+			keycloakclient.ErrUserNotFound)
+		_, err := client.ListUserSessions(ctx, unknown)
+		assert.ErrorIs(t, err, keycloakclient.ErrUserNotFound)
+	})
+}
@@ -2,8 +2,16 @@ package keycloakclient
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-resty/resty/v2"
+	"go.uber.org/zap"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/logger"
 )
 
 //go:generate options-gen -out-filename=client_options.gen.go -from-struct=Options
@@ -15,6 +23,23 @@ type Options struct {
 	username     string
 	password     string
 	debugMode    bool
+	// name имя клиента в логах и метриках
+	name string
+	// timeout таймаут одной попытки запроса
+	timeout time.Duration
+	// maxRetries число повторов идемпотентных запросов при 5xx и ошибках соединения.
+	// nil - значение по умолчанию, 0 - без повторов
+	maxRetries   *int
+	retryWaitMin time.Duration
+	retryWaitMax time.Duration
+	// breakerThreshold число неудачных запросов подряд, после которого breaker размыкается
+	breakerThreshold   int
+	breakerOpenTimeout time.Duration
+	// issuer ожидаемый issuer провайдера, по нему загружается discovery документ.
+	// По умолчанию {basePath}/realms/{realm}
+	issuer string
+	// discoveryTTL как долго discovery документ считается актуальным
+	discoveryTTL time.Duration
 }
 
 // Client is a tiny client to the KeyCloak realm operations. UMA configuration:
@@ -30,8 +55,20 @@ type Client struct {
 	username     string
 	password     string
 	debugMode    bool
+	issuer       string
 
 	cli *resty.Client
+
+	// discovery кэш discovery документа, из которого берутся OIDC эндпоинты
+	discovery *discoveryCache
+
+	breaker *circuitBreaker
+	retries atomic.Int64
+
+	// adminToken общий кэш токена для всех методов Admin API
+	adminToken *adminTokenSource
+	// clientUUIDs кэш внутренних ID клиентов realm: clientId -> ID
+	clientUUIDs sync.Map
 }
 
 func New(opts Options) (*Client, error) {
@@ -39,11 +76,22 @@ func New(opts Options) (*Client, error) {
 		return nil, fmt.Errorf("validate options: %v", err)
 	}
 
+	opts.setDefaults()
+	breaker := newCircuitBreaker(opts.name, opts.breakerThreshold, opts.breakerOpenTimeout)
+
 	cli := resty.New()
 	cli.SetDebug(opts.debugMode)
 	cli.SetBaseURL(opts.basePath)
+	cli.SetTimeout(opts.timeout)
+	cli.SetTransport(&breakerTransport{next: http.DefaultTransport.(*http.Transport).Clone(), breaker: breaker})
+	// Между повторами resty ждет экспоненциально растущую паузу со случайным разбросом
+	cli.SetRetryCount(*opts.maxRetries)
+	cli.SetRetryWaitTime(opts.retryWaitMin)
+	cli.SetRetryMaxWaitTime(opts.retryWaitMax)
+	cli.AddRetryCondition(retryCondition)
+	cli.OnBeforeRequest(withCallerContext)
 
-	return &Client{
+	c := &Client{
 		// opts: opts,
 
 		// Копируем значения из Options в прямые поля
@@ -54,7 +102,55 @@ func New(opts Options) (*Client, error) {
 		username:     opts.username,
 		password:     opts.password,
 		debugMode:    opts.debugMode,
+		issuer:       strings.TrimSuffix(opts.issuer, "/"),
+
+		cli:       cli,
+		breaker:   breaker,
+		discovery: &discoveryCache{ttl: opts.discoveryTTL, now: time.Now},
+	}
+	cli.AddRetryHook(func(resp *resty.Response, err error) {
+		// resty вызывает хук и после последней попытки, когда повтора уже не будет
+		if resp != nil && resp.Request != nil && resp.Request.Attempt > *opts.maxRetries {
+			return
+		}
+		c.retries.Add(1)
+		logger.GetLogger().Warn("Retrying Keycloak request",
+			zap.String("client", opts.name),
+			zap.Error(err))
+	})
+	c.adminToken = newAdminTokenSource(c.fetchAdminToken)
+
+	return c, nil
+}
 
-		cli: cli,
-	}, nil
+// setDefaults подставляет значения по умолчанию для незаданных настроек устойчивости.
+func (o *Options) setDefaults() {
+	if o.name == "" {
+		o.name = defaultName
+	}
+	if o.timeout <= 0 {
+		o.timeout = defaultTimeout
+	}
+	if o.maxRetries == nil || *o.maxRetries < 0 {
+		maxRetries := defaultMaxRetries
+		o.maxRetries = &maxRetries
+	}
+	if o.retryWaitMin <= 0 {
+		o.retryWaitMin = defaultRetryWaitMin
+	}
+	if o.retryWaitMax <= 0 {
+		o.retryWaitMax = defaultRetryWaitMax
+	}
+	if o.breakerThreshold <= 0 {
+		o.breakerThreshold = defaultBreakerThreshold
+	}
+	if o.breakerOpenTimeout <= 0 {
+		o.breakerOpenTimeout = defaultBreakerOpenTimeout
+	}
+	if o.issuer == "" {
+		o.issuer = fmt.Sprintf("%s/realms/%s", strings.TrimSuffix(o.basePath, "/"), o.realm)
+	}
+	if o.discoveryTTL <= 0 {
+		o.discoveryTTL = defaultDiscoveryTTL
+	}
 }
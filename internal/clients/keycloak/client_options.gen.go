@@ -3,6 +3,7 @@ package keycloakclient
 
 import (
 	fmt461e464ebed9 "fmt"
+	"time"
 
 	errors461e464ebed9 "github.com/kazhuravlev/options-gen/pkg/errors"
 	validator461e464ebed9 "github.com/kazhuravlev/options-gen/pkg/validator"
@@ -56,6 +57,69 @@ func WithDebugMode(opt bool) OptOptionsSetter {
 	}
 }
 
+func WithName(opt string) OptOptionsSetter {
+	return func(o *Options) {
+		o.name = opt
+
+	}
+}
+
+func WithTimeout(opt time.Duration) OptOptionsSetter {
+	return func(o *Options) {
+		o.timeout = opt
+
+	}
+}
+
+func WithMaxRetries(opt *int) OptOptionsSetter {
+	return func(o *Options) {
+		o.maxRetries = opt
+
+	}
+}
+
+func WithRetryWaitMin(opt time.Duration) OptOptionsSetter {
+	return func(o *Options) {
+		o.retryWaitMin = opt
+
+	}
+}
+
+func WithRetryWaitMax(opt time.Duration) OptOptionsSetter {
+	return func(o *Options) {
+		o.retryWaitMax = opt
+
+	}
+}
+
+func WithBreakerThreshold(opt int) OptOptionsSetter {
+	return func(o *Options) {
+		o.breakerThreshold = opt
+
+	}
+}
+
+func WithBreakerOpenTimeout(opt time.Duration) OptOptionsSetter {
+	return func(o *Options) {
+		o.breakerOpenTimeout = opt
+
+	}
+}
+
+func WithIssuer(opt string) OptOptionsSetter {
+	return func(o *Options) {
+		o.issuer = opt
+
+	}
+}
+
+func WithDiscoveryTTL(opt time.Duration) OptOptionsSetter {
+	return func(o *Options) {
+		o.discoveryTTL = opt
+
+	}
+}
+
 func (o *Options) Validate() error {
 	errs := new(errors461e464ebed9.ValidationErrors)
 	errs.Add(errors461e464ebed9.NewValidationError("basePath", _validate_Options_basePath(o)))
@@ -0,0 +1,151 @@
+package keycloakclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/logger"
+)
+
+const defaultDiscoveryTTL = time.Hour
+
+// ErrEndpointNotSupported провайдер не объявил нужный эндпоинт в discovery документе.
+var ErrEndpointNotSupported = errors.New("endpoint is not supported by the provider")
+
+// ErrInvalidDiscovery discovery документ не прошел проверку.
+var ErrInvalidDiscovery = errors.New("invalid openid configuration")
+
+// asymmetricAlgPrefixes семейства алгоритмов с открытым ключом: только такие подписи можно проверить по JWKS.
+var asymmetricAlgPrefixes = []string{"RS", "PS", "ES"}
+
+// discoveryCache кэш discovery документа провайдера.
+type discoveryCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	// mu удерживается на время загрузки, чтобы параллельные вызовы не запрашивали документ повторно
+	mu        sync.Mutex
+	config    *OpenIDConfiguration
+	fetchedAt time.Time
+}
+
+// OpenIDConfiguration возвращает discovery документ провайдера.
+// Документ загружается один раз и перечитывается после истечения TTL;
+// если обновить его не удалось, возвращается последняя успешно загруженная версия.
+func (c *Client) OpenIDConfiguration(ctx context.Context) (*OpenIDConfiguration, error) {
+	d := c.discovery
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.config != nil && d.now().Sub(d.fetchedAt) < d.ttl {
+		return d.config, nil
+	}
+
+	config, err := c.fetchOpenIDConfiguration(ctx)
+	if err != nil {
+		if d.config != nil {
+			logger.GetLogger().Warn("Failed to refresh OpenID configuration, using cached one",
+				zap.String("issuer", c.issuer),
+				zap.Error(err))
+			return d.config, nil
+		}
+		return nil, err
+	}
+
+	d.config = config
+	d.fetchedAt = d.now()
+	return config, nil
+}
+
+func (c *Client) fetchOpenIDConfiguration(ctx context.Context) (*OpenIDConfiguration, error) {
+	var result OpenIDConfiguration
+	resp, err := c.cli.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(c.issuer + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, unavailableError("openid configuration", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newAPIError("openid configuration", resp)
+	}
+
+	if err := result.validate(c.issuer); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// validate проверяет, что документ выдан ожидаемым issuer, содержит обязательные эндпоинты
+// и объявляет хотя бы один алгоритм подписи с открытым ключом.
+func (o *OpenIDConfiguration) validate(issuer string) error {
+	if strings.TrimSuffix(o.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return fmt.Errorf("%w: issuer %q, expected %q", ErrInvalidDiscovery, o.Issuer, issuer)
+	}
+
+	endpoints := map[string]string{
+		"token_endpoint":         o.TokenEndpoint,
+		"jwks_uri":               o.JWKSURI,
+		"authorization_endpoint": o.AuthorizationEndpoint,
+		"introspection_endpoint": o.IntrospectionEndpoint,
+		"revocation_endpoint":    o.RevocationEndpoint,
+		"end_session_endpoint":   o.EndSessionEndpoint,
+		"userinfo_endpoint":      o.UserinfoEndpoint,
+	}
+	for name, endpoint := range endpoints {
+		required := name == "token_endpoint" || name == "jwks_uri"
+		if endpoint == "" && !required {
+			continue
+		}
+		if u, err := url.Parse(endpoint); err != nil || !u.IsAbs() {
+			return fmt.Errorf("%w: %s %q is not an absolute URL", ErrInvalidDiscovery, name, endpoint)
+		}
+	}
+
+	if len(o.IDTokenSigningAlgValuesSupported) > 0 && len(o.SigningAlgorithms()) == 0 {
+		return fmt.Errorf("%w: no asymmetric signing algorithm in %v",
+			ErrInvalidDiscovery, o.IDTokenSigningAlgValuesSupported)
+	}
+	return nil
+}
+
+// SigningAlgorithms возвращает объявленные провайдером алгоритмы подписи с открытым ключом.
+func (o *OpenIDConfiguration) SigningAlgorithms() []string {
+	var algs []string
+	for _, alg := range o.IDTokenSigningAlgValuesSupported {
+		for _, prefix := range asymmetricAlgPrefixes {
+			if strings.HasPrefix(alg, prefix) {
+				algs = append(algs, alg)
+				break
+			}
+		}
+	}
+	return algs
+}
+
+// endpoint возвращает эндпоинт из discovery документа, выбранный get.
+func (c *Client) endpoint(ctx context.Context, op string, get func(*OpenIDConfiguration) string) (string, error) {
+	config, err := c.OpenIDConfiguration(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	endpoint := get(config)
+	if endpoint == "" {
+		return "", fmt.Errorf("%s: %w", op, ErrEndpointNotSupported)
+	}
+	return endpoint, nil
+}
+
+func (c *Client) tokenEndpoint(ctx context.Context, op string) (string, error) {
+	return c.endpoint(ctx, op, func(o *OpenIDConfiguration) string { return o.TokenEndpoint })
+}
@@ -0,0 +1,133 @@
+package keycloakclient_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/testingh/fakekeycloak"
+)
+
+func TestDiscoveryIsCached(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t)
+	kc.AddUser(fakekeycloak.User{Email: "jane@example.com", Password: "password", Enabled: true})
+
+	for i := 0; i < 3; i++ {
+		_, err := client.LoginUser(ctx, "jane@example.com", "password")
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 1, kc.Requests(fakekeycloak.EndpointDiscovery))
+}
+
+func TestDiscoveryRefresh(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t, keycloakclient.WithDiscoveryTTL(50*time.Millisecond))
+
+	_, err := client.OpenIDConfiguration(ctx)
+	require.NoError(t, err)
+
+	t.Run("expired document is reloaded", func(t *testing.T) {
+		time.Sleep(60 * time.Millisecond)
+		_, err := client.OpenIDConfiguration(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, kc.Requests(fakekeycloak.EndpointDiscovery))
+	})
+
+	t.Run("stale document is used when reload fails", func(t *testing.T) {
+		kc.Fail(fakekeycloak.EndpointDiscovery, fakekeycloak.Fault{Status: http.StatusBadRequest})
+		defer kc.ClearFaults()
+		time.Sleep(60 * time.Millisecond)
+
+		config, err := client.OpenIDConfiguration(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, kc.Issuer(), config.Issuer)
+	})
+}
+
+func TestDiscoveryValidation(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "foreign issuer",
+			body: `{"issuer":"https://evil.example.com/realms/Testing",
+				"token_endpoint":"https://evil.example.com/token","jwks_uri":"https://evil.example.com/certs"}`,
+		},
+		{
+			name: "no token endpoint",
+			body: `{"issuer":"%s","jwks_uri":"%s/certs"}`,
+		},
+		{
+			name: "relative endpoint",
+			body: `{"issuer":"%s","token_endpoint":"/token","jwks_uri":"%s/certs"}`,
+		},
+		{
+			name: "only symmetric algorithms",
+			body: `{"issuer":"%s","token_endpoint":"%s/token","jwks_uri":"%s/certs",
+				"id_token_signing_alg_values_supported":["HS256","none"]}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			kc := fakekeycloak.New(t)
+			client := kc.NewClient(t)
+			kc.Fail(fakekeycloak.EndpointDiscovery, fakekeycloak.Fault{
+				Status: http.StatusOK,
+				Body:   fillIssuer(tc.body, kc.Issuer()),
+			})
+
+			_, err := client.OpenIDConfiguration(ctx)
+			require.ErrorIs(t, err, keycloakclient.ErrInvalidDiscovery)
+
+			_, err = client.LoginUser(ctx, "jane@example.com", "password")
+			require.ErrorIs(t, err, keycloakclient.ErrInvalidDiscovery)
+		})
+	}
+}
+
+func TestPathPrefixedProvider(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t, fakekeycloak.WithPathPrefix("/be"))
+	client := kc.NewClient(t)
+	user := kc.AddUser(fakekeycloak.User{Email: "jane@example.com", Password: "password", Enabled: true})
+
+	tokens, err := client.LoginUser(ctx, "jane@example.com", "password")
+	require.NoError(t, err)
+
+	info, err := client.UserInfo(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, info.Subject)
+
+	introspection, err := client.IntrospectToken(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.True(t, introspection.Active)
+
+	_, err = client.ListUsers(ctx, 0, 10)
+	require.NoError(t, err)
+
+	t.Run("revoke", func(t *testing.T) {
+		require.NoError(t, client.RevokeToken(ctx, tokens.RefreshToken, keycloakclient.TokenTypeHintRefreshToken))
+
+		_, err := client.RefreshAccessToken(ctx, tokens.RefreshToken)
+		require.ErrorIs(t, err, keycloakclient.ErrInvalidGrant)
+	})
+}
+
+// fillIssuer подставляет issuer вместо всех %s в шаблоне.
+func fillIssuer(template, issuer string) string {
+	return strings.ReplaceAll(template, "%s", issuer)
+}
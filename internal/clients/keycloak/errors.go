@@ -0,0 +1,108 @@
+package keycloakclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Ошибки Keycloak, по которым вызывающий код выбирает реакцию.
+// Проверяются через errors.Is, в том числе для *APIError.
+var (
+	// ErrUserNotFound пользователь не найден в Keycloak.
+	ErrUserNotFound = errors.New("keycloak user not found")
+	// ErrRoleNotFound роль не найдена в realm или клиенте.
+	ErrRoleNotFound = errors.New("keycloak role not found")
+	// ErrClientNotFound клиент с указанным clientId не найден в realm.
+	ErrClientNotFound = errors.New("keycloak client not found")
+	// ErrInvalidCredentials неверный логин или пароль.
+	ErrInvalidCredentials = errors.New("invalid user credentials")
+	// ErrUserDisabled учетная запись пользователя отключена.
+	ErrUserDisabled = errors.New("user account is disabled")
+	// ErrUserExists пользователь с таким username или email уже существует.
+	ErrUserExists = errors.New("user already exists")
+	// ErrInvalidGrant Keycloak отклонил grant: истекший или отозванный refresh token,
+	// незавершенная настройка учетной записи и т.п.
+	ErrInvalidGrant = errors.New("invalid grant")
+	// ErrUnavailable Keycloak недоступен: ошибка соединения, таймаут, 5xx или открытый circuit breaker.
+	ErrUnavailable = errors.New("keycloak unavailable")
+)
+
+// Описания ошибки invalid_grant, которые Keycloak возвращает для password grant.
+const (
+	descInvalidCredentials = "Invalid user credentials"
+	descAccountDisabled    = "Account disabled"
+)
+
+// APIError ответ Keycloak с неуспешным статусом.
+type APIError struct {
+	// Op операция клиента, в которой получена ошибка.
+	Op string
+	// StatusCode HTTP статус ответа.
+	StatusCode int
+	// Code OAuth код ошибки ("error"), например invalid_grant.
+	Code string
+	// Description описание ошибки ("error_description" или "errorMessage" в Admin API).
+	Description string
+
+	// kind одна из ошибок пакета, которой соответствует ответ.
+	kind error
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s: keycloak responded with status %d", e.Op, e.StatusCode)
+	if e.Code != "" {
+		msg += ": " + e.Code
+	}
+	if e.Description != "" {
+		msg += ": " + e.Description
+	}
+	return msg
+}
+
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+// newAPIError разбирает тело ответа Keycloak и определяет вид ошибки.
+func newAPIError(op string, resp *resty.Response) *APIError {
+	var body struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"` //nolint:tagliatelle // OAuth 2.0 format
+		ErrorMessage     string `json:"errorMessage"`
+	}
+	_ = json.Unmarshal(resp.Body(), &body)
+
+	e := &APIError{
+		Op:          op,
+		StatusCode:  resp.StatusCode(),
+		Code:        body.Error,
+		Description: body.ErrorDescription,
+	}
+	if e.Description == "" {
+		e.Description = body.ErrorMessage
+	}
+
+	switch {
+	case e.StatusCode >= http.StatusInternalServerError:
+		e.kind = ErrUnavailable
+	case e.StatusCode == http.StatusConflict:
+		e.kind = ErrUserExists
+	case e.Code == "invalid_grant" && e.Description == descInvalidCredentials:
+		e.kind = ErrInvalidCredentials
+	case e.Code == "invalid_grant" && e.Description == descAccountDisabled:
+		e.kind = ErrUserDisabled
+	case e.Code == "invalid_grant":
+		e.kind = ErrInvalidGrant
+	}
+
+	return e
+}
+
+// unavailableError оборачивает ошибку отправки запроса, сохраняя исходную причину.
+func unavailableError(op string, err error) error {
+	return fmt.Errorf("%s: %w: %w", op, ErrUnavailable, err)
+}
@@ -0,0 +1,110 @@
+package keycloakclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/testingh/fakekeycloak"
+)
+
+func TestClientErrors(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t, maxRetries(1))
+
+	kc.AddUser(fakekeycloak.User{Username: "active@example.com", Email: "active@example.com", Password: "password", Enabled: true})
+	kc.AddUser(fakekeycloak.User{Username: "disabled@example.com", Email: "disabled@example.com", Password: "password"})
+
+	cases := []struct {
+		name       string
+		call       func() error
+		wantErr    error
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name: "invalid credentials",
+			call: func() error {
+				_, err := client.LoginUser(ctx, "active@example.com", "wrong")
+				return err
+			},
+			wantErr:    keycloakclient.ErrInvalidCredentials,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   "invalid_grant",
+		},
+		{
+			name: "user disabled",
+			call: func() error {
+				_, err := client.LoginUser(ctx, "disabled@example.com", "password")
+				return err
+			},
+			wantErr:    keycloakclient.ErrUserDisabled,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_grant",
+		},
+		{
+			name: "invalid refresh token",
+			call: func() error {
+				_, err := client.RefreshAccessToken(ctx, "garbage")
+				return err
+			},
+			wantErr:    keycloakclient.ErrInvalidGrant,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_grant",
+		},
+		{
+			name: "user exists",
+			call: func() error {
+				_, err := client.CreateUser(ctx, keycloakclient.CreateUserRequest{
+					Username: "active@example.com",
+					Email:    "active@example.com",
+					Enabled:  true,
+				})
+				return err
+			},
+			wantErr:    keycloakclient.ErrUserExists,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name: "server error",
+			call: func() error {
+				kc.Fail(fakekeycloak.EndpointToken, fakekeycloak.Fault{Status: http.StatusServiceUnavailable, Times: 1})
+				_, err := client.LoginUser(ctx, "active@example.com", "password")
+				return err
+			},
+			wantErr:    keycloakclient.ErrUnavailable,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name: "dropped connection",
+			call: func() error {
+				kc.Fail(fakekeycloak.EndpointToken, fakekeycloak.Fault{Drop: true, Times: 1})
+				_, err := client.LoginUser(ctx, "active@example.com", "password")
+				return err
+			},
+			wantErr: keycloakclient.ErrUnavailable,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call()
+			require.ErrorIs(t, err, tc.wantErr)
+
+			var apiErr *keycloakclient.APIError
+			if tc.wantStatus == 0 {
+				assert.False(t, errors.As(err, &apiErr))
+				return
+			}
+			require.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, tc.wantStatus, apiErr.StatusCode)
+			assert.Equal(t, tc.wantCode, apiErr.Code)
+		})
+	}
+}
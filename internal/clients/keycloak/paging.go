@@ -0,0 +1,44 @@
+package keycloakclient
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// DefaultPageSize размер страницы Admin API по умолчанию.
+const DefaultPageSize = 100
+
+// ListFunc загружает страницу из max элементов, начиная с позиции first.
+type ListFunc[T any] func(ctx context.Context, first, max int) ([]T, error)
+
+// ListAll постранично загружает все элементы, пока не придет неполная страница.
+// pageSize <= 0 означает DefaultPageSize.
+func ListAll[T any](ctx context.Context, pageSize int, list ListFunc[T]) ([]T, error) {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	var all []T
+	for first := 0; ; first += pageSize {
+		page, err := list(ctx, first, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < pageSize {
+			return all, nil
+		}
+	}
+}
+
+// setPage добавляет к запросу параметры постраничного чтения Admin API.
+func setPage(r *resty.Request, first, max int) *resty.Request {
+	if max <= 0 {
+		max = DefaultPageSize
+	}
+	return r.
+		SetQueryParam("first", strconv.Itoa(first)).
+		SetQueryParam("max", strconv.Itoa(max))
+}
@@ -0,0 +1,57 @@
+package keycloakclient_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+)
+
+func TestListAll(t *testing.T) {
+	source := make([]int, 25)
+	for i := range source {
+		source[i] = i
+	}
+
+	cases := []struct {
+		name      string
+		total     int
+		pageSize  int
+		wantCalls int
+	}{
+		{name: "several pages", total: 25, pageSize: 10, wantCalls: 3},
+		{name: "exact multiple needs empty page", total: 20, pageSize: 10, wantCalls: 3},
+		{name: "single page", total: 5, pageSize: 10, wantCalls: 1},
+		{name: "empty", total: 0, pageSize: 10, wantCalls: 1},
+		{name: "default page size", total: 25, pageSize: 0, wantCalls: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			got, err := keycloakclient.ListAll(context.Background(), tc.pageSize,
+				func(_ context.Context, first, max int) ([]int, error) {
+					calls++
+					end := min(first+max, tc.total)
+					if first >= end {
+						return nil, nil
+					}
+					return source[first:end], nil
+				})
+			require.NoError(t, err)
+			assert.Equal(t, source[:tc.total], append([]int{}, got...))
+			assert.Equal(t, tc.wantCalls, calls)
+		})
+	}
+
+	t.Run("error stops paging", func(t *testing.T) {
+		errList := errors.New("list failed")
+		_, err := keycloakclient.ListAll(context.Background(), 10,
+			func(context.Context, int, int) ([]int, error) { return nil, errList })
+		require.ErrorIs(t, err, errList)
+	})
+}
@@ -0,0 +1,242 @@
+package keycloakclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"go.uber.org/zap"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/logger"
+)
+
+const (
+	defaultName               = "keycloak"
+	defaultTimeout            = 10 * time.Second
+	defaultMaxRetries         = 2
+	defaultRetryWaitMin       = 100 * time.Millisecond
+	defaultRetryWaitMax       = 2 * time.Second
+	defaultBreakerThreshold   = 5
+	defaultBreakerOpenTimeout = 30 * time.Second
+)
+
+// ErrCircuitOpen Keycloak недоступен: запрос отклонен без обращения к серверу.
+var ErrCircuitOpen = errors.New("keycloak circuit breaker is open")
+
+// BreakerState состояние circuit breaker.
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// ClientStats метрики устойчивости клиента для /debug/vars.
+type ClientStats struct {
+	BreakerState        BreakerState `json:"breakerState"`
+	ConsecutiveFailures int          `json:"consecutiveFailures"`
+	Retries             int64        `json:"retries"`
+	Rejected            int64        `json:"rejected"`
+}
+
+// circuitBreaker размыкается после threshold подряд неудачных запросов
+// (ошибки соединения и 5xx) и отклоняет запросы в течение openTimeout.
+// Затем пропускает один пробный запрос: успех замыкает цепь, неудача снова размыкает.
+type circuitBreaker struct {
+	name        string
+	threshold   int
+	openTimeout time.Duration
+	now         func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+
+	rejected atomic.Int64
+}
+
+func newCircuitBreaker(name string, threshold int, openTimeout time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		name:        name,
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		now:         time.Now,
+		state:       BreakerClosed,
+	}
+}
+
+// allow решает, можно ли отправить запрос.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			b.rejected.Add(1)
+			return ErrCircuitOpen
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		// Пока пробный запрос не завершился, остальные отклоняются
+		if b.probing {
+			b.rejected.Add(1)
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// record учитывает результат запроса.
+func (b *circuitBreaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		b.failures = 0
+		if b.state != BreakerClosed {
+			b.setState(BreakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		if b.state != BreakerOpen {
+			b.setState(BreakerOpen)
+		}
+	}
+}
+
+// release снимает пробный запрос, результат которого не говорит о состоянии Keycloak.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// setState меняет состояние и пишет его в лог. Вызывается под mu.
+func (b *circuitBreaker) setState(state BreakerState) {
+	from := b.state
+	b.state = state
+
+	log := logger.GetLogger().Info
+	if state == BreakerOpen {
+		log = logger.GetLogger().Warn
+	}
+	log("Keycloak circuit breaker state changed",
+		zap.String("client", b.name),
+		zap.String("from", string(from)),
+		zap.String("to", string(state)),
+		zap.Int("consecutive_failures", b.failures))
+}
+
+func (b *circuitBreaker) snapshot() (BreakerState, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state, b.failures
+}
+
+// breakerTransport пропускает HTTP запросы через circuit breaker.
+type breakerTransport struct {
+	next    http.RoundTripper
+	breaker *circuitBreaker
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	switch {
+	case err != nil && callerCanceled(req):
+		// Запрос отменил вызывающий: Keycloak тут ни при чем
+		t.breaker.release()
+	case err != nil:
+		t.breaker.record(false)
+	default:
+		t.breaker.record(resp.StatusCode < http.StatusInternalServerError)
+	}
+
+	return resp, err
+}
+
+// callerContextKey ключ контекста вызывающего метод клиента. http.Client.Timeout
+// ограничивает запрос через deadline его контекста, поэтому по req.Context()
+// таймаут медленного Keycloak не отличить от отмены запроса вызывающим.
+type callerContextKey struct{}
+
+// withCallerContext сохраняет контекст вызывающего в контексте запроса.
+func withCallerContext(_ *resty.Client, r *resty.Request) error {
+	ctx := r.Context()
+	if _, ok := ctx.Value(callerContextKey{}).(context.Context); !ok {
+		r.SetContext(context.WithValue(ctx, callerContextKey{}, ctx))
+	}
+	return nil
+}
+
+// callerCanceled сообщает, что запрос прерван отменой или deadline контекста вызывающего,
+// а не таймаутом самого запроса.
+func callerCanceled(req *http.Request) bool {
+	if ctx, ok := req.Context().Value(callerContextKey{}).(context.Context); ok {
+		return ctx.Err() != nil
+	}
+	return errors.Is(req.Context().Err(), context.Canceled)
+}
+
+// retryCondition повторяет только идемпотентные запросы и только при ошибках соединения и 5xx.
+func retryCondition(resp *resty.Response, err error) bool {
+	if errors.Is(err, ErrCircuitOpen) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var method string
+	switch {
+	case resp != nil && resp.Request != nil:
+		method = resp.Request.Method
+	default:
+		return false
+	}
+	if !isIdempotent(method) {
+		return false
+	}
+
+	return err != nil || resp.StatusCode() >= http.StatusInternalServerError
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stats возвращает метрики устойчивости клиента.
+func (c *Client) Stats() ClientStats {
+	state, failures := c.breaker.snapshot()
+	return ClientStats{
+		BreakerState:        state,
+		ConsecutiveFailures: failures,
+		Retries:             c.retries.Load(),
+		Rejected:            c.breaker.rejected.Load(),
+	}
+}
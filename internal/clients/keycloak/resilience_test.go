@@ -0,0 +1,225 @@
+package keycloakclient_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/testingh/fakekeycloak"
+)
+
+func TestClientRetries(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		name     string
+		endpoint fakekeycloak.Endpoint
+		fault    fakekeycloak.Fault
+		call     func(c *keycloakclient.Client) error
+		// wantRequests запросов к endpoint, включая повторы
+		wantRequests int
+		wantErr      bool
+	}{
+		{
+			name:     "GET is retried on 5xx",
+			endpoint: fakekeycloak.EndpointAdminUsers,
+			fault:    fakekeycloak.Fault{Status: http.StatusServiceUnavailable, Times: 2},
+			call: func(c *keycloakclient.Client) error {
+				_, err := c.ListUsers(ctx, 0, 10)
+				return err
+			},
+			wantRequests: 3,
+		},
+		{
+			name:     "GET gives up after max retries",
+			endpoint: fakekeycloak.EndpointAdminUsers,
+			fault:    fakekeycloak.Fault{Status: http.StatusInternalServerError},
+			call: func(c *keycloakclient.Client) error {
+				_, err := c.ListUsers(ctx, 0, 10)
+				return err
+			},
+			wantRequests: 3,
+			wantErr:      true,
+		},
+		{
+			name:     "GET is not retried on 4xx",
+			endpoint: fakekeycloak.EndpointAdminUsers,
+			fault:    fakekeycloak.Fault{Status: http.StatusBadRequest},
+			call: func(c *keycloakclient.Client) error {
+				_, err := c.ListUsers(ctx, 0, 10)
+				return err
+			},
+			wantRequests: 1,
+			wantErr:      true,
+		},
+		{
+			name:     "POST is not retried",
+			endpoint: fakekeycloak.EndpointToken,
+			fault:    fakekeycloak.Fault{Status: http.StatusServiceUnavailable, Times: 1},
+			call: func(c *keycloakclient.Client) error {
+				_, err := c.LoginUser(ctx, "user@example.com", "password")
+				return err
+			},
+			wantRequests: 1,
+			wantErr:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			kc := fakekeycloak.New(t)
+			client := kc.NewClient(t, maxRetries(2), keycloakclient.WithBreakerThreshold(100))
+			if tc.endpoint == fakekeycloak.EndpointAdminUsers {
+				// Получаем токен заранее, чтобы он не влиял на счетчики
+				_, err := client.ListUsers(ctx, 0, 1)
+				require.NoError(t, err)
+			}
+			before := kc.Requests(tc.endpoint)
+
+			kc.Fail(tc.endpoint, tc.fault)
+			err := tc.call(client)
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.wantRequests, kc.Requests(tc.endpoint)-before)
+			assert.Equal(t, int64(tc.wantRequests-1), client.Stats().Retries)
+		})
+	}
+}
+
+func TestClientRetriesDisabled(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t, maxRetries(0))
+	_, err := client.ListUsers(ctx, 0, 1)
+	require.NoError(t, err)
+	before := kc.Requests(fakekeycloak.EndpointAdminUsers)
+
+	kc.Fail(fakekeycloak.EndpointAdminUsers, fakekeycloak.Fault{Status: http.StatusServiceUnavailable, Times: 1})
+	_, err = client.ListUsers(ctx, 0, 1)
+	require.Error(t, err)
+	assert.Equal(t, 1, kc.Requests(fakekeycloak.EndpointAdminUsers)-before)
+	assert.Zero(t, client.Stats().Retries)
+}
+
+func TestClientRetriesDroppedConnection(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t, maxRetries(3))
+	_, err := client.ListUsers(ctx, 0, 1)
+	require.NoError(t, err)
+
+	// Повтор на переиспользованном соединении может сделать и сам http.Transport,
+	// поэтому проверяем только итог
+	kc.Fail(fakekeycloak.EndpointAdminUsers, fakekeycloak.Fault{Drop: true, Times: 2})
+	_, err = client.ListUsers(ctx, 0, 1)
+	require.NoError(t, err)
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t,
+		maxRetries(1),
+		keycloakclient.WithBreakerThreshold(3),
+		keycloakclient.WithBreakerOpenTimeout(100*time.Millisecond),
+	)
+	_, err := client.ListUsers(ctx, 0, 1)
+	require.NoError(t, err)
+
+	kc.Fail(fakekeycloak.EndpointAdminUsers, fakekeycloak.Fault{Status: http.StatusBadGateway})
+	for i := 0; i < 2; i++ {
+		_, err = client.ListUsers(ctx, 0, 1)
+		require.Error(t, err)
+	}
+	assert.Equal(t, keycloakclient.BreakerOpen, client.Stats().BreakerState)
+
+	t.Run("open breaker fails fast", func(t *testing.T) {
+		before := kc.Requests(fakekeycloak.EndpointAdminUsers)
+
+		_, err := client.ListUsers(ctx, 0, 1)
+		require.ErrorIs(t, err, keycloakclient.ErrCircuitOpen)
+		assert.Equal(t, before, kc.Requests(fakekeycloak.EndpointAdminUsers))
+		assert.Positive(t, client.Stats().Rejected)
+	})
+
+	t.Run("failed probe opens breaker again", func(t *testing.T) {
+		time.Sleep(150 * time.Millisecond)
+		before := kc.Requests(fakekeycloak.EndpointAdminUsers)
+
+		_, err := client.ListUsers(ctx, 0, 1)
+		require.Error(t, err)
+		assert.Equal(t, before+1, kc.Requests(fakekeycloak.EndpointAdminUsers))
+		assert.Equal(t, keycloakclient.BreakerOpen, client.Stats().BreakerState)
+	})
+
+	t.Run("successful probe closes breaker", func(t *testing.T) {
+		kc.ClearFaults()
+		time.Sleep(150 * time.Millisecond)
+
+		_, err := client.ListUsers(ctx, 0, 1)
+		require.NoError(t, err)
+		stats := client.Stats()
+		assert.Equal(t, keycloakclient.BreakerClosed, stats.BreakerState)
+		assert.Zero(t, stats.ConsecutiveFailures)
+	})
+}
+
+func TestClientTimeout(t *testing.T) {
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t, keycloakclient.WithTimeout(50*time.Millisecond), maxRetries(1))
+	kc.SetLatency(200 * time.Millisecond)
+
+	start := time.Now()
+	_, err := client.LoginUser(context.Background(), "user@example.com", "password")
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestClientCircuitBreakerTimeouts(t *testing.T) {
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t,
+		keycloakclient.WithTimeout(50*time.Millisecond),
+		maxRetries(0),
+		keycloakclient.WithBreakerThreshold(2),
+	)
+	_, err := client.ListUsers(context.Background(), 0, 1)
+	require.NoError(t, err)
+	kc.SetLatency(300 * time.Millisecond)
+
+	t.Run("canceled caller is not a failure", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		for range 3 {
+			_, err := client.ListUsers(ctx, 0, 1)
+			require.Error(t, err)
+		}
+		stats := client.Stats()
+		assert.Equal(t, keycloakclient.BreakerClosed, stats.BreakerState)
+		assert.Zero(t, stats.ConsecutiveFailures)
+	})
+
+	t.Run("request timeouts open breaker", func(t *testing.T) {
+		for range 2 {
+			_, err := client.ListUsers(context.Background(), 0, 1)
+			require.Error(t, err)
+		}
+		assert.Equal(t, keycloakclient.BreakerOpen, client.Stats().BreakerState)
+
+		_, err := client.ListUsers(context.Background(), 0, 1)
+		require.ErrorIs(t, err, keycloakclient.ErrCircuitOpen)
+	})
+}
+
+// maxRetries задает число повторов, 0 - без повторов.
+func maxRetries(n int) keycloakclient.OptOptionsSetter {
+	return keycloakclient.WithMaxRetries(&n)
+}
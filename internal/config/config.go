@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 // Config представляет конфигурацию приложения.
 type Config struct {
 	Global  GlobalConfig  `yaml:"global"`
@@ -7,6 +9,8 @@ type Config struct {
 	Servers ServersConfig `yaml:"servers"`
 	Clients ClientsConfig `yaml:"clients"`
 	Storage StorageConfig `yaml:"storage"`
+	Auth    AuthConfig    `yaml:"auth"`
+	Jobs    JobsConfig    `yaml:"jobs"`
 }
 
 // GlobalConfig представляет глобальные настройки.
@@ -37,6 +41,9 @@ type DebugServerConfig struct {
 type ClientServerConfig struct {
 	Addr         string   `yaml:"addr" validate:"required,hostname_port"`
 	AllowOrigins []string `yaml:"allow_origins"`
+	// TrustedProxies адреса и подсети прокси, которым доверяется X-Forwarded-For и X-Real-IP.
+	// Для остальных запросов адресом клиента считается адрес сокета.
+	TrustedProxies []string `yaml:"trusted_proxies" validate:"dive,ip|cidr"`
 }
 
 // ClientsConfig представляет настройки для внешних клиентов.
@@ -52,16 +59,154 @@ type KeycloakConfig struct {
 	ClientID     string `yaml:"client_id" validate:"required"`
 	ClientSecret string `yaml:"client_secret" validate:"required"`
 	DebugMode    bool   `yaml:"debug_mode"`
+	// Timeout таймаут одной попытки запроса к Keycloak
+	Timeout time.Duration `yaml:"timeout" validate:"gte=0"`
+	// MaxRetries число повторов идемпотентных запросов при 5xx и ошибках соединения.
+	// Не задано - значение по умолчанию, 0 - без повторов
+	MaxRetries   *int          `yaml:"max_retries" validate:"omitempty,gte=0"`
+	RetryWaitMin time.Duration `yaml:"retry_wait_min" validate:"gte=0"`
+	RetryWaitMax time.Duration `yaml:"retry_wait_max" validate:"gte=0"`
+	// BreakerFailureThreshold число неудачных запросов подряд, после которого запросы отклоняются сразу
+	BreakerFailureThreshold int           `yaml:"breaker_failure_threshold" validate:"gte=0"`
+	BreakerOpenTimeout      time.Duration `yaml:"breaker_open_timeout" validate:"gte=0"`
+	// Issuer ожидаемый issuer провайдера, если он отличается от {base_path}/realms/{realm}
+	Issuer string `yaml:"issuer" validate:"omitempty,url"`
+	// DiscoveryTTL как часто перечитывать discovery документ провайдера
+	DiscoveryTTL time.Duration `yaml:"discovery_ttl" validate:"gte=0"`
 }
 
 // StorageConfig представляет настройки для хранения данных.
 type StorageConfig struct {
-	DBName        string `yaml:"db_name" validate:"required"`
-	DBUser        string `yaml:"db_user" validate:"required"`
-	DBPassword    string `yaml:"db_password" validate:"required"`
-	DBHost        string `yaml:"db_host" validate:"required"`
-	DBPort        string `yaml:"db_port" validate:"required"`
-	DBSSLMode     string `yaml:"db_ssl_mode"`
+	DBName     string `yaml:"db_name" validate:"required"`
+	DBUser     string `yaml:"db_user" validate:"required"`
+	DBPassword string `yaml:"db_password" validate:"required"`
+	DBHost     string `yaml:"db_host" validate:"required"`
+	DBPort     string `yaml:"db_port" validate:"required"`
+	DBSSLMode  string `yaml:"db_ssl_mode" validate:"omitempty,oneof=disable allow prefer require verify-ca verify-full"`
+	// CA для проверки сертификата сервера (verify-ca, verify-full).
 	DBSSLRootCert string `yaml:"db_ssl_root_cert"`
-	DBSSLKey      string `yaml:"db_ssl_key"`
+	// Клиентский сертификат и ключ для аутентификации по TLS (задаются вместе).
+	DBSSLCert string `yaml:"db_ssl_cert" validate:"required_with=DBSSLKey"`
+	DBSSLKey  string `yaml:"db_ssl_key" validate:"required_with=DBSSLCert"`
+	// Имя приложения в pg_stat_activity (по умолчанию woman-app-backend).
+	ApplicationName string `yaml:"application_name"`
+	// Размер пула соединений (по умолчанию max(4, число CPU) и 0).
+	MaxConns int32 `yaml:"max_conns" validate:"gte=0"`
+	MinConns int32 `yaml:"min_conns" validate:"gte=0"`
+	// Время жизни соединения и простоя до закрытия (по умолчанию 1h и 30m).
+	MaxConnLifetime time.Duration `yaml:"max_conn_lifetime" validate:"gte=0"`
+	MaxConnIdleTime time.Duration `yaml:"max_conn_idle_time" validate:"gte=0"`
+	// Период проверки простаивающих соединений (по умолчанию 1m).
+	HealthCheckPeriod time.Duration `yaml:"health_check_period" validate:"gte=0"`
+	// Ограничение времени запроса на стороне Postgres (0 - без ограничения).
+	StatementTimeout time.Duration `yaml:"statement_timeout" validate:"gte=0"`
+	// Применять встроенные миграции схемы при запуске сервера.
+	MigrateOnStart bool `yaml:"migrate_on_start"`
+}
+
+// AuthConfig представляет настройки проверки токенов доступа.
+type AuthConfig struct {
+	// Аудитория, которая должна присутствовать в "aud" токена (пусто - не проверяется).
+	Audience string `yaml:"audience"`
+	// Интервал фонового обновления JWKS (по умолчанию 15m).
+	JWKSRefreshInterval time.Duration `yaml:"jwks_refresh_interval" validate:"gte=0"`
+	// Дополнительно проверять отзыв токена через introspection на каждый запрос.
+	CheckRevocation bool `yaml:"check_revocation"`
+	// TTL кэша результатов introspection, не больше срока жизни токена (по умолчанию 30s).
+	IntrospectionCacheTTL time.Duration `yaml:"introspection_cache_ttl" validate:"gte=0"`
+	// TTL кэша неактивных токенов (по умолчанию 5s).
+	IntrospectionNegativeCacheTTL time.Duration `yaml:"introspection_negative_cache_ttl" validate:"gte=0"`
+	// Максимальное время жизни access token в realm: столько хранятся записи об отозванных сессиях (по умолчанию 15m).
+	AccessTokenLifetime time.Duration `yaml:"access_token_lifetime" validate:"gte=0"`
+	// Закрыть пользовательские эндпоинты для пользователей с неподтвержденным email.
+	RequireVerifiedEmail bool `yaml:"require_verified_email"`
+	// Максимум попыток сброса и смены пароля за окно (по умолчанию 5 за 15m).
+	PasswordAttempts       int           `yaml:"password_attempts" validate:"gte=0"`
+	PasswordAttemptsWindow time.Duration `yaml:"password_attempts_window" validate:"gte=0"`
+	// Отключить вход по паролю (POST /auth/login), оставив только authorization code flow.
+	DisablePasswordLogin bool `yaml:"disable_password_login"`
+	// Вход через страницу Keycloak (authorization code flow с PKCE).
+	AuthorizationCode AuthorizationCodeConfig `yaml:"authorization_code"`
+	// Токены в зашифрованных HttpOnly cookie вместо тела ответа для веб-клиентов.
+	SessionCookies SessionCookiesConfig `yaml:"session_cookies"`
+	// Защита входа по паролю от перебора.
+	LoginProtection LoginProtectionConfig `yaml:"login_protection"`
+	// Распознавание токенов внутренних сервисов (client credentials).
+	ServiceAccounts ServiceAccountsConfig `yaml:"service_accounts"`
+}
+
+// ServiceAccountsConfig представляет настройки распознавания токенов service account.
+type ServiceAccountsConfig struct {
+	// Клиенты Keycloak (azp) внутренних сервисов.
+	Clients []string `yaml:"clients"`
+	// Роль service account в виде "role" (realm) или "client:role" (пусто - не проверяется).
+	Role string `yaml:"role"`
+}
+
+// LoginProtectionConfig представляет настройки защиты входа от перебора паролей.
+type LoginProtectionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Где хранить попытки: memory - в памяти одного экземпляра, postgres - общая таблица для нескольких (по умолчанию memory).
+	Store string `yaml:"store" validate:"omitempty,oneof=memory postgres"`
+	// Неудачных попыток с одного IP адреса за окно до блокировки (по умолчанию 50 за 15m).
+	IPMaxFailures int           `yaml:"ip_max_failures" validate:"gte=0"`
+	IPWindow      time.Duration `yaml:"ip_window" validate:"gte=0"`
+	// Неудачных попыток для одной учетной записи за окно до блокировки (по умолчанию 10 за 15m).
+	AccountMaxFailures int           `yaml:"account_max_failures" validate:"gte=0"`
+	AccountWindow      time.Duration `yaml:"account_window" validate:"gte=0"`
+	// После скольких неудач включается задержка между попытками (по умолчанию 3),
+	// начальная задержка, удваивающаяся с каждой неудачей, и ее максимум (по умолчанию 1s и 30s).
+	DelayAfter int           `yaml:"delay_after" validate:"gte=0"`
+	Delay      time.Duration `yaml:"delay" validate:"gte=0"`
+	MaxDelay   time.Duration `yaml:"max_delay" validate:"gte=0"`
+	// Длительность блокировки (по умолчанию 15m).
+	Lockout time.Duration `yaml:"lockout" validate:"gte=0"`
+}
+
+// SessionCookiesConfig представляет настройки режима cookie сессии.
+type SessionCookiesConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Ключ шифрования cookie: 32 байта в base64 (openssl rand -base64 32).
+	EncryptionKey string `yaml:"encryption_key" validate:"required_if=Enabled true,omitempty,base64"`
+	// Имена cookie и заголовка CSRF (по умолчанию access_token, refresh_token, csrf_token, X-CSRF-Token).
+	AccessTokenName  string `yaml:"access_token_name"`
+	RefreshTokenName string `yaml:"refresh_token_name"`
+	CSRFCookieName   string `yaml:"csrf_cookie_name"`
+	CSRFHeaderName   string `yaml:"csrf_header_name"`
+	// Domain и Path cookie (пусто - cookie только для хоста API на "/").
+	Domain string `yaml:"domain"`
+	Path   string `yaml:"path"`
+	// Атрибут SameSite: lax, strict или none (по умолчанию lax).
+	SameSite string `yaml:"same_site" validate:"omitempty,oneof=lax strict none"`
+	// Max-Age cookie access token (0 - по сроку действия токена).
+	AccessTokenLifetime time.Duration `yaml:"access_token_lifetime" validate:"gte=0"`
+	// Max-Age cookie refresh token и CSRF токена (0 - до закрытия браузера).
+	RefreshTokenLifetime time.Duration `yaml:"refresh_token_lifetime" validate:"gte=0"`
+	// За сколько до истечения access token обновлять его (по умолчанию 1m).
+	RefreshBefore time.Duration `yaml:"refresh_before" validate:"gte=0"`
+}
+
+// AuthorizationCodeConfig представляет настройки входа через authorization code flow.
+type AuthorizationCodeConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Адрес GET /api/v1/auth/callback этого API, разрешенный в Valid Redirect URIs клиента Keycloak.
+	RedirectURL string `yaml:"redirect_url" validate:"required_if=Enabled true,omitempty,url"`
+	// Запрашиваемые scope (по умолчанию openid).
+	Scopes []string `yaml:"scopes"`
+	// Сколько ждать возврата пользователя со страницы входа (по умолчанию 10m).
+	StateTTL time.Duration `yaml:"state_ttl" validate:"gte=0"`
+	// Сколько незавершенных входов хранить, при переполнении вытесняются самые старые (по умолчанию 10000).
+	MaxPendingStates int `yaml:"max_pending_states" validate:"gte=0"`
+	// Как отдать токены после входа: body - в теле ответа, cookie - в Secure HttpOnly cookie (по умолчанию body).
+	TokenDelivery string `yaml:"token_delivery" validate:"omitempty,oneof=body cookie"`
+	// Куда вернуть браузер после входа в режиме cookie (пусто - ответ JSON).
+	PostLoginRedirectURL string `yaml:"post_login_redirect_url" validate:"omitempty,url"`
+}
+
+// JobsConfig представляет настройки фоновых задач.
+type JobsConfig struct {
+	// Интервал очистки пользователей Keycloak, оставшихся после неудачной регистрации (по умолчанию 5m).
+	OrphanCleanupInterval time.Duration `yaml:"orphan_cleanup_interval" validate:"gte=0"`
+	// Интервал сверки пользователей Keycloak с таблицей users (0 - только вручную через reconcile-users).
+	UserReconcileInterval time.Duration `yaml:"user_reconcile_interval" validate:"gte=0"`
 }
@@ -4,6 +4,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/Fisher-Development/woman-app-backend/internal/config"
 	"github.com/stretchr/testify/assert"
@@ -21,4 +22,5 @@ func TestParseAndValidate(t *testing.T) {
 	cfg, err := config.ParseAndValidate(configExamplePath)
 	require.NoError(t, err)
 	assert.NotEmpty(t, cfg.Log.Level)
+	assert.Equal(t, 15*time.Minute, cfg.Auth.JWKSRefreshInterval)
 }
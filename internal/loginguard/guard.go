@@ -0,0 +1,362 @@
+package loginguard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+)
+
+const (
+	defaultIPMaxFailures      = 50
+	defaultIPWindow           = 15 * time.Minute
+	defaultAccountMaxFailures = 10
+	defaultAccountWindow      = 15 * time.Minute
+	defaultDelayAfter         = 3
+	defaultDelay              = time.Second
+	defaultMaxDelay           = 30 * time.Second
+	defaultLockout            = 15 * time.Minute
+
+	cleanupInterval = time.Minute
+)
+
+// AuditLog журнал, в который пишутся блокировки и их снятие.
+type AuditLog interface {
+	CreateAuditEvent(ctx context.Context, event *models.AuditEvent) error
+}
+
+type policy struct {
+	maxFailures int
+	window      time.Duration
+}
+
+// Guard защищает вход от перебора паролей. Неудачные попытки считаются в скользящем
+// окне отдельно по IP адресу и по учетной записи: после delayAfter неудач подряд
+// следующая попытка для учетной записи разрешается только через растущую задержку,
+// а после maxFailures неудач ключ блокируется на lockout.
+type Guard struct {
+	store Store
+	audit AuditLog
+	now   func() time.Time
+
+	ip         policy
+	account    policy
+	delayAfter int
+	delay      time.Duration
+	maxDelay   time.Duration
+	lockout    time.Duration
+
+	logger *zap.Logger
+}
+
+// Option настраивает Guard.
+type Option func(g *Guard)
+
+// WithIPPolicy задает число неудачных попыток с одного IP адреса за окно, после которого адрес блокируется.
+func WithIPPolicy(maxFailures int, window time.Duration) Option {
+	return func(g *Guard) {
+		if maxFailures > 0 {
+			g.ip.maxFailures = maxFailures
+		}
+		if window > 0 {
+			g.ip.window = window
+		}
+	}
+}
+
+// WithAccountPolicy задает число неудачных попыток для одной учетной записи за окно, после которого она блокируется.
+func WithAccountPolicy(maxFailures int, window time.Duration) Option {
+	return func(g *Guard) {
+		if maxFailures > 0 {
+			g.account.maxFailures = maxFailures
+		}
+		if window > 0 {
+			g.account.window = window
+		}
+	}
+}
+
+// WithDelays задает задержку между попытками для учетной записи: после after неудач
+// она начинается с delay и удваивается с каждой следующей неудачей, но не больше maxDelay.
+func WithDelays(after int, delay, maxDelay time.Duration) Option {
+	return func(g *Guard) {
+		if after > 0 {
+			g.delayAfter = after
+		}
+		if delay > 0 {
+			g.delay = delay
+		}
+		if maxDelay > 0 {
+			g.maxDelay = maxDelay
+		}
+	}
+}
+
+// WithLockout задает длительность блокировки.
+func WithLockout(d time.Duration) Option {
+	return func(g *Guard) {
+		if d > 0 {
+			g.lockout = d
+		}
+	}
+}
+
+// New создает Guard.
+func New(store Store, audit AuditLog, opts ...Option) *Guard {
+	g := &Guard{
+		store:      store,
+		audit:      audit,
+		now:        time.Now,
+		ip:         policy{maxFailures: defaultIPMaxFailures, window: defaultIPWindow},
+		account:    policy{maxFailures: defaultAccountMaxFailures, window: defaultAccountWindow},
+		delayAfter: defaultDelayAfter,
+		delay:      defaultDelay,
+		maxDelay:   defaultMaxDelay,
+		lockout:    defaultLockout,
+		logger:     zap.L().Named("login-guard"),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// target ключ, по которому считаются попытки.
+type target struct {
+	key string
+	// subject как ключ показывается в журнале аудита: IP адрес или хэш email, но не сам адрес
+	subject string
+	policy  policy
+	// задержки применяются только к учетной записи: за одним IP адресом может быть много пользователей
+	delays bool
+}
+
+func (g *Guard) targets(ip, email string) []target {
+	var targets []target
+	if ip != "" {
+		targets = append(targets, target{key: "ip:" + ip, subject: ip, policy: g.ip})
+	}
+	if email = normalizeEmail(email); email != "" {
+		key := accountKey(email)
+		targets = append(targets, target{key: key, subject: key, policy: g.account, delays: true})
+	}
+	return targets
+}
+
+// Attempt попытка входа, зарезервированная Check. Ее нужно завершить вызовом
+// Failure, Success или Release.
+type Attempt struct {
+	ip    string
+	email string
+	at    time.Time
+	// reserved попытка записана в хранилище как ожидающая неудача
+	reserved bool
+}
+
+// Check возвращает, через сколько можно попытаться войти (0 - можно сейчас).
+// Разрешенная попытка сразу резервируется: записывается как неудача, пока не станет
+// известен ее исход. Проверка и резервирование выполняются под блокировкой ключей,
+// поэтому параллельные попытки видят друг друга и не обходят задержки и лимиты.
+func (g *Guard) Check(ctx context.Context, ip, email string) (Attempt, time.Duration, error) {
+	// Postgres хранит время с точностью до микросекунд, а по нему резерв потом снимается
+	now := g.now().Truncate(time.Microsecond)
+	targets := g.targets(ip, email)
+	keys := make([]string, 0, len(targets))
+	for _, t := range targets {
+		keys = append(keys, t.key)
+	}
+
+	attempt := Attempt{ip: ip, email: email, at: now}
+	var wait time.Duration
+	err := g.store.LockLoginAttempts(ctx, keys, func(ctx context.Context) error {
+		wait = 0
+		for _, t := range targets {
+			until, err := g.store.GetLoginLockout(ctx, t.key)
+			if err != nil {
+				return fmt.Errorf("get login lockout: %w", err)
+			}
+			wait = max(wait, until.Sub(now))
+
+			count, last, err := g.store.CountLoginFailures(ctx, t.key, now.Add(-t.policy.window))
+			if err != nil {
+				return fmt.Errorf("count login failures: %w", err)
+			}
+			if count >= t.policy.maxFailures {
+				// Лимит исчерпан попытками, исход которых еще неизвестен
+				wait = max(wait, g.delay)
+			}
+			if t.delays {
+				wait = max(wait, last.Add(g.delayFor(count)).Sub(now))
+			}
+		}
+		if wait > 0 {
+			return nil
+		}
+
+		for _, t := range targets {
+			if err := g.store.AddLoginFailure(ctx, t.key, now); err != nil {
+				return fmt.Errorf("reserve login attempt: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Attempt{}, 0, err
+	}
+
+	attempt.reserved = wait <= 0
+	return attempt, wait, nil
+}
+
+// Failure учитывает неудачную попытку входа и блокирует ключи, исчерпавшие лимит.
+func (g *Guard) Failure(ctx context.Context, attempt Attempt) error {
+	if !attempt.reserved {
+		return nil
+	}
+	now := g.now()
+
+	// Неудача уже записана при резервировании, остается проверить лимиты
+	for _, t := range g.targets(attempt.ip, attempt.email) {
+		count, _, err := g.store.CountLoginFailures(ctx, t.key, now.Add(-t.policy.window))
+		if err != nil {
+			return fmt.Errorf("count login failures: %w", err)
+		}
+		if count < t.policy.maxFailures {
+			continue
+		}
+
+		until := now.Add(g.lockout)
+		if err := g.store.SetLoginLockout(ctx, t.key, until); err != nil {
+			return fmt.Errorf("set login lockout: %w", err)
+		}
+		g.logger.Warn("Login locked out",
+			zap.String("key", t.key),
+			zap.Int("failures", count),
+			zap.Time("locked_until", until))
+		g.record(ctx, &models.AuditEvent{
+			Action:  models.AuditActionLoginLockout,
+			Subject: t.subject,
+			Details: fmt.Sprintf("%d failed attempts within %s, locked until %s",
+				count, t.policy.window, until.UTC().Format(time.RFC3339)),
+		})
+	}
+	return nil
+}
+
+// Success сбрасывает неудачные попытки учетной записи после успешного входа.
+// Попытки IP адреса не сбрасываются, иначе вход в свою учетную запись
+// позволял бы продолжать перебор чужих; снимается только резерв этой попытки.
+func (g *Guard) Success(ctx context.Context, attempt Attempt) error {
+	if !attempt.reserved {
+		return nil
+	}
+	for _, t := range g.targets(attempt.ip, attempt.email) {
+		if t.delays {
+			if _, err := g.store.ClearLoginAttempts(ctx, t.key); err != nil {
+				return fmt.Errorf("clear login attempts: %w", err)
+			}
+			continue
+		}
+		if err := g.store.DeleteLoginFailure(ctx, t.key, attempt.at); err != nil {
+			return fmt.Errorf("release login attempt: %w", err)
+		}
+	}
+	return nil
+}
+
+// Release снимает резерв попытки, исход которой неизвестен (например, Keycloak недоступен):
+// такая попытка не считается неудачей.
+func (g *Guard) Release(ctx context.Context, attempt Attempt) error {
+	if !attempt.reserved {
+		return nil
+	}
+	for _, t := range g.targets(attempt.ip, attempt.email) {
+		if err := g.store.DeleteLoginFailure(ctx, t.key, attempt.at); err != nil {
+			return fmt.Errorf("release login attempt: %w", err)
+		}
+	}
+	return nil
+}
+
+// Clear снимает блокировку и сбрасывает попытки IP адреса и (или) учетной записи.
+// actor - кто снял блокировку, попадает в журнал аудита.
+func (g *Guard) Clear(ctx context.Context, actor, ip, email string) error {
+	for _, t := range g.targets(ip, email) {
+		locked, err := g.store.ClearLoginAttempts(ctx, t.key)
+		if err != nil {
+			return fmt.Errorf("clear login attempts: %w", err)
+		}
+
+		details := "no active lockout, failed attempts reset"
+		if locked {
+			details = "lockout removed"
+		}
+		g.logger.Info("Login attempts cleared",
+			zap.String("key", t.key),
+			zap.String("actor", actor),
+			zap.Bool("was_locked", locked))
+		g.record(ctx, &models.AuditEvent{
+			Action:  models.AuditActionLoginUnlock,
+			Subject: t.subject,
+			Actor:   actor,
+			Details: details,
+		})
+	}
+	return nil
+}
+
+// Run периодически удаляет устаревшие попытки и блокировки до отмены контекста.
+func (g *Guard) Run(ctx context.Context) error {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			before := g.now().Add(-max(g.ip.window, g.account.window))
+			if err := g.store.DeleteExpiredLoginAttempts(ctx, before); err != nil && ctx.Err() == nil {
+				g.logger.Warn("Failed to delete expired login attempts", zap.Error(err))
+			}
+		}
+	}
+}
+
+// delayFor возвращает задержку после count неудачных попыток.
+func (g *Guard) delayFor(count int) time.Duration {
+	if count < g.delayAfter {
+		return 0
+	}
+	delay := g.delay
+	for i := g.delayAfter; i < count && delay < g.maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, g.maxDelay)
+}
+
+// record пишет событие в журнал аудита. Ошибка журнала не должна мешать входу, поэтому только логируется.
+func (g *Guard) record(ctx context.Context, event *models.AuditEvent) {
+	if err := g.audit.CreateAuditEvent(ctx, event); err != nil {
+		g.logger.Error("Failed to write audit event",
+			zap.String("action", event.Action),
+			zap.Error(err))
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// accountKey хэширует email, чтобы в таблице попыток и журнале аудита не хранились адреса,
+// в том числе несуществующие и введенные с опечатками.
+func accountKey(email string) string {
+	sum := sha256.Sum256([]byte(email))
+	return "account:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,154 @@
+package loginguard
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+)
+
+type fakeAuditLog struct {
+	events []models.AuditEvent
+}
+
+func (f *fakeAuditLog) CreateAuditEvent(_ context.Context, event *models.AuditEvent) error {
+	f.events = append(f.events, *event)
+	return nil
+}
+
+func TestGuard(t *testing.T) {
+	ctx := context.Background()
+	current := time.Unix(1_700_000_000, 0)
+	store := NewMemoryStore()
+	audit := &fakeAuditLog{}
+	g := New(store, audit,
+		WithIPPolicy(5, time.Hour),
+		WithAccountPolicy(4, time.Hour),
+		WithDelays(2, time.Second, 3*time.Second),
+		WithLockout(10*time.Minute),
+	)
+	g.now = func() time.Time { return current }
+
+	// check проверяет, можно ли войти, не оставляя резерва попытки
+	check := func(ip, email string) time.Duration {
+		t.Helper()
+		attempt, wait, err := g.Check(ctx, ip, email)
+		require.NoError(t, err)
+		require.NoError(t, g.Release(ctx, attempt))
+		return wait
+	}
+	fail := func(ip, email string) {
+		t.Helper()
+		attempt, wait, err := g.Check(ctx, ip, email)
+		require.NoError(t, err)
+		require.Zero(t, wait)
+		require.NoError(t, g.Failure(ctx, attempt))
+	}
+
+	// Первые неудачи не задерживают следующую попытку
+	fail("10.0.0.1", "Jane@Example.com")
+	assert.Zero(t, check("10.0.0.1", "jane@example.com"))
+
+	// Задержка растет с каждой неудачей и ограничена сверху
+	fail("10.0.0.1", "jane@example.com")
+	assert.Equal(t, time.Second, check("10.0.0.1", "jane@example.com"))
+	assert.Equal(t, time.Second, check("10.0.0.2", "jane@example.com"), "delay is per account")
+	assert.Zero(t, check("10.0.0.1", "john@example.com"), "delays do not apply to IP")
+
+	current = current.Add(time.Second)
+	fail("10.0.0.1", "jane@example.com")
+	assert.Equal(t, 2*time.Second, check("", "jane@example.com"))
+
+	// Четвертая неудача блокирует учетную запись
+	current = current.Add(2 * time.Second)
+	fail("10.0.0.1", "jane@example.com")
+	assert.Equal(t, 10*time.Minute, check("10.0.0.3", "jane@example.com"))
+	require.Len(t, audit.events, 1)
+	assert.Equal(t, models.AuditActionLoginLockout, audit.events[0].Action)
+	assert.Equal(t, accountKey("jane@example.com"), audit.events[0].Subject, "email is not stored in plaintext")
+
+	// Пятая неудача с того же адреса блокирует IP для всех учетных записей
+	fail("10.0.0.1", "john@example.com")
+	assert.Equal(t, 10*time.Minute, check("10.0.0.1", "kate@example.com"))
+	require.Len(t, audit.events, 2)
+	assert.Equal(t, "10.0.0.1", audit.events[1].Subject)
+
+	// Блокировка истекает сама
+	current = current.Add(10 * time.Minute)
+	assert.Zero(t, check("10.0.0.1", "jane@example.com"))
+
+	t.Run("success resets account failures", func(t *testing.T) {
+		fail("10.0.0.4", "kate@example.com")
+		fail("10.0.0.4", "kate@example.com")
+		require.NotZero(t, check("10.0.0.4", "kate@example.com"))
+
+		current = current.Add(time.Second)
+		attempt, wait, err := g.Check(ctx, "10.0.0.4", "kate@example.com")
+		require.NoError(t, err)
+		require.Zero(t, wait)
+		require.NoError(t, g.Success(ctx, attempt))
+		assert.Zero(t, check("10.0.0.4", "kate@example.com"))
+
+		// Успешный вход не считается неудачей IP адреса
+		count, _, err := store.CountLoginFailures(ctx, "ip:10.0.0.4", time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("admin clears lockout", func(t *testing.T) {
+		for range 4 {
+			current = current.Add(3 * time.Second)
+			fail("", "bob@example.com")
+		}
+		require.NotZero(t, check("", "bob@example.com"))
+
+		require.NoError(t, g.Clear(ctx, "admin-id", "", "BOB@example.com"))
+		assert.Zero(t, check("", "bob@example.com"))
+
+		event := audit.events[len(audit.events)-1]
+		assert.Equal(t, models.AuditActionLoginUnlock, event.Action)
+		assert.Equal(t, accountKey("bob@example.com"), event.Subject)
+		assert.Equal(t, "admin-id", event.Actor)
+		assert.Equal(t, "lockout removed", event.Details)
+	})
+
+	t.Run("failures outside the window expire", func(t *testing.T) {
+		for range 3 {
+			current = current.Add(3 * time.Second)
+			fail("", "eve@example.com")
+		}
+
+		current = current.Add(time.Hour)
+		fail("", "eve@example.com")
+		assert.Zero(t, check("", "eve@example.com"))
+
+		require.NoError(t, store.DeleteExpiredLoginAttempts(ctx, current.Add(-time.Hour)))
+		count, _, err := store.CountLoginFailures(ctx, accountKey("eve@example.com"), time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+	t.Run("attempts in flight are reserved", func(t *testing.T) {
+		// Параллельные попытки: исход ни одной еще неизвестен
+		var allowed []Attempt
+		for range 5 {
+			attempt, wait, err := g.Check(ctx, "10.0.0.5", "mallory@example.com")
+			require.NoError(t, err)
+			if wait == 0 {
+				allowed = append(allowed, attempt)
+			}
+		}
+		require.Len(t, allowed, 2, "only attempts before the delay threshold pass")
+
+		// Попытка с неизвестным исходом не считается неудачей
+		for _, attempt := range allowed {
+			require.NoError(t, g.Release(ctx, attempt))
+		}
+		count, _, err := store.CountLoginFailures(ctx, accountKey("mallory@example.com"), time.Time{})
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+}
@@ -0,0 +1,151 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store хранит неудачные попытки входа и блокировки по ключу.
+// MemoryStore подходит для одного экземпляра приложения, для нескольких
+// экземпляров используется общее хранилище (store.Storage в Postgres).
+type Store interface {
+	// LockLoginAttempts выполняет fn, удерживая блокировку ключей: проверка и резервирование
+	// попытки по ключу не должны пересекаться с другими попытками, в том числе в других экземплярах.
+	LockLoginAttempts(ctx context.Context, keys []string, fn func(ctx context.Context) error) error
+	AddLoginFailure(ctx context.Context, key string, at time.Time) error
+	// DeleteLoginFailure удаляет одну попытку ключа, записанную в момент at.
+	DeleteLoginFailure(ctx context.Context, key string, at time.Time) error
+	// CountLoginFailures возвращает число попыток позже since и время последней из них.
+	CountLoginFailures(ctx context.Context, key string, since time.Time) (int, time.Time, error)
+	// SetLoginLockout блокирует ключ до until и сбрасывает его попытки.
+	SetLoginLockout(ctx context.Context, key string, until time.Time) error
+	// GetLoginLockout возвращает окончание блокировки (нулевое время - блокировки нет).
+	GetLoginLockout(ctx context.Context, key string) (time.Time, error)
+	// ClearLoginAttempts удаляет попытки и блокировку ключа и сообщает, была ли блокировка.
+	ClearLoginAttempts(ctx context.Context, key string) (bool, error)
+	// DeleteExpiredLoginAttempts удаляет попытки и блокировки не позже before.
+	DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) error
+}
+
+// MemoryStore хранит попытки входа в памяти процесса.
+type MemoryStore struct {
+	lock     sync.Mutex // блокировка ключей LockLoginAttempts (одна на все ключи)
+	mu       sync.Mutex
+	failures map[string][]time.Time
+	lockouts map[string]time.Time
+}
+
+// NewMemoryStore создает MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		failures: make(map[string][]time.Time),
+		lockouts: make(map[string]time.Time),
+	}
+}
+
+// LockLoginAttempts реализует Store.
+func (s *MemoryStore) LockLoginAttempts(ctx context.Context, _ []string, fn func(ctx context.Context) error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return fn(ctx)
+}
+
+// AddLoginFailure реализует Store.
+func (s *MemoryStore) AddLoginFailure(_ context.Context, key string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[key] = append(s.failures[key], at)
+	return nil
+}
+
+// DeleteLoginFailure реализует Store.
+func (s *MemoryStore) DeleteLoginFailure(_ context.Context, key string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failures := s.failures[key]
+	for i, failedAt := range failures {
+		if failedAt.Equal(at) {
+			s.failures[key] = append(failures[:i], failures[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// CountLoginFailures реализует Store.
+func (s *MemoryStore) CountLoginFailures(_ context.Context, key string, since time.Time) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int
+	var last time.Time
+	for _, at := range s.failures[key] {
+		if !at.After(since) {
+			continue
+		}
+		count++
+		if at.After(last) {
+			last = at
+		}
+	}
+	return count, last, nil
+}
+
+// SetLoginLockout реализует Store.
+func (s *MemoryStore) SetLoginLockout(_ context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lockouts[key] = until
+	delete(s.failures, key)
+	return nil
+}
+
+// GetLoginLockout реализует Store.
+func (s *MemoryStore) GetLoginLockout(_ context.Context, key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lockouts[key], nil
+}
+
+// ClearLoginAttempts реализует Store.
+func (s *MemoryStore) ClearLoginAttempts(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, locked := s.lockouts[key]
+	delete(s.failures, key)
+	delete(s.lockouts, key)
+	return locked, nil
+}
+
+// DeleteExpiredLoginAttempts реализует Store.
+func (s *MemoryStore) DeleteExpiredLoginAttempts(_ context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, failures := range s.failures {
+		kept := failures[:0]
+		for _, at := range failures {
+			if at.After(before) {
+				kept = append(kept, at)
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.failures, key)
+		} else {
+			s.failures[key] = kept
+		}
+	}
+	for key, until := range s.lockouts {
+		if !until.After(before) {
+			delete(s.lockouts, key)
+		}
+	}
+	return nil
+}
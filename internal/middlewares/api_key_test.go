@@ -0,0 +1,68 @@
+package middlewares_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	"github.com/Fisher-Development/woman-app-backend/internal/testingh/fakekeycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+type fakeAPIKeys map[string]*middlewares.APIKeyIdentity
+
+func (f fakeAPIKeys) AuthenticateAPIKey(_ context.Context, key string) (*middlewares.APIKeyIdentity, error) {
+	if key == "broken" {
+		return nil, errors.New("database is down")
+	}
+	identity, ok := f[key]
+	if !ok {
+		return nil, middlewares.ErrInvalidAPIKey
+	}
+	return identity, nil
+}
+
+func TestAuthMiddleware_APIKeys(t *testing.T) {
+	userID := types.MustParse[types.UserID]("5cb40dc0-a249-4783-a301-9e1f3cf3ea41")
+	keys := fakeAPIKeys{
+		"wak_qa_secret": {KeyID: "key-1", UserID: userID, Roles: []string{"qa"}, EmailVerified: true},
+	}
+	kc := fakekeycloak.New(t)
+	authMiddleware := middlewares.NewAuthMiddleware(kc.NewClient(t), middlewares.WithAPIKeys(keys))
+
+	whoami := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		roles, _ := middlewares.GetUserRoles(r.Context())
+		_, _ = w.Write([]byte(middlewares.MustGetUserID(r.Context()).String() + " " + roles[0]))
+	})
+
+	serve := func(authorization string, mw ...func(http.Handler) http.Handler) *httptest.ResponseRecorder {
+		t.Helper()
+		var handler http.Handler = whoami
+		for i := len(mw) - 1; i >= 0; i-- {
+			handler = mw[i](handler)
+		}
+		handler = authMiddleware.RequireAuth()(handler)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("ApiKey wak_qa_secret", middlewares.RequireUser(), middlewares.RequireVerifiedEmail())
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, userID.String()+" qa", w.Body.String())
+
+	assert.Equal(t, http.StatusOK, serve("apikey wak_qa_secret", middlewares.RequireRealmRole("qa")).Code)
+	assert.Equal(t, http.StatusForbidden, serve("ApiKey wak_qa_secret", middlewares.RequireRealmRole("admin")).Code)
+	assert.Equal(t, http.StatusForbidden, serve("ApiKey wak_qa_secret", middlewares.DenyAPIKeys()).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve("ApiKey wak_unknown").Code)
+	assert.Equal(t, http.StatusInternalServerError, serve("ApiKey broken").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve("Bearer wak_qa_secret").Code)
+}
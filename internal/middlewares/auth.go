@@ -1,7 +1,9 @@
 package middlewares
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
@@ -18,86 +20,148 @@ var (
 
 // AuthMiddleware представляет middleware для авторизации через Keycloak.
 type AuthMiddleware struct {
-	keycloakClient KeycloakClient
-	logger         *zap.Logger
+	keycloakClient  KeycloakClient
+	verifier        TokenVerifier
+	checkRevocation bool
+	revocations     *RevocationList
+	cookies         *SessionCookies
+	refresher       TokenRefresher
+	refreshes       *refreshGroup
+	serviceAccounts ServiceAccounts
+	apiKeys         APIKeyAuthenticator
+	logger          *zap.Logger
+}
+
+// AuthMiddlewareOption настраивает AuthMiddleware.
+type AuthMiddlewareOption func(a *AuthMiddleware)
+
+// WithTokenVerifier включает локальную проверку токенов вместо introspection на каждый запрос.
+func WithTokenVerifier(verifier TokenVerifier) AuthMiddlewareOption {
+	return func(a *AuthMiddleware) {
+		a.verifier = verifier
+	}
+}
+
+// WithRevocationCheck дополнительно проверяет отзыв локально проверенного токена через introspection.
+func WithRevocationCheck(enabled bool) AuthMiddlewareOption {
+	return func(a *AuthMiddleware) {
+		a.checkRevocation = enabled
+	}
+}
+
+// WithRevocationList отклоняет токены сессий, завершенных через logout, не дожидаясь истечения их срока.
+func WithRevocationList(revocations *RevocationList) AuthMiddlewareOption {
+	return func(a *AuthMiddleware) {
+		a.revocations = revocations
+	}
+}
+
+// WithSessionCookies принимает токен из cookie сессии, если нет заголовка Authorization.
+// Если задан refresher, access token обновляется незаметно для клиента незадолго до истечения.
+func WithSessionCookies(cookies *SessionCookies, refresher TokenRefresher) AuthMiddlewareOption {
+	return func(a *AuthMiddleware) {
+		a.cookies = cookies
+		a.refresher = refresher
+		a.refreshes = newRefreshGroup()
+	}
 }
 
 // NewAuthMiddleware создает новый экземпляр middleware авторизации.
-func NewAuthMiddleware(keycloakClient KeycloakClient) *AuthMiddleware {
-	return &AuthMiddleware{
+func NewAuthMiddleware(keycloakClient KeycloakClient, opts ...AuthMiddlewareOption) *AuthMiddleware {
+	a := &AuthMiddleware{
 		keycloakClient: keycloakClient,
 		logger:         zap.L().Named("auth-middleware"),
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
 }
 
 // RequireAuth возвращает middleware функцию, которая требует валидный JWT токен.
 func (a *AuthMiddleware) RequireAuth() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Если Keycloak клиент не настроен, пропускаем проверку
-			if a.keycloakClient == nil {
+			// Если ни Keycloak клиент, ни верификатор не настроены, пропускаем проверку
+			if a.keycloakClient == nil && a.verifier == nil {
 				a.logger.Warn("Keycloak client not configured, skipping auth")
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			// Извлекаем токен из заголовка Authorization
+			if a.apiKeys != nil {
+				if key, ok := extractAPIKey(r); ok {
+					a.serveAPIKey(w, r, next, key)
+					return
+				}
+			}
+
+			// Извлекаем токен из заголовка Authorization, а без него - из cookie сессии
 			tokenStr, err := a.extractToken(r)
+			if errors.Is(err, ErrMissingAuthHeader) && a.cookies != nil {
+				tokenStr, err = a.cookieToken(w, r)
+			}
+			if errors.Is(err, ErrCSRFTokenMismatch) {
+				a.logger.Debug("CSRF token mismatch", zap.String("remote_addr", r.RemoteAddr))
+				WriteErrorResponse(w, ErrInvalidCSRFToken)
+				return
+			}
 			if err != nil {
 				a.logger.Debug("Failed to extract token", zap.Error(err))
 				WriteErrorResponse(w, ErrUnauthorized)
 				return
 			}
 
-			// Проверяем токен через Keycloak
-			tokenResult, err := a.keycloakClient.IntrospectToken(r.Context(), tokenStr)
+			token, tokenClaims, err := a.authenticate(r.Context(), tokenStr)
 			if err != nil {
-				a.logger.Error("Token introspection failed",
+				a.logger.Debug("Token rejected",
 					zap.Error(err),
 					zap.String("remote_addr", r.RemoteAddr))
 				WriteErrorResponse(w, ErrUnauthorized)
 				return
 			}
 
-			// Проверяем что токен активен
-			if !tokenResult.Active {
-				a.logger.Debug("Token is not active",
+			// Проверяем, что сессия не была завершена через logout
+			if a.revocations != nil && a.revocations.IsRevoked(tokenClaims) {
+				a.logger.Debug("Token is revoked",
+					zap.String("subject", tokenClaims.Subject),
 					zap.String("remote_addr", r.RemoteAddr))
 				WriteErrorResponse(w, ErrUnauthorized)
 				return
 			}
 
-			// Парсим токен, используя наши claims (без проверки подписи, это уже сделал Keycloak)
-			tokenClaims := &Claims{}
-			token, _ := jwt.ParseWithClaims(tokenStr, tokenClaims, func(_ *jwt.Token) (any, error) {
-				return nil, errors.New("не проверяем подпись")
-			})
+			ctx := SetToken(r.Context(), tokenStr)
+			// Сохраняем токен в контекст
+			ctx = SetJWTToken(ctx, token)
 
-			// Проверяем, что клеймы валидные, включая проверку Subject и ResourceAccess
-			if err := tokenClaims.Valid(); err != nil {
-				a.logger.Error("Invalid token claims", zap.Error(err))
-				WriteErrorResponse(w, ErrUnauthorized)
+			// Токен внутреннего сервиса не относится к пользователю
+			if principal, ok := a.serviceAccounts.match(tokenClaims); ok {
+				a.logger.Debug("Service authenticated successfully",
+					zap.String("client_id", principal.ClientID),
+					zap.String("remote_addr", r.RemoteAddr))
+				next.ServeHTTP(w, r.WithContext(SetServicePrincipal(ctx, principal)))
 				return
 			}
 
 			// Извлекаем UserID из claims
-			userID := tokenClaims.UserID()
+			userID, err := tokenClaims.UserID()
+			if err != nil {
+				a.logger.Debug("Token subject is not a user",
+					zap.Error(err),
+					zap.String("azp", tokenClaims.AuthorizedParty),
+					zap.String("remote_addr", r.RemoteAddr))
+				WriteErrorResponse(w, ErrUnauthorized)
+				return
+			}
 
 			// Добавляем UserID в контекст
-			ctx := SetUserID(r.Context(), userID)
-			ctx = SetToken(ctx, tokenStr)
+			ctx = SetUserID(ctx, userID)
 
 			// Добавляем роли если есть
 			if realmRoles, exists := tokenClaims.RealmAccess["roles"]; exists && len(realmRoles) > 0 {
 				ctx = SetUserRoles(ctx, realmRoles)
 			}
 
-			// Сохраняем токен в контекст
-			if token == nil {
-				token = &jwt.Token{Claims: tokenClaims}
-			}
-			ctx = SetJWTToken(ctx, token)
-
 			a.logger.Debug("User authenticated successfully",
 				zap.String("user_id", userID.String()),
 				zap.String("remote_addr", r.RemoteAddr))
@@ -108,6 +172,64 @@ func (a *AuthMiddleware) RequireAuth() func(http.Handler) http.Handler {
 	}
 }
 
+// authenticate проверяет токен локально (если настроен верификатор) или через introspection.
+func (a *AuthMiddleware) authenticate(ctx context.Context, tokenStr string) (*jwt.Token, *Claims, error) {
+	if a.verifier != nil {
+		token, err := a.verifier.Verify(ctx, tokenStr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("verify token: %w", err)
+		}
+		tokenClaims, ok := token.Claims.(*Claims)
+		if !ok {
+			return nil, nil, errors.New("unexpected claims type")
+		}
+
+		// Подпись и срок действия проверены, при необходимости спрашиваем Keycloak об отзыве
+		if a.checkRevocation && a.keycloakClient != nil {
+			if err := a.introspect(ctx, tokenStr); err != nil {
+				return nil, nil, err
+			}
+		}
+		return token, tokenClaims, nil
+	}
+
+	// Проверяем токен через Keycloak
+	if err := a.introspect(ctx, tokenStr); err != nil {
+		return nil, nil, err
+	}
+
+	// Парсим токен, используя наши claims (без проверки подписи, это уже сделал Keycloak)
+	tokenClaims := &Claims{}
+	token, _ := jwt.ParseWithClaims(tokenStr, tokenClaims, func(_ *jwt.Token) (any, error) {
+		return nil, errors.New("не проверяем подпись")
+	})
+
+	// Проверяем, что клеймы валидные, включая проверку Subject и ResourceAccess
+	if err := tokenClaims.Valid(); err != nil {
+		return nil, nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+
+	if token == nil {
+		token = &jwt.Token{Claims: tokenClaims}
+	}
+	return token, tokenClaims, nil
+}
+
+// introspect проверяет через Keycloak, что токен активен.
+func (a *AuthMiddleware) introspect(ctx context.Context, tokenStr string) error {
+	tokenResult, err := a.keycloakClient.IntrospectToken(ctx, tokenStr)
+	if err != nil {
+		a.logger.Error("Token introspection failed", zap.Error(err))
+		return fmt.Errorf("introspect token: %w", err)
+	}
+
+	// Проверяем что токен активен
+	if !tokenResult.Active {
+		return errors.New("token is not active")
+	}
+	return nil
+}
+
 // extractToken извлекает JWT токен из заголовка Authorization.
 func (a *AuthMiddleware) extractToken(r *http.Request) (string, error) {
 	authHeader := r.Header.Get("Authorization")
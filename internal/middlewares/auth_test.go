@@ -11,6 +11,7 @@ import (
 	middlewaresmocks "github.com/Fisher-Development/woman-app-backend/internal/middlewares/mocks"
 	"github.com/Fisher-Development/woman-app-backend/internal/types"
 	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -175,6 +176,82 @@ func (s *AuthMiddlewareSuite) TestNoKeycloakClient() {
 	s.Equal(http.StatusInternalServerError, w.Code) // Поскольку userID не найден
 }
 
+func (s *AuthMiddlewareSuite) TestLocalVerification() {
+	const token = "locally.verified.token" //nolint:gosec // Test token string
+
+	verifier := middlewaresmocks.NewMockTokenVerifier(s.ctrl)
+	verifier.EXPECT().
+		Verify(gomock.Any(), token).
+		Return(&jwt.Token{Claims: &middlewares.Claims{
+			StandardClaims: jwt.StandardClaims{Subject: "5cb40dc0-a249-4783-a301-9e1f3cf3ea41"},
+		}}, nil)
+
+	// Introspection не вызывается, так как проверка отзыва выключена
+	router := s.newRouter(middlewares.NewAuthMiddleware(s.keycloakClient, middlewares.WithTokenVerifier(verifier)))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", bearerPrefix+token)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), "5cb40dc0-a249-4783-a301-9e1f3cf3ea41")
+}
+
+func (s *AuthMiddlewareSuite) TestLocalVerificationFailed() {
+	const token = "forged.jwt.token" //nolint:gosec // Test token string
+
+	verifier := middlewaresmocks.NewMockTokenVerifier(s.ctrl)
+	verifier.EXPECT().
+		Verify(gomock.Any(), token).
+		Return(nil, middlewares.ErrUnknownSigningKey)
+
+	router := s.newRouter(middlewares.NewAuthMiddleware(s.keycloakClient, middlewares.WithTokenVerifier(verifier)))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", bearerPrefix+token)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	s.Equal(http.StatusUnauthorized, w.Code)
+}
+
+func (s *AuthMiddlewareSuite) TestLocalVerificationWithRevokedToken() {
+	const token = "revoked.jwt.token" //nolint:gosec // Test token string
+
+	verifier := middlewaresmocks.NewMockTokenVerifier(s.ctrl)
+	verifier.EXPECT().
+		Verify(gomock.Any(), token).
+		Return(&jwt.Token{Claims: &middlewares.Claims{
+			StandardClaims: jwt.StandardClaims{Subject: "5cb40dc0-a249-4783-a301-9e1f3cf3ea41"},
+		}}, nil)
+	s.keycloakClient.EXPECT().
+		IntrospectToken(gomock.Any(), token).
+		Return(&keycloakclient.IntrospectTokenResult{Active: false}, nil)
+
+	router := s.newRouter(middlewares.NewAuthMiddleware(s.keycloakClient,
+		middlewares.WithTokenVerifier(verifier),
+		middlewares.WithRevocationCheck(true),
+	))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", bearerPrefix+token)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	s.Equal(http.StatusUnauthorized, w.Code)
+}
+
+func (s *AuthMiddlewareSuite) newRouter(authMdlwr *middlewares.AuthMiddleware) *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(authMdlwr.RequireAuth())
+	router.Get("/test", s.testHandler)
+	return router
+}
+
 // Тест функций работы с контекстом
 
 func TestContextFunctions(t *testing.T) {
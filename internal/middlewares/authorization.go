@@ -0,0 +1,138 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Role роль realm (Resource пустой) или клиента Keycloak.
+type Role struct {
+	Resource string
+	Name     string
+}
+
+// RealmRole описывает роль realm.
+func RealmRole(name string) Role {
+	return Role{Name: name}
+}
+
+// ResourceRole описывает роль клиента (resource_access).
+func ResourceRole(resource, name string) Role {
+	return Role{Resource: resource, Name: name}
+}
+
+// ParseRole разбирает роль в виде "role" или "client:role".
+func ParseRole(s string) Role {
+	if resource, name, ok := strings.Cut(s, ":"); ok {
+		return ResourceRole(resource, name)
+	}
+	return RealmRole(s)
+}
+
+// String возвращает роль в виде "role" или "client:role".
+func (r Role) String() string {
+	if r.Resource == "" {
+		return r.Name
+	}
+	return r.Resource + ":" + r.Name
+}
+
+// grantedTo проверяет наличие роли в claims.
+func (r Role) grantedTo(claims *Claims) bool {
+	if r.Resource == "" {
+		return claims.HasRealmRole(r.Name)
+	}
+	return claims.HasResourceRole(r.Resource, r.Name)
+}
+
+// RequireRealmRole требует роль realm. Используется после RequireAuth.
+func RequireRealmRole(role string) func(http.Handler) http.Handler {
+	return RequireAnyRole(RealmRole(role))
+}
+
+// RequireResourceRole требует роль клиента. Используется после RequireAuth.
+func RequireResourceRole(resource, role string) func(http.Handler) http.Handler {
+	return RequireAnyRole(ResourceRole(resource, role))
+}
+
+// RequireAnyRole требует хотя бы одну из перечисленных ролей. Используется после RequireAuth.
+func RequireAnyRole(roles ...Role) func(http.Handler) http.Handler {
+	required := make([]string, 0, len(roles))
+	for _, role := range roles {
+		required = append(required, role.String())
+	}
+
+	return authorize("role", strings.Join(required, ","), func(claims *Claims) bool {
+		for _, role := range roles {
+			if role.grantedTo(claims) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// RequireScope требует, чтобы токен был выдан с указанным scope. Используется после RequireAuth.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return authorize("scope", scope, func(claims *Claims) bool {
+		return claims.HasScope(scope)
+	})
+}
+
+// RequireVerifiedEmail пропускает только пользователей с подтвержденным email (claim email_verified).
+// Используется после RequireAuth на маршрутах, недоступных до подтверждения.
+func RequireVerifiedEmail() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := GetClaims(r.Context())
+			if !ok {
+				zap.L().Named("authorization").Warn("No claims in context, ensure RequireAuth middleware is used",
+					zap.String("path", r.URL.Path))
+				WriteErrorResponse(w, ErrUnauthorized)
+				return
+			}
+
+			if !claims.EmailVerified {
+				zap.L().Named("authorization").Info("Email is not verified",
+					zap.String("subject", claims.Subject),
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path))
+				WriteErrorResponse(w, ErrEmailNotVerified)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authorize строит middleware, пропускающую запрос только при выполнении условия allowed.
+func authorize(kind, required string, allowed func(claims *Claims) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := zap.L().Named("authorization")
+
+			claims, ok := GetClaims(r.Context())
+			if !ok {
+				logger.Warn("No claims in context, ensure RequireAuth middleware is used",
+					zap.String("path", r.URL.Path))
+				WriteErrorResponse(w, ErrUnauthorized)
+				return
+			}
+
+			if !allowed(claims) {
+				logger.Info("Access denied",
+					zap.String("subject", claims.Subject),
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+					zap.String("required_"+kind, required))
+				WriteErrorResponse(w, ErrForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
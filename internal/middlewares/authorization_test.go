@@ -0,0 +1,158 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+)
+
+func TestAuthorizationMiddlewares(t *testing.T) {
+	claims := &middlewares.Claims{
+		StandardClaims: jwt.StandardClaims{Subject: "5cb40dc0-a249-4783-a301-9e1f3cf3ea41"},
+		RealmAccess:    map[string][]string{"roles": {"offline_access", "admin"}},
+		ResourceAccess: map[string]struct {
+			Roles []string `json:"roles,omitempty"`
+		}{
+			"back-end": {Roles: []string{"manage-users"}},
+		},
+		Scope: "openid profile email",
+	}
+
+	cases := []struct {
+		name       string
+		claims     *middlewares.Claims
+		middleware func(http.Handler) http.Handler
+		wantStatus int
+	}{
+		{
+			name:       "realm role granted",
+			claims:     claims,
+			middleware: middlewares.RequireRealmRole("admin"),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "realm role missing",
+			claims:     claims,
+			middleware: middlewares.RequireRealmRole("superadmin"),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "resource role granted",
+			claims:     claims,
+			middleware: middlewares.RequireResourceRole("back-end", "manage-users"),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "resource role of another client",
+			claims:     claims,
+			middleware: middlewares.RequireResourceRole("account", "manage-users"),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "any role granted",
+			claims: claims,
+			middleware: middlewares.RequireAnyRole(
+				middlewares.RealmRole("superadmin"),
+				middlewares.ResourceRole("back-end", "manage-users"),
+			),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "none of roles granted",
+			claims: claims,
+			middleware: middlewares.RequireAnyRole(
+				middlewares.RealmRole("superadmin"),
+				middlewares.ResourceRole("back-end", "view-reports"),
+			),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "scope granted",
+			claims:     claims,
+			middleware: middlewares.RequireScope("email"),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "scope missing",
+			claims:     claims,
+			middleware: middlewares.RequireScope("offline_access"),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "no claims in context",
+			middleware: middlewares.RequireRealmRole("admin"),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := tt.middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tt.claims != nil {
+				req = req.WithContext(middlewares.SetJWTToken(req.Context(), &jwt.Token{Claims: tt.claims}))
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusForbidden {
+				assert.Contains(t, w.Body.String(), "forbidden")
+			}
+		})
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	cases := []struct {
+		name       string
+		claims     *middlewares.Claims
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "verified email",
+			claims:     &middlewares.Claims{EmailVerified: true},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unverified email",
+			claims:     &middlewares.Claims{},
+			wantStatus: http.StatusForbidden,
+			wantError:  "email_not_verified",
+		},
+		{
+			name:       "no claims in context",
+			wantStatus: http.StatusUnauthorized,
+			wantError:  "unauthorized",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := middlewares.RequireVerifiedEmail()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/user/dashboard", nil)
+			if tt.claims != nil {
+				req = req.WithContext(middlewares.SetJWTToken(req.Context(), &jwt.Token{Claims: tt.claims}))
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantError)
+		})
+	}
+}
@@ -26,6 +26,18 @@ var (
 		Code:    http.StatusForbidden,
 	}
 
+	ErrEmailNotVerified = &ErrorResponse{
+		Error:   "email_not_verified",
+		Message: "Email address is not verified",
+		Code:    http.StatusForbidden,
+	}
+
+	ErrInvalidCSRFToken = &ErrorResponse{
+		Error:   "invalid_csrf_token",
+		Message: "CSRF token is missing or does not match",
+		Code:    http.StatusForbidden,
+	}
+
 	ErrInternalError = &ErrorResponse{
 		Error:   "internal_error",
 		Message: "Internal server error",
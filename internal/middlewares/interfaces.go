@@ -4,6 +4,7 @@ import (
 	"context"
 
 	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/golang-jwt/jwt"
 )
 
 //go:generate mockgen -source=interfaces.go -destination=mocks/keycloak_client_mock.go
@@ -11,3 +12,25 @@ import (
 type KeycloakClient interface {
 	IntrospectToken(ctx context.Context, token string) (*keycloakclient.IntrospectTokenResult, error)
 }
+
+// JWKSClient источник discovery документа и публичных ключей realm.
+type JWKSClient interface {
+	OpenIDConfiguration(ctx context.Context) (*keycloakclient.OpenIDConfiguration, error)
+	JWKS(ctx context.Context, jwksURI string) (*keycloakclient.JSONWebKeySet, error)
+}
+
+// TokenVerifier проверяет токен локально и возвращает его с разобранными Claims.
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*jwt.Token, error)
+}
+
+// TokenRefresher обновляет токены по refresh token из cookie сессии.
+type TokenRefresher interface {
+	RefreshAccessToken(ctx context.Context, refreshToken string) (*keycloakclient.TokenResponse, error)
+}
+
+// APIKeyAuthenticator проверяет персональные API ключи.
+// Для неизвестного, отозванного или истекшего ключа возвращает ErrInvalidAPIKey.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*APIKeyIdentity, error)
+}
@@ -0,0 +1,189 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+)
+
+const (
+	defaultIntrospectionCacheTTL         = 30 * time.Second
+	defaultIntrospectionNegativeCacheTTL = 5 * time.Second
+	introspectionCacheCleanupInterval    = time.Minute
+)
+
+// IntrospectionCacheStats счетчики работы кэша introspection.
+type IntrospectionCacheStats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Coalesced int64 `json:"coalesced"`
+	Entries   int   `json:"entries"`
+}
+
+type introspectionEntry struct {
+	result    *keycloakclient.IntrospectTokenResult
+	expiresAt time.Time
+}
+
+type introspectionCall struct {
+	done   chan struct{}
+	result *keycloakclient.IntrospectTokenResult
+	err    error
+}
+
+// CachingKeycloakClient декоратор KeycloakClient, кэширующий результаты introspection.
+// Активные токены хранятся до min(ttl, exp), неактивные - negativeTTL.
+// Параллельные запросы одного и того же токена схлопываются в один вызов Keycloak.
+type CachingKeycloakClient struct {
+	next        KeycloakClient
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+	logger      *zap.Logger
+
+	mu       sync.Mutex
+	entries  map[string]introspectionEntry
+	inflight map[string]*introspectionCall
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	coalesced atomic.Int64
+}
+
+// NewCachingKeycloakClient создает кэширующий декоратор. Нулевые TTL заменяются значениями по умолчанию.
+func NewCachingKeycloakClient(next KeycloakClient, ttl, negativeTTL time.Duration) *CachingKeycloakClient {
+	if ttl <= 0 {
+		ttl = defaultIntrospectionCacheTTL
+	}
+	if negativeTTL <= 0 {
+		negativeTTL = defaultIntrospectionNegativeCacheTTL
+	}
+	return &CachingKeycloakClient{
+		next:        next,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+		logger:      zap.L().Named("introspection-cache"),
+		entries:     make(map[string]introspectionEntry),
+		inflight:    make(map[string]*introspectionCall),
+	}
+}
+
+// IntrospectToken возвращает результат из кэша или запрашивает его у Keycloak.
+func (c *CachingKeycloakClient) IntrospectToken(
+	ctx context.Context,
+	token string,
+) (*keycloakclient.IntrospectTokenResult, error) {
+	key := tokenHash(token)
+
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok && c.now().Before(entry.expiresAt) {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return entry.result, nil
+	}
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		c.coalesced.Add(1)
+		select {
+		case <-call.done:
+			return call.result, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	call := &introspectionCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	c.misses.Add(1)
+	// Запрос не привязан к отмене запроса-лидера: его результат нужен и ожидающим запросам
+	call.result, call.err = c.next.IntrospectToken(context.WithoutCancel(ctx), token)
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	// Ошибки не кэшируем: следующий запрос снова пойдет в Keycloak
+	if call.err == nil {
+		if expiresAt, ok := c.expiresAt(call.result); ok {
+			c.entries[key] = introspectionEntry{result: call.result, expiresAt: expiresAt}
+		}
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.result, call.err
+}
+
+// expiresAt вычисляет время жизни записи для результата introspection.
+func (c *CachingKeycloakClient) expiresAt(result *keycloakclient.IntrospectTokenResult) (time.Time, bool) {
+	now := c.now()
+	if !result.Active {
+		return now.Add(c.negativeTTL), true
+	}
+
+	expiresAt := now.Add(c.ttl)
+	if result.Exp > 0 {
+		tokenExp := time.Unix(int64(result.Exp), 0)
+		if tokenExp.Before(expiresAt) {
+			expiresAt = tokenExp
+		}
+	}
+	return expiresAt, expiresAt.After(now)
+}
+
+// Stats возвращает текущие значения счетчиков кэша.
+func (c *CachingKeycloakClient) Stats() IntrospectionCacheStats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+
+	return IntrospectionCacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Coalesced: c.coalesced.Load(),
+		Entries:   entries,
+	}
+}
+
+// Run периодически удаляет устаревшие записи до отмены контекста.
+func (c *CachingKeycloakClient) Run(ctx context.Context) error {
+	ticker := time.NewTicker(introspectionCacheCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			c.cleanup()
+		}
+	}
+}
+
+func (c *CachingKeycloakClient) cleanup() {
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+
+	c.logger.Debug("Introspection cache cleaned up", zap.Int("entries", len(c.entries)))
+}
+
+// tokenHash возвращает ключ кэша, чтобы не держать сами токены в памяти.
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,200 @@
+package middlewares_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	middlewaresmocks "github.com/Fisher-Development/woman-app-backend/internal/middlewares/mocks"
+)
+
+const cachedToken = "cached.jwt.token" //nolint:gosec // Test token string
+
+func activeResult(exp time.Time) *keycloakclient.IntrospectTokenResult {
+	return &keycloakclient.IntrospectTokenResult{Active: true, Exp: int(exp.Unix())}
+}
+
+func TestCachingKeycloakClient_Hit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := middlewaresmocks.NewMockKeycloakClient(ctrl)
+	next.EXPECT().
+		IntrospectToken(gomock.Any(), cachedToken).
+		Return(activeResult(time.Now().Add(time.Hour)), nil).
+		Times(1)
+
+	cache := middlewares.NewCachingKeycloakClient(next, time.Minute, time.Second)
+	for range 3 {
+		result, err := cache.IntrospectToken(context.Background(), cachedToken)
+		require.NoError(t, err)
+		assert.True(t, result.Active)
+	}
+
+	stats := cache.Stats()
+	assert.EqualValues(t, 1, stats.Misses)
+	assert.EqualValues(t, 2, stats.Hits)
+	assert.Equal(t, 1, stats.Entries)
+}
+
+func TestCachingKeycloakClient_TTLExpired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := middlewaresmocks.NewMockKeycloakClient(ctrl)
+	next.EXPECT().
+		IntrospectToken(gomock.Any(), cachedToken).
+		Return(activeResult(time.Now().Add(time.Hour)), nil).
+		Times(2)
+
+	cache := middlewares.NewCachingKeycloakClient(next, 20*time.Millisecond, time.Second)
+
+	_, err := cache.IntrospectToken(context.Background(), cachedToken)
+	require.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+	_, err = cache.IntrospectToken(context.Background(), cachedToken)
+	require.NoError(t, err)
+}
+
+func TestCachingKeycloakClient_BoundedByTokenExpiry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := middlewaresmocks.NewMockKeycloakClient(ctrl)
+	// Токен уже истек: TTL кэша не должен продлевать ему жизнь
+	next.EXPECT().
+		IntrospectToken(gomock.Any(), cachedToken).
+		Return(activeResult(time.Now().Add(-time.Second)), nil).
+		Times(2)
+
+	cache := middlewares.NewCachingKeycloakClient(next, time.Minute, time.Second)
+	for range 2 {
+		_, err := cache.IntrospectToken(context.Background(), cachedToken)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 0, cache.Stats().Entries)
+}
+
+func TestCachingKeycloakClient_NegativeCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := middlewaresmocks.NewMockKeycloakClient(ctrl)
+	next.EXPECT().
+		IntrospectToken(gomock.Any(), cachedToken).
+		Return(&keycloakclient.IntrospectTokenResult{Active: false}, nil).
+		Times(1)
+
+	cache := middlewares.NewCachingKeycloakClient(next, time.Minute, time.Second)
+	for range 2 {
+		result, err := cache.IntrospectToken(context.Background(), cachedToken)
+		require.NoError(t, err)
+		assert.False(t, result.Active)
+	}
+}
+
+func TestCachingKeycloakClient_ErrorsAreNotCached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := middlewaresmocks.NewMockKeycloakClient(ctrl)
+	next.EXPECT().
+		IntrospectToken(gomock.Any(), cachedToken).
+		Return(nil, errors.New("keycloak is down")).
+		Times(2)
+
+	cache := middlewares.NewCachingKeycloakClient(next, time.Minute, time.Second)
+	for range 2 {
+		_, err := cache.IntrospectToken(context.Background(), cachedToken)
+		require.Error(t, err)
+	}
+}
+
+func TestCachingKeycloakClient_CoalescesConcurrentLookups(t *testing.T) {
+	const workers = 10
+
+	ctrl := gomock.NewController(t)
+	next := middlewaresmocks.NewMockKeycloakClient(ctrl)
+
+	release := make(chan struct{})
+	next.EXPECT().
+		IntrospectToken(gomock.Any(), cachedToken).
+		DoAndReturn(func(context.Context, string) (*keycloakclient.IntrospectTokenResult, error) {
+			<-release
+			return activeResult(time.Now().Add(time.Hour)), nil
+		}).
+		Times(1)
+
+	cache := middlewares.NewCachingKeycloakClient(next, time.Minute, time.Second)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := cache.IntrospectToken(context.Background(), cachedToken)
+			assert.NoError(t, err)
+			assert.True(t, result.Active)
+		}()
+	}
+
+	// Даем горутинам дойти до ожидания первого вызова
+	require.Eventually(t, func() bool {
+		return cache.Stats().Coalesced == workers-1
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, cache.Stats().Misses)
+}
+
+func TestCachingKeycloakClient_CanceledRequests(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := middlewaresmocks.NewMockKeycloakClient(ctrl)
+
+	release := make(chan struct{})
+	next.EXPECT().
+		IntrospectToken(gomock.Any(), cachedToken).
+		DoAndReturn(func(ctx context.Context, _ string) (*keycloakclient.IntrospectTokenResult, error) {
+			<-release
+			// Отмена запроса-лидера не должна прерывать introspection
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return activeResult(time.Now().Add(time.Hour)), nil
+		}).
+		Times(1)
+
+	cache := middlewares.NewCachingKeycloakClient(next, time.Minute, time.Second)
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderDone := make(chan error, 1)
+	go func() {
+		_, err := cache.IntrospectToken(leaderCtx, cachedToken)
+		leaderDone <- err
+	}()
+	require.Eventually(t, func() bool { return cache.Stats().Misses == 1 }, time.Second, time.Millisecond)
+
+	// Ожидающий запрос уходит по своей отмене, не дожидаясь Keycloak
+	waiterCtx, cancelWaiter := context.WithCancel(context.Background())
+	waiterDone := make(chan error, 1)
+	go func() {
+		_, err := cache.IntrospectToken(waiterCtx, cachedToken)
+		waiterDone <- err
+	}()
+	require.Eventually(t, func() bool { return cache.Stats().Coalesced == 1 }, time.Second, time.Millisecond)
+	cancelWaiter()
+	select {
+	case err := <-waiterDone:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("waiter is blocked after cancellation")
+	}
+
+	cancelLeader()
+	close(release)
+	require.NoError(t, <-leaderDone)
+
+	result, err := cache.IntrospectToken(context.Background(), cachedToken)
+	require.NoError(t, err)
+	assert.True(t, result.Active)
+	assert.EqualValues(t, 1, cache.Stats().Hits)
+}
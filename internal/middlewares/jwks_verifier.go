@@ -0,0 +1,306 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+)
+
+const (
+	defaultJWKSRefreshInterval = 15 * time.Minute
+	// minJWKSRefreshInterval защищает Keycloak от перезапросов JWKS токенами с несуществующим kid.
+	minJWKSRefreshInterval = 30 * time.Second
+)
+
+// Ошибки локальной проверки токена.
+var (
+	ErrUnexpectedSigningMethod = errors.New("unexpected signing method")
+	ErrUnknownSigningKey       = errors.New("unknown signing key")
+	ErrInvalidIssuer           = errors.New("invalid issuer")
+	ErrInvalidAudience         = errors.New("invalid audience")
+	ErrExpiryNotDefined        = errors.New(`"exp" is not defined`)
+)
+
+// supportedSigningAlgs алгоритмы подписи, которые мы принимаем.
+var supportedSigningAlgs = map[string]bool{
+	jwt.SigningMethodRS256.Alg(): true,
+	jwt.SigningMethodES256.Alg(): true,
+}
+
+type signingKey struct {
+	alg string
+	key any
+}
+
+// JWKSVerifier проверяет подпись, issuer, audience и срок действия токена локально,
+// используя публичные ключи realm из JWKS.
+type JWKSVerifier struct {
+	client          JWKSClient
+	audience        string
+	refreshInterval time.Duration
+	logger          *zap.Logger
+
+	// refreshMu схлопывает параллельные обновления ключей в одно.
+	refreshMu sync.Mutex
+
+	mu     sync.RWMutex
+	issuer string
+	// algs алгоритмы, которые мы поддерживаем и которые объявил провайдер
+	algs        map[string]bool
+	keys        map[string]signingKey
+	lastRefresh time.Time
+}
+
+// NewJWKSVerifier создает верификатор токенов. Пустой audience отключает проверку "aud",
+// нулевой refreshInterval заменяется значением по умолчанию.
+func NewJWKSVerifier(client JWKSClient, audience string, refreshInterval time.Duration) *JWKSVerifier {
+	if refreshInterval <= 0 {
+		refreshInterval = defaultJWKSRefreshInterval
+	}
+	return &JWKSVerifier{
+		client:          client,
+		audience:        audience,
+		refreshInterval: refreshInterval,
+		logger:          zap.L().Named("jwks-verifier"),
+		keys:            make(map[string]signingKey),
+	}
+}
+
+// Run загружает ключи и периодически обновляет их до отмены контекста.
+func (v *JWKSVerifier) Run(ctx context.Context) error {
+	if err := v.Refresh(ctx); err != nil {
+		// Не валим приложение: ключи будут загружены при первом запросе или следующем тике
+		v.logger.Error("Initial JWKS load failed", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(v.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := v.Refresh(ctx); err != nil {
+				v.logger.Error("JWKS refresh failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Refresh перечитывает discovery документ и набор ключей realm.
+func (v *JWKSVerifier) Refresh(ctx context.Context) error {
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+
+	return v.refresh(ctx)
+}
+
+func (v *JWKSVerifier) refresh(ctx context.Context) error {
+	oidcConfig, err := v.client.OpenIDConfiguration(ctx)
+	if err != nil {
+		return fmt.Errorf("get openid configuration: %w", err)
+	}
+	if oidcConfig.Issuer == "" || oidcConfig.JWKSURI == "" {
+		return errors.New("openid configuration has no issuer or jwks_uri")
+	}
+
+	jwks, err := v.client.JWKS(ctx, oidcConfig.JWKSURI)
+	if err != nil {
+		return fmt.Errorf("get jwks: %w", err)
+	}
+
+	keys := make(map[string]signingKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseJSONWebKey(jwk)
+		if err != nil {
+			v.logger.Warn("Skip unsupported JWK", zap.String("kid", jwk.Kid), zap.Error(err))
+			continue
+		}
+		keys[jwk.Kid] = signingKey{alg: jwk.Alg, key: key}
+	}
+
+	algs := supportedSigningAlgs
+	if advertised := oidcConfig.SigningAlgorithms(); len(advertised) > 0 {
+		algs = make(map[string]bool, len(advertised))
+		for _, alg := range advertised {
+			if supportedSigningAlgs[alg] {
+				algs[alg] = true
+			}
+		}
+	}
+
+	v.mu.Lock()
+	v.issuer = oidcConfig.Issuer
+	v.algs = algs
+	v.keys = keys
+	v.lastRefresh = time.Now()
+	v.mu.Unlock()
+
+	v.logger.Debug("JWKS refreshed", zap.Int("keys", len(keys)))
+	return nil
+}
+
+// Verify проверяет токен и возвращает его вместе с разобранными Claims.
+func (v *JWKSVerifier) Verify(ctx context.Context, tokenStr string) (*jwt.Token, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (any, error) {
+		alg := t.Method.Alg()
+		if !v.allowedAlg(alg) {
+			return nil, fmt.Errorf("%w: %s", ErrUnexpectedSigningMethod, alg)
+		}
+
+		kid, _ := t.Header["kid"].(string)
+		key, err := v.signingKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if key.alg != "" && key.alg != alg {
+			return nil, fmt.Errorf("%w: key %q is for %s", ErrUnexpectedSigningMethod, kid, key.alg)
+		}
+		return key.key, nil
+	})
+	if err != nil {
+		// jwt.ValidationError не реализует Unwrap, достаем исходную ошибку сами
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) && validationErr.Inner != nil {
+			err = validationErr.Inner
+		}
+		return nil, fmt.Errorf("parse token: %w", err)
+	}
+
+	if claims.ExpiresAt == 0 {
+		return nil, ErrExpiryNotDefined
+	}
+
+	v.mu.RLock()
+	issuer := v.issuer
+	v.mu.RUnlock()
+	if claims.Issuer != issuer {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidIssuer, claims.Issuer)
+	}
+
+	if v.audience != "" && !claims.Audience.Contains(v.audience) {
+		return nil, ErrInvalidAudience
+	}
+
+	return token, nil
+}
+
+// allowedAlg проверяет алгоритм подписи. До первой загрузки discovery документа
+// принимаются все поддерживаемые алгоритмы.
+func (v *JWKSVerifier) allowedAlg(alg string) bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if v.algs == nil {
+		return supportedSigningAlgs[alg]
+	}
+	return v.algs[alg]
+}
+
+// signingKey возвращает ключ по kid, обновляя JWKS при ротации ключей в realm.
+func (v *JWKSVerifier) signingKey(ctx context.Context, kid string) (signingKey, error) {
+	if key, ok := v.lookup(kid); ok {
+		return key, nil
+	}
+
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+
+	// Пока ждали блокировку, ключи мог обновить другой запрос
+	if key, ok := v.lookup(kid); ok {
+		return key, nil
+	}
+
+	v.mu.RLock()
+	lastRefresh := v.lastRefresh
+	v.mu.RUnlock()
+	if time.Since(lastRefresh) < minJWKSRefreshInterval {
+		return signingKey{}, fmt.Errorf("%w: %q", ErrUnknownSigningKey, kid)
+	}
+
+	if err := v.refresh(ctx); err != nil {
+		return signingKey{}, fmt.Errorf("refresh jwks: %w", err)
+	}
+
+	if key, ok := v.lookup(kid); ok {
+		return key, nil
+	}
+	return signingKey{}, fmt.Errorf("%w: %q", ErrUnknownSigningKey, kid)
+}
+
+func (v *JWKSVerifier) lookup(kid string) (signingKey, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+// parseJSONWebKey строит публичный ключ из JWK.
+func parseJSONWebKey(jwk keycloakclient.JSONWebKey) (any, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("decode modulus: %v", err)
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("decode exponent: %v", err)
+		}
+		if !e.IsInt64() {
+			return nil, errors.New("exponent is too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, fmt.Errorf("decode x: %v", err)
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decode y: %v", err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
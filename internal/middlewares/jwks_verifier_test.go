@@ -0,0 +1,273 @@
+package middlewares_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+)
+
+const (
+	testRealm   = "Testing"
+	testSubject = "5cb40dc0-a249-4783-a301-9e1f3cf3ea41"
+	rsaKid      = "rsa-key"
+	ecKid       = "ec-key"
+)
+
+type jwksServer struct {
+	*httptest.Server
+	issuer       string
+	rsaKey       *rsa.PrivateKey
+	ecKey        *ecdsa.PrivateKey
+	jwksRequests atomic.Int32
+	// algs объявленные в discovery документе алгоритмы подписи
+	algs []string
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	s := &jwksServer{rsaKey: rsaKey, ecKey: ecKey}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/realms/"+testRealm+"/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, keycloakclient.OpenIDConfiguration{
+			Issuer:        s.issuer,
+			TokenEndpoint: s.issuer + "/protocol/openid-connect/token",
+			JWKSURI:       s.issuer + "/protocol/openid-connect/certs",
+
+			IDTokenSigningAlgValuesSupported: s.algs,
+		})
+	})
+	mux.HandleFunc("/realms/"+testRealm+"/protocol/openid-connect/certs", func(w http.ResponseWriter, _ *http.Request) {
+		s.jwksRequests.Add(1)
+		b64 := base64.RawURLEncoding.EncodeToString
+		writeJSON(w, keycloakclient.JSONWebKeySet{Keys: []keycloakclient.JSONWebKey{
+			{
+				Kid: rsaKid, Kty: "RSA", Alg: "RS256", Use: "sig",
+				N: b64(rsaKey.N.Bytes()), E: b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				Kid: ecKid, Kty: "EC", Alg: "ES256", Use: "sig", Crv: "P-256",
+				X: b64(ecKey.X.Bytes()), Y: b64(ecKey.Y.Bytes()),
+			},
+			{Kid: "enc-key", Kty: "RSA", Alg: "RSA-OAEP", Use: "enc"},
+		}})
+	})
+
+	s.Server = httptest.NewServer(mux)
+	s.issuer = s.URL + "/realms/" + testRealm
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+type testClaims struct {
+	jwt.StandardClaims
+	Aud            []string                       `json:"aud,omitempty"`
+	ResourceAccess map[string]map[string][]string `json:"resource_access,omitempty"` //nolint:tagliatelle // Keycloak API format
+}
+
+func (s *jwksServer) claims() testClaims {
+	return testClaims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   testSubject,
+			Issuer:    s.issuer,
+			ExpiresAt: time.Now().Add(5 * time.Minute).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+		Aud:            []string{"back-end", "account"},
+		ResourceAccess: map[string]map[string][]string{"account": {"roles": {"view-profile"}}},
+	}
+}
+
+func (s *jwksServer) sign(t *testing.T, method jwt.SigningMethod, kid string, claims testClaims) string {
+	t.Helper()
+
+	var key any = s.rsaKey
+	if method == jwt.SigningMethodES256 {
+		key = s.ecKey
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func newVerifier(t *testing.T, srv *jwksServer, audience string) *middlewares.JWKSVerifier {
+	t.Helper()
+
+	kc, err := keycloakclient.New(keycloakclient.NewOptions(srv.URL, testRealm, "back-end", "secret"))
+	require.NoError(t, err)
+	return middlewares.NewJWKSVerifier(kc, audience, time.Minute)
+}
+
+func TestJWKSVerifier_Verify(t *testing.T) {
+	srv := newJWKSServer(t)
+
+	expired := srv.claims()
+	expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+
+	foreignIssuer := srv.claims()
+	foreignIssuer.Issuer = "https://evil.example.com/realms/" + testRealm
+
+	noExpiry := srv.claims()
+	noExpiry.ExpiresAt = 0
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodRS256, srv.claims()).SignedString(otherKey)
+	require.NoError(t, err)
+	forgedWithKid := jwt.NewWithClaims(jwt.SigningMethodRS256, srv.claims())
+	forgedWithKid.Header["kid"] = rsaKid
+	forgedSigned, err := forgedWithKid.SignedString(otherKey)
+	require.NoError(t, err)
+
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, srv.claims())
+	hmacToken.Header["kid"] = rsaKid
+	hmacSigned, err := hmacToken.SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	cases := []struct {
+		name     string
+		token    string
+		audience string
+		wantErr  error
+		wantFail bool // ошибка ожидается, но ее тип определяется библиотекой jwt
+	}{
+		{
+			name:  "valid RS256",
+			token: srv.sign(t, jwt.SigningMethodRS256, rsaKid, srv.claims()),
+		},
+		{
+			name:  "valid ES256",
+			token: srv.sign(t, jwt.SigningMethodES256, ecKid, srv.claims()),
+		},
+		{
+			name:     "audience matches",
+			token:    srv.sign(t, jwt.SigningMethodRS256, rsaKid, srv.claims()),
+			audience: "back-end",
+		},
+		{
+			name:     "audience mismatch",
+			token:    srv.sign(t, jwt.SigningMethodRS256, rsaKid, srv.claims()),
+			audience: "woman-app-admin",
+			wantErr:  middlewares.ErrInvalidAudience,
+		},
+		{
+			name:    "foreign issuer",
+			token:   srv.sign(t, jwt.SigningMethodRS256, rsaKid, foreignIssuer),
+			wantErr: middlewares.ErrInvalidIssuer,
+		},
+		{
+			name:    "no expiry",
+			token:   srv.sign(t, jwt.SigningMethodRS256, rsaKid, noExpiry),
+			wantErr: middlewares.ErrExpiryNotDefined,
+		},
+		{
+			name:     "expired",
+			token:    srv.sign(t, jwt.SigningMethodRS256, rsaKid, expired),
+			wantFail: true,
+		},
+		{
+			name:    "unknown kid",
+			token:   forged,
+			wantErr: middlewares.ErrUnknownSigningKey,
+		},
+		{
+			name:     "forged signature",
+			token:    forgedSigned,
+			wantFail: true,
+		},
+		{
+			name:    "HS256 is not accepted",
+			token:   hmacSigned,
+			wantErr: middlewares.ErrUnexpectedSigningMethod,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := newVerifier(t, srv, tt.audience)
+
+			token, err := verifier.Verify(context.Background(), tt.token)
+			if tt.wantFail {
+				require.Error(t, err)
+				return
+			}
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			claims, ok := token.Claims.(*middlewares.Claims)
+			require.True(t, ok)
+			userID, err := claims.UserID()
+			require.NoError(t, err)
+			assert.Equal(t, testSubject, userID.String())
+			assert.Equal(t, middlewares.Audience{"back-end", "account"}, claims.Audience)
+		})
+	}
+}
+
+func TestJWKSVerifier_UnknownKidRefreshIsRateLimited(t *testing.T) {
+	srv := newJWKSServer(t)
+	verifier := newVerifier(t, srv, "")
+	ctx := context.Background()
+
+	require.NoError(t, verifier.Refresh(ctx))
+	require.EqualValues(t, 1, srv.jwksRequests.Load())
+
+	claims := srv.claims()
+	for range 3 {
+		_, err := verifier.Verify(ctx, srv.sign(t, jwt.SigningMethodRS256, "rotated-key", claims))
+		require.ErrorIs(t, err, middlewares.ErrUnknownSigningKey)
+	}
+
+	// Ключи только что обновлены, поэтому повторных запросов JWKS быть не должно
+	assert.EqualValues(t, 1, srv.jwksRequests.Load())
+}
+
+func TestJWKSVerifier_AdvertisedAlgorithms(t *testing.T) {
+	srv := newJWKSServer(t)
+	srv.algs = []string{"RS256", "HS256"}
+	verifier := newVerifier(t, srv, "")
+	ctx := context.Background()
+
+	require.NoError(t, verifier.Refresh(ctx))
+
+	_, err := verifier.Verify(ctx, srv.sign(t, jwt.SigningMethodRS256, rsaKid, srv.claims()))
+	require.NoError(t, err)
+
+	// ES256 мы поддерживаем, но провайдер его не объявил
+	_, err = verifier.Verify(ctx, srv.sign(t, jwt.SigningMethodES256, ecKid, srv.claims()))
+	require.ErrorIs(t, err, middlewares.ErrUnexpectedSigningMethod)
+}
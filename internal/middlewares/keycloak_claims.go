@@ -1,7 +1,10 @@
 package middlewares
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/Fisher-Development/woman-app-backend/internal/types"
 	"github.com/golang-jwt/jwt"
@@ -14,11 +17,55 @@ var (
 
 type Claims struct {
 	jwt.StandardClaims
+	// Audience перекрывает StandardClaims.Audience: Keycloak присылает "aud" как строкой, так и массивом
+	Audience Audience `json:"aud,omitempty"`
 	// Keycloak использует snake_case для JSON полей
 	RealmAccess    map[string][]string `json:"realm_access,omitempty"` //nolint:tagliatelle // Keycloak API format
 	ResourceAccess map[string]struct {
 		Roles []string `json:"roles,omitempty"`
 	} `json:"resource_access,omitempty"` //nolint:tagliatelle // Keycloak API format
+	// Scope список OAuth scope через пробел
+	Scope string `json:"scope,omitempty"`
+	// SessionState и SessionID идентификатор сессии Keycloak (старые версии присылают только session_state)
+	SessionState string `json:"session_state,omitempty"` //nolint:tagliatelle // Keycloak API format
+	SessionID    string `json:"sid,omitempty"`
+	// EmailVerified подтвержден ли email пользователя
+	EmailVerified bool `json:"email_verified,omitempty"` //nolint:tagliatelle // Keycloak API format
+	// AuthorizedParty клиент, которому выдан токен
+	AuthorizedParty string `json:"azp,omitempty"`
+	// ClientID клиент service account (есть только в токенах client credentials)
+	ClientID string `json:"client_id,omitempty"` //nolint:tagliatelle // Keycloak API format
+	// PreferredUsername имя пользователя, у service account - service-account-<clientId>
+	PreferredUsername string `json:"preferred_username,omitempty"` //nolint:tagliatelle // Keycloak API format
+}
+
+// Audience список получателей токена.
+type Audience []string
+
+// UnmarshalJSON декодирует "aud" из строки или массива строк.
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// Contains проверяет, что аудитория содержит указанного получателя.
+func (a Audience) Contains(aud string) bool {
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+	return false
 }
 
 // Valid returns errors:
@@ -44,8 +91,13 @@ func (c Claims) Valid() error {
 }
 
 // UserID парсит и возвращает UserID из claims.
-func (c Claims) UserID() types.UserID {
-	return types.MustParse[types.UserID](c.Subject)
+// Ошибка означает, что "sub" не UUID пользователя (например, токен внешнего сервиса).
+func (c Claims) UserID() (types.UserID, error) {
+	userID, err := types.Parse[types.UserID](c.Subject)
+	if err != nil {
+		return types.UserIDNil, fmt.Errorf("parse subject as user ID: %w", err)
+	}
+	return userID, nil
 }
 
 // HasResourceRole проверяет наличие указанной роли для указанного ресурса.
@@ -62,3 +114,31 @@ func (c Claims) HasResourceRole(resource, role string) bool {
 	}
 	return false
 }
+
+// HasRealmRole проверяет наличие указанной роли realm.
+func (c Claims) HasRealmRole(role string) bool {
+	for _, r := range c.RealmAccess["roles"] {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope проверяет, что токен выдан с указанным scope.
+func (c Claims) HasScope(scope string) bool {
+	for _, s := range strings.Fields(c.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Session возвращает идентификатор сессии Keycloak, к которой относится токен.
+func (c Claims) Session() string {
+	if c.SessionID != "" {
+		return c.SessionID
+	}
+	return c.SessionState
+}
@@ -8,9 +8,10 @@ import (
 	context "context"
 	reflect "reflect"
 
-	gomock "github.com/golang/mock/gomock"
-
 	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	middlewares "github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	jwt "github.com/golang-jwt/jwt"
+	gomock "github.com/golang/mock/gomock"
 )
 
 // MockKeycloakClient is a mock of KeycloakClient interface.
@@ -50,3 +51,170 @@ func (mr *MockKeycloakClientMockRecorder) IntrospectToken(ctx, token interface{}
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IntrospectToken", reflect.TypeOf((*MockKeycloakClient)(nil).IntrospectToken), ctx, token)
 }
+
+// MockJWKSClient is a mock of JWKSClient interface.
+type MockJWKSClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockJWKSClientMockRecorder
+}
+
+// MockJWKSClientMockRecorder is the mock recorder for MockJWKSClient.
+type MockJWKSClientMockRecorder struct {
+	mock *MockJWKSClient
+}
+
+// NewMockJWKSClient creates a new mock instance.
+func NewMockJWKSClient(ctrl *gomock.Controller) *MockJWKSClient {
+	mock := &MockJWKSClient{ctrl: ctrl}
+	mock.recorder = &MockJWKSClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockJWKSClient) EXPECT() *MockJWKSClientMockRecorder {
+	return m.recorder
+}
+
+// JWKS mocks base method.
+func (m *MockJWKSClient) JWKS(ctx context.Context, jwksURI string) (*keycloakclient.JSONWebKeySet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JWKS", ctx, jwksURI)
+	ret0, _ := ret[0].(*keycloakclient.JSONWebKeySet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// JWKS indicates an expected call of JWKS.
+func (mr *MockJWKSClientMockRecorder) JWKS(ctx, jwksURI interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JWKS", reflect.TypeOf((*MockJWKSClient)(nil).JWKS), ctx, jwksURI)
+}
+
+// OpenIDConfiguration mocks base method.
+func (m *MockJWKSClient) OpenIDConfiguration(ctx context.Context) (*keycloakclient.OpenIDConfiguration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenIDConfiguration", ctx)
+	ret0, _ := ret[0].(*keycloakclient.OpenIDConfiguration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenIDConfiguration indicates an expected call of OpenIDConfiguration.
+func (mr *MockJWKSClientMockRecorder) OpenIDConfiguration(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenIDConfiguration", reflect.TypeOf((*MockJWKSClient)(nil).OpenIDConfiguration), ctx)
+}
+
+// MockTokenVerifier is a mock of TokenVerifier interface.
+type MockTokenVerifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenVerifierMockRecorder
+}
+
+// MockTokenVerifierMockRecorder is the mock recorder for MockTokenVerifier.
+type MockTokenVerifierMockRecorder struct {
+	mock *MockTokenVerifier
+}
+
+// NewMockTokenVerifier creates a new mock instance.
+func NewMockTokenVerifier(ctrl *gomock.Controller) *MockTokenVerifier {
+	mock := &MockTokenVerifier{ctrl: ctrl}
+	mock.recorder = &MockTokenVerifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenVerifier) EXPECT() *MockTokenVerifierMockRecorder {
+	return m.recorder
+}
+
+// Verify mocks base method.
+func (m *MockTokenVerifier) Verify(ctx context.Context, token string) (*jwt.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Verify", ctx, token)
+	ret0, _ := ret[0].(*jwt.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Verify indicates an expected call of Verify.
+func (mr *MockTokenVerifierMockRecorder) Verify(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockTokenVerifier)(nil).Verify), ctx, token)
+}
+
+// MockTokenRefresher is a mock of TokenRefresher interface.
+type MockTokenRefresher struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenRefresherMockRecorder
+}
+
+// MockTokenRefresherMockRecorder is the mock recorder for MockTokenRefresher.
+type MockTokenRefresherMockRecorder struct {
+	mock *MockTokenRefresher
+}
+
+// NewMockTokenRefresher creates a new mock instance.
+func NewMockTokenRefresher(ctrl *gomock.Controller) *MockTokenRefresher {
+	mock := &MockTokenRefresher{ctrl: ctrl}
+	mock.recorder = &MockTokenRefresherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenRefresher) EXPECT() *MockTokenRefresherMockRecorder {
+	return m.recorder
+}
+
+// RefreshAccessToken mocks base method.
+func (m *MockTokenRefresher) RefreshAccessToken(ctx context.Context, refreshToken string) (*keycloakclient.TokenResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshAccessToken", ctx, refreshToken)
+	ret0, _ := ret[0].(*keycloakclient.TokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshAccessToken indicates an expected call of RefreshAccessToken.
+func (mr *MockTokenRefresherMockRecorder) RefreshAccessToken(ctx, refreshToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshAccessToken", reflect.TypeOf((*MockTokenRefresher)(nil).RefreshAccessToken), ctx, refreshToken)
+}
+
+// MockAPIKeyAuthenticator is a mock of APIKeyAuthenticator interface.
+type MockAPIKeyAuthenticator struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPIKeyAuthenticatorMockRecorder
+}
+
+// MockAPIKeyAuthenticatorMockRecorder is the mock recorder for MockAPIKeyAuthenticator.
+type MockAPIKeyAuthenticatorMockRecorder struct {
+	mock *MockAPIKeyAuthenticator
+}
+
+// NewMockAPIKeyAuthenticator creates a new mock instance.
+func NewMockAPIKeyAuthenticator(ctrl *gomock.Controller) *MockAPIKeyAuthenticator {
+	mock := &MockAPIKeyAuthenticator{ctrl: ctrl}
+	mock.recorder = &MockAPIKeyAuthenticatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAPIKeyAuthenticator) EXPECT() *MockAPIKeyAuthenticatorMockRecorder {
+	return m.recorder
+}
+
+// AuthenticateAPIKey mocks base method.
+func (m *MockAPIKeyAuthenticator) AuthenticateAPIKey(ctx context.Context, key string) (*middlewares.APIKeyIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthenticateAPIKey", ctx, key)
+	ret0, _ := ret[0].(*middlewares.APIKeyIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthenticateAPIKey indicates an expected call of AuthenticateAPIKey.
+func (mr *MockAPIKeyAuthenticatorMockRecorder) AuthenticateAPIKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateAPIKey", reflect.TypeOf((*MockAPIKeyAuthenticator)(nil).AuthenticateAPIKey), ctx, key)
+}
@@ -0,0 +1,117 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ServicePrincipalKey ключ для хранения ServicePrincipal в контексте.
+const ServicePrincipalKey ContextKey = "service_principal"
+
+// ServicePrincipal внутренний сервис, который вызывает API без пользователя
+// с токеном своего service account (client credentials grant).
+type ServicePrincipal struct {
+	// ClientID клиент Keycloak сервиса.
+	ClientID string
+	// Subject идентификатор service account в Keycloak.
+	Subject string
+}
+
+// ServiceAccounts правила, по которым токен считается токеном сервиса, а не пользователя.
+type ServiceAccounts struct {
+	// Clients клиенты Keycloak (azp) внутренних сервисов.
+	Clients []string
+	// Role роль, выданная service account внутренних сервисов (пустое имя - не проверяется).
+	Role Role
+}
+
+// serviceAccountUsernamePrefix префикс имени пользователя service account в Keycloak.
+const serviceAccountUsernamePrefix = "service-account-"
+
+// match возвращает ServicePrincipal, если токен выдан сервису. Токен пользователя,
+// выданный клиенту сервиса, тоже имеет azp этого клиента, поэтому сначала проверяется,
+// что токен получен service account: по claim client_id или имени пользователя.
+func (s ServiceAccounts) match(claims *Claims) (ServicePrincipal, bool) {
+	if claims.ClientID == "" && !strings.HasPrefix(claims.PreferredUsername, serviceAccountUsernamePrefix) {
+		return ServicePrincipal{}, false
+	}
+
+	clientID := claims.ClientID
+	if clientID == "" {
+		clientID = claims.AuthorizedParty
+	}
+
+	switch {
+	case clientID != "" && slices.Contains(s.Clients, clientID):
+	case s.Role.Name != "" && s.Role.grantedTo(claims):
+	default:
+		return ServicePrincipal{}, false
+	}
+	return ServicePrincipal{ClientID: clientID, Subject: claims.Subject}, true
+}
+
+// WithServiceAccounts распознает токены внутренних сервисов: вместо UserID
+// в контекст кладется ServicePrincipal.
+func WithServiceAccounts(accounts ServiceAccounts) AuthMiddlewareOption {
+	return func(a *AuthMiddleware) {
+		a.serviceAccounts = accounts
+	}
+}
+
+// SetServicePrincipal добавляет ServicePrincipal в контекст.
+func SetServicePrincipal(ctx context.Context, principal ServicePrincipal) context.Context {
+	return context.WithValue(ctx, ServicePrincipalKey, principal)
+}
+
+// GetServicePrincipal извлекает ServicePrincipal из контекста.
+func GetServicePrincipal(ctx context.Context) (ServicePrincipal, bool) {
+	principal, ok := ctx.Value(ServicePrincipalKey).(ServicePrincipal)
+	return principal, ok
+}
+
+// RequireUser пропускает только запросы пользователей. Используется после RequireAuth.
+func RequireUser() func(http.Handler) http.Handler {
+	return requirePrincipal("user", func(ctx context.Context) bool {
+		return IsAuthenticated(ctx)
+	})
+}
+
+// RequireService пропускает только запросы внутренних сервисов, а если перечислены
+// clients - только этих клиентов. Используется после RequireAuth.
+func RequireService(clients ...string) func(http.Handler) http.Handler {
+	return requirePrincipal("service", func(ctx context.Context) bool {
+		principal, ok := GetServicePrincipal(ctx)
+		return ok && (len(clients) == 0 || slices.Contains(clients, principal.ClientID))
+	})
+}
+
+// requirePrincipal строит middleware, пропускающую запрос только от принципала нужного вида.
+func requirePrincipal(kind string, allowed func(ctx context.Context) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := GetClaims(r.Context())
+			if !ok {
+				zap.L().Named("authorization").Warn("No claims in context, ensure RequireAuth middleware is used",
+					zap.String("path", r.URL.Path))
+				WriteErrorResponse(w, ErrUnauthorized)
+				return
+			}
+
+			if !allowed(r.Context()) {
+				zap.L().Named("authorization").Info("Access denied",
+					zap.String("subject", claims.Subject),
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+					zap.String("required_principal", kind))
+				WriteErrorResponse(w, ErrForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
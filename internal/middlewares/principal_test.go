@@ -0,0 +1,91 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	"github.com/Fisher-Development/woman-app-backend/internal/testingh/fakekeycloak"
+)
+
+func TestAuthMiddleware_ServicePrincipal(t *testing.T) {
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t)
+
+	user := kc.AddUser(fakekeycloak.User{Email: "jane@example.com", Enabled: true})
+	// Service account с "sub", который не является UUID
+	reports := kc.AddUser(fakekeycloak.User{ID: "service-account-reports", ServiceAccountClientID: "reports", Enabled: true})
+	exporter := kc.AddUser(fakekeycloak.User{
+		ServiceAccountClientID: "exporter",
+		ClientRoles:            map[string][]string{"woman-app-api": {"service"}},
+		Enabled:                true,
+	})
+
+	authMiddleware := middlewares.NewAuthMiddleware(client, middlewares.WithServiceAccounts(middlewares.ServiceAccounts{
+		// Пользовательские токены выдаются клиенту kc.ClientID() и имеют тот же azp
+		Clients: []string{"reports", kc.ClientID()},
+		Role:    middlewares.ParseRole("woman-app-api:service"),
+	}))
+	whoami := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principal, ok := middlewares.GetServicePrincipal(r.Context()); ok {
+			_, _ = w.Write([]byte("service:" + principal.ClientID))
+			return
+		}
+		_, _ = w.Write([]byte("user:" + middlewares.MustGetUserID(r.Context()).String()))
+	})
+
+	serve := func(userID string, mw ...func(http.Handler) http.Handler) *httptest.ResponseRecorder {
+		t.Helper()
+		token, err := kc.IssueAccessToken(userID)
+		require.NoError(t, err)
+
+		var handler http.Handler = whoami
+		for i := len(mw) - 1; i >= 0; i-- {
+			handler = mw[i](handler)
+		}
+		handler = authMiddleware.RequireAuth()(handler)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("principal kinds", func(t *testing.T) {
+		w := serve(user.ID)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "user:"+user.ID, w.Body.String(), "user token of a service client")
+
+		w = serve(reports.ID)
+		require.Equal(t, http.StatusOK, w.Code, "recognised by azp")
+		assert.Equal(t, "service:reports", w.Body.String())
+
+		w = serve(exporter.ID)
+		require.Equal(t, http.StatusOK, w.Code, "recognised by client role")
+		assert.Equal(t, "service:exporter", w.Body.String())
+	})
+
+	t.Run("unknown service with non-uuid subject is rejected", func(t *testing.T) {
+		other := kc.AddUser(fakekeycloak.User{ID: "service-account-other", ServiceAccountClientID: "other", Enabled: true})
+
+		w := serve(other.ID)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("require user", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(user.ID, middlewares.RequireUser()).Code)
+		assert.Equal(t, http.StatusForbidden, serve(reports.ID, middlewares.RequireUser()).Code)
+	})
+
+	t.Run("require service", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve(user.ID, middlewares.RequireService()).Code)
+		assert.Equal(t, http.StatusOK, serve(reports.ID, middlewares.RequireService()).Code)
+		assert.Equal(t, http.StatusOK, serve(reports.ID, middlewares.RequireService("reports")).Code)
+		assert.Equal(t, http.StatusForbidden, serve(exporter.ID, middlewares.RequireService("reports")).Code)
+	})
+}
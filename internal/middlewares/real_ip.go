@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies разбирает адреса доверенных прокси: отдельные IP адреса или подсети в нотации CIDR.
+func ParseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if addr, err := netip.ParseAddr(value); err == nil {
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: expected IP address or CIDR", value)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// RealIP подставляет в RemoteAddr адрес клиента из X-Forwarded-For или X-Real-IP,
+// но только если запрос пришел от доверенного прокси. Иначе заголовки игнорируются
+// и остается адрес сокета: любой клиент может прислать заголовок с чужим адресом.
+//
+// В X-Forwarded-For адресом клиента считается самый правый адрес, не принадлежащий
+// доверенным прокси: левые значения мог подделать сам клиент.
+func RealIP(trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip, ok := forwardedIP(r, trustedProxies); ok {
+				r.RemoteAddr = ip
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func forwardedIP(r *http.Request, trustedProxies []netip.Prefix) (string, bool) {
+	peer, ok := parseIP(r.RemoteAddr)
+	if !ok || !isTrusted(peer, trustedProxies) {
+		return "", false
+	}
+
+	if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		hops := strings.Split(strings.Join(values, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, ok := parseIP(strings.TrimSpace(hops[i]))
+			if !ok {
+				// Цепочка испорчена: дальше доверять ей нельзя
+				return "", false
+			}
+			if !isTrusted(hop, trustedProxies) {
+				return hop.String(), true
+			}
+		}
+		return "", false
+	}
+
+	if ip, ok := parseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ok {
+		return ip.String(), true
+	}
+	return "", false
+}
+
+// parseIP разбирает IP адрес с портом или без.
+func parseIP(value string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func isTrusted(addr netip.Addr, trustedProxies []netip.Prefix) bool {
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted, err := middlewares.ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1"})
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{
+			name:       "untrusted peer cannot spoof X-Forwarded-For",
+			remoteAddr: "203.0.113.7:5000",
+			forwarded:  []string{"198.51.100.1"},
+			want:       "203.0.113.7:5000",
+		},
+		{
+			name:       "untrusted peer cannot spoof X-Real-IP",
+			remoteAddr: "203.0.113.7:5000",
+			realIP:     "198.51.100.1",
+			want:       "203.0.113.7:5000",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.1.2.3:5000",
+			forwarded:  []string{"198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "spoofed hops left of the client are ignored",
+			remoteAddr: "10.1.2.3:5000",
+			forwarded:  []string{"1.1.1.1, 198.51.100.1", "192.0.2.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "X-Real-IP from trusted proxy",
+			remoteAddr: "192.0.2.1:5000",
+			realIP:     "198.51.100.1",
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "malformed chain keeps socket address",
+			remoteAddr: "10.1.2.3:5000",
+			forwarded:  []string{"garbage"},
+			want:       "10.1.2.3:5000",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := middlewares.RealIP(trusted)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err = middlewares.ParseTrustedProxies([]string{"not-an-ip"})
+	require.Error(t, err)
+}
@@ -0,0 +1,144 @@
+package middlewares
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRevocationTTL      = 15 * time.Minute
+	revocationCleanupInterval = time.Minute
+)
+
+// RevocationList короткоживущий список отозванных сессий и токенов.
+// Записи живут не дольше access token, после чего токен отклоняется по сроку действия.
+// Список хранится в памяти и действует в пределах одного экземпляра приложения.
+type RevocationList struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu       sync.RWMutex
+	sessions map[string]time.Time // session_state/sid -> до какого времени хранить
+	tokens   map[string]time.Time // jti -> до какого времени хранить
+	subjects map[string]subjectRevocation
+}
+
+type subjectRevocation struct {
+	issuedBefore time.Time
+	until        time.Time
+}
+
+// NewRevocationList создает список. ttl - максимальное время жизни access token в realm.
+func NewRevocationList(ttl time.Duration) *RevocationList {
+	if ttl <= 0 {
+		ttl = defaultRevocationTTL
+	}
+	return &RevocationList{
+		ttl:      ttl,
+		now:      time.Now,
+		sessions: make(map[string]time.Time),
+		tokens:   make(map[string]time.Time),
+		subjects: make(map[string]subjectRevocation),
+	}
+}
+
+// RevokeSession отзывает все токены сессии Keycloak.
+func (l *RevocationList) RevokeSession(sessionID string) {
+	if sessionID == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sessions[sessionID] = l.now().Add(l.ttl)
+}
+
+// RevokeToken отзывает отдельный токен по jti до истечения его срока действия.
+func (l *RevocationList) RevokeToken(jti string, expiresAt time.Time) {
+	if jti == "" {
+		return
+	}
+	until := l.now().Add(l.ttl)
+	if !expiresAt.IsZero() && expiresAt.Before(until) {
+		until = expiresAt
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tokens[jti] = until
+}
+
+// RevokeSubject отзывает все токены пользователя, выданные до текущего момента.
+func (l *RevocationList) RevokeSubject(subject string) {
+	if subject == "" {
+		return
+	}
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.subjects[subject] = subjectRevocation{issuedBefore: now, until: now.Add(l.ttl)}
+}
+
+// IsRevoked проверяет, отозван ли токен с указанными claims.
+func (l *RevocationList) IsRevoked(claims *Claims) bool {
+	now := l.now()
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if until, ok := l.sessions[claims.Session()]; ok && now.Before(until) {
+		return true
+	}
+	if until, ok := l.tokens[claims.Id]; ok && now.Before(until) {
+		return true
+	}
+	if revocation, ok := l.subjects[claims.Subject]; ok && now.Before(revocation.until) {
+		// iat в секундах: токен, выданный в ту же секунду, что и отзыв, тоже считается отозванным
+		if claims.IssuedAt <= revocation.issuedBefore.Unix() {
+			return true
+		}
+	}
+	return false
+}
+
+// Run периодически удаляет устаревшие записи до отмены контекста.
+func (l *RevocationList) Run(ctx context.Context) error {
+	ticker := time.NewTicker(revocationCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			l.cleanup()
+		}
+	}
+}
+
+func (l *RevocationList) cleanup() {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, until := range l.sessions {
+		if !now.Before(until) {
+			delete(l.sessions, key)
+		}
+	}
+	for key, until := range l.tokens {
+		if !now.Before(until) {
+			delete(l.tokens, key)
+		}
+	}
+	for key, revocation := range l.subjects {
+		if !now.Before(revocation.until) {
+			delete(l.subjects, key)
+		}
+	}
+}
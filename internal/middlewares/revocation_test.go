@@ -0,0 +1,86 @@
+package middlewares
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevocationList(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	newClaims := func(session, jti, subject string, issuedAt time.Time) *Claims {
+		return &Claims{
+			StandardClaims: jwt.StandardClaims{Id: jti, Subject: subject, IssuedAt: issuedAt.Unix()},
+			SessionState:   session,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		revoke  func(l *RevocationList)
+		claims  *Claims
+		elapsed time.Duration
+		want    bool
+	}{
+		{
+			name:   "session revoked",
+			revoke: func(l *RevocationList) { l.RevokeSession("s1") },
+			claims: newClaims("s1", "t1", "u1", now),
+			want:   true,
+		},
+		{
+			name:   "other session is valid",
+			revoke: func(l *RevocationList) { l.RevokeSession("s1") },
+			claims: newClaims("s2", "t1", "u1", now),
+			want:   false,
+		},
+		{
+			name:    "session revocation expires with ttl",
+			revoke:  func(l *RevocationList) { l.RevokeSession("s1") },
+			claims:  newClaims("s1", "t1", "u1", now),
+			elapsed: time.Hour,
+			want:    false,
+		},
+		{
+			name:   "token revoked by jti",
+			revoke: func(l *RevocationList) { l.RevokeToken("t1", now.Add(time.Minute)) },
+			claims: newClaims("", "t1", "u1", now),
+			want:   true,
+		},
+		{
+			name:    "token revocation ends at token expiry",
+			revoke:  func(l *RevocationList) { l.RevokeToken("t1", now.Add(time.Minute)) },
+			claims:  newClaims("", "t1", "u1", now),
+			elapsed: 2 * time.Minute,
+			want:    false,
+		},
+		{
+			name:   "subject tokens issued before revocation",
+			revoke: func(l *RevocationList) { l.RevokeSubject("u1") },
+			claims: newClaims("s1", "t1", "u1", now.Add(-time.Minute)),
+			want:   true,
+		},
+		{
+			name:    "subject tokens issued after revocation",
+			revoke:  func(l *RevocationList) { l.RevokeSubject("u1") },
+			claims:  newClaims("s1", "t1", "u1", now.Add(2*time.Second)),
+			elapsed: 2 * time.Second,
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := now
+			l := NewRevocationList(15 * time.Minute)
+			l.now = func() time.Time { return current }
+
+			tt.revoke(l)
+			current = current.Add(tt.elapsed)
+
+			assert.Equal(t, tt.want, l.IsRevoked(tt.claims))
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package middlewares
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultAccessTokenCookie  = "access_token"
+	defaultRefreshTokenCookie = "refresh_token"
+	defaultCSRFCookie         = "csrf_token"
+	defaultCSRFHeader         = "X-CSRF-Token"
+	defaultRefreshBefore      = time.Minute
+
+	// SessionCookieKeySize размер ключа шифрования cookie (AES-256-GCM).
+	SessionCookieKeySize = 32
+)
+
+// Ошибки cookie сессии.
+var (
+	ErrMissingSessionCookie = errors.New("missing session cookie")
+	ErrInvalidSessionCookie = errors.New("invalid session cookie")
+	ErrCSRFTokenMismatch    = errors.New("csrf token mismatch")
+)
+
+// SessionCookies хранит токены Keycloak в зашифрованных HttpOnly cookie
+// и защищает их от CSRF по схеме double-submit: небезопасные запросы
+// должны повторить значение CSRF cookie в заголовке.
+type SessionCookies struct {
+	aead cipher.AEAD
+
+	accessName    string
+	refreshName   string
+	csrfName      string
+	csrfHeader    string
+	domain        string
+	path          string
+	sameSite      http.SameSite
+	accessMaxAge  time.Duration
+	refreshMaxAge time.Duration
+	refreshBefore time.Duration
+}
+
+// SessionCookiesOption настраивает SessionCookies.
+type SessionCookiesOption func(c *SessionCookies)
+
+// WithCookieNames задает имена cookie access token, refresh token и CSRF токена. Пустые имена не меняются.
+func WithCookieNames(access, refresh, csrf string) SessionCookiesOption {
+	return func(c *SessionCookies) {
+		if access != "" {
+			c.accessName = access
+		}
+		if refresh != "" {
+			c.refreshName = refresh
+		}
+		if csrf != "" {
+			c.csrfName = csrf
+		}
+	}
+}
+
+// WithCSRFHeader задает заголовок, в котором клиент повторяет CSRF токен.
+func WithCSRFHeader(header string) SessionCookiesOption {
+	return func(c *SessionCookies) {
+		if header != "" {
+			c.csrfHeader = header
+		}
+	}
+}
+
+// WithCookieScope задает Domain и Path cookie (по умолчанию host-only cookie на "/").
+func WithCookieScope(domain, path string) SessionCookiesOption {
+	return func(c *SessionCookies) {
+		c.domain = domain
+		if path != "" {
+			c.path = path
+		}
+	}
+}
+
+// WithCookieSameSite задает атрибут SameSite (по умолчанию Lax).
+func WithCookieSameSite(sameSite http.SameSite) SessionCookiesOption {
+	return func(c *SessionCookies) {
+		c.sameSite = sameSite
+	}
+}
+
+// WithCookieLifetimes задает Max-Age cookie. Нулевой access - по сроку действия токена,
+// нулевой refresh - до закрытия браузера.
+func WithCookieLifetimes(access, refresh time.Duration) SessionCookiesOption {
+	return func(c *SessionCookies) {
+		c.accessMaxAge = access
+		c.refreshMaxAge = refresh
+	}
+}
+
+// WithRefreshBefore задает, за сколько до истечения access token middleware обновит его.
+func WithRefreshBefore(d time.Duration) SessionCookiesOption {
+	return func(c *SessionCookies) {
+		if d > 0 {
+			c.refreshBefore = d
+		}
+	}
+}
+
+// NewSessionCookies создает SessionCookies с ключом шифрования размером SessionCookieKeySize.
+func NewSessionCookies(key []byte, opts ...SessionCookiesOption) (*SessionCookies, error) {
+	if len(key) != SessionCookieKeySize {
+		return nil, fmt.Errorf("session cookie key must be %d bytes, got %d", SessionCookieKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %v", err)
+	}
+
+	c := &SessionCookies{
+		aead:          aead,
+		accessName:    defaultAccessTokenCookie,
+		refreshName:   defaultRefreshTokenCookie,
+		csrfName:      defaultCSRFCookie,
+		csrfHeader:    defaultCSRFHeader,
+		path:          "/",
+		sameSite:      http.SameSiteLaxMode,
+		refreshBefore: defaultRefreshBefore,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// CSRFHeader возвращает имя заголовка с CSRF токеном.
+func (c *SessionCookies) CSRFHeader() string { return c.csrfHeader }
+
+// SetTokens устанавливает зашифрованные cookie с токенами. expiresIn - срок действия access token в секундах.
+func (c *SessionCookies) SetTokens(w http.ResponseWriter, accessToken, refreshToken string, expiresIn int) error {
+	access, err := c.seal(c.accessName, accessToken)
+	if err != nil {
+		return err
+	}
+	refresh, err := c.seal(c.refreshName, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	accessMaxAge := c.accessMaxAge
+	if accessMaxAge == 0 {
+		accessMaxAge = time.Duration(expiresIn) * time.Second
+	}
+	http.SetCookie(w, c.cookie(c.accessName, access, accessMaxAge, true))
+	http.SetCookie(w, c.cookie(c.refreshName, refresh, c.refreshMaxAge, true))
+	return nil
+}
+
+// IssueCSRFToken устанавливает новый CSRF токен. Cookie доступна JavaScript,
+// токен также возвращается для клиентов, которые не могут прочитать cookie домена API.
+func (c *SessionCookies) IssueCSRFToken(w http.ResponseWriter) (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generate csrf token: %v", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b[:])
+
+	http.SetCookie(w, c.cookie(c.csrfName, token, c.refreshMaxAge, false))
+	return token, nil
+}
+
+// Clear удаляет cookie сессии.
+func (c *SessionCookies) Clear(w http.ResponseWriter) {
+	for _, name := range []string{c.accessName, c.refreshName, c.csrfName} {
+		cookie := c.cookie(name, "", 0, name != c.csrfName)
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+	}
+}
+
+// HasSession сообщает, что запрос пришел с cookie сессии.
+func (c *SessionCookies) HasSession(r *http.Request) bool {
+	_, err := r.Cookie(c.refreshName)
+	return err == nil
+}
+
+// AccessToken возвращает access token из cookie.
+func (c *SessionCookies) AccessToken(r *http.Request) (string, error) {
+	return c.read(r, c.accessName)
+}
+
+// RefreshToken возвращает refresh token из cookie.
+func (c *SessionCookies) RefreshToken(r *http.Request) (string, error) {
+	return c.read(r, c.refreshName)
+}
+
+// CheckCSRF сверяет CSRF токен из заголовка со значением cookie.
+func (c *SessionCookies) CheckCSRF(r *http.Request) error {
+	cookie, err := r.Cookie(c.csrfName)
+	if err != nil || cookie.Value == "" {
+		return ErrCSRFTokenMismatch
+	}
+	header := r.Header.Get(c.csrfHeader)
+	if subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) != 1 {
+		return ErrCSRFTokenMismatch
+	}
+	return nil
+}
+
+func (c *SessionCookies) cookie(name, value string, maxAge time.Duration, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Domain:   c.domain,
+		Path:     c.path,
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   true,
+		HttpOnly: httpOnly,
+		SameSite: c.sameSite,
+	}
+}
+
+func (c *SessionCookies) read(r *http.Request, name string) (string, error) {
+	cookie, err := r.Cookie(name)
+	if err != nil || cookie.Value == "" {
+		return "", ErrMissingSessionCookie
+	}
+	return c.open(name, cookie.Value)
+}
+
+// seal шифрует значение. Имя cookie входит в additional data, поэтому значения cookie нельзя поменять местами.
+func (c *SessionCookies) seal(name, value string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %v", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (c *SessionCookies) open(name, value string) (string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", ErrInvalidSessionCookie
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", ErrInvalidSessionCookie
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,187 @@
+package middlewares_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	"github.com/Fisher-Development/woman-app-backend/internal/testingh/fakekeycloak"
+)
+
+var testCookieKey = []byte("0123456789abcdef0123456789abcdef")
+
+func newTestSessionCookies(t *testing.T, opts ...middlewares.SessionCookiesOption) *middlewares.SessionCookies {
+	t.Helper()
+
+	cookies, err := middlewares.NewSessionCookies(testCookieKey, opts...)
+	require.NoError(t, err)
+	return cookies
+}
+
+// withCookies переносит cookie из ответа в новый запрос, как это делает браузер.
+func withCookies(req *http.Request, resp *httptest.ResponseRecorder) *http.Request {
+	for _, cookie := range resp.Result().Cookies() {
+		if cookie.MaxAge >= 0 {
+			req.AddCookie(cookie)
+		}
+	}
+	return req
+}
+
+func TestSessionCookies(t *testing.T) {
+	_, err := middlewares.NewSessionCookies([]byte("short"))
+	require.Error(t, err)
+
+	cookies := newTestSessionCookies(t,
+		middlewares.WithCookieNames("at", "rt", "csrf"),
+		middlewares.WithCookieScope("example.com", "/api"),
+		middlewares.WithCookieLifetimes(0, time.Hour),
+	)
+
+	w := httptest.NewRecorder()
+	require.NoError(t, cookies.SetTokens(w, "access", "refresh", 300))
+	csrfToken, err := cookies.IssueCSRFToken(w)
+	require.NoError(t, err)
+
+	byName := make(map[string]*http.Cookie)
+	for _, cookie := range w.Result().Cookies() {
+		byName[cookie.Name] = cookie
+	}
+	require.Len(t, byName, 3)
+	assert.NotContains(t, byName["at"].Value, "access", "value is encrypted")
+	assert.True(t, byName["at"].HttpOnly)
+	assert.True(t, byName["at"].Secure)
+	assert.Equal(t, 300, byName["at"].MaxAge)
+	assert.Equal(t, 3600, byName["rt"].MaxAge)
+	assert.Equal(t, "example.com", byName["rt"].Domain)
+	assert.Equal(t, "/api", byName["rt"].Path)
+	assert.False(t, byName["csrf"].HttpOnly, "csrf cookie is readable by JavaScript")
+
+	req := withCookies(httptest.NewRequest(http.MethodGet, "/", nil), w)
+	accessToken, err := cookies.AccessToken(req)
+	require.NoError(t, err)
+	assert.Equal(t, "access", accessToken)
+	refreshToken, err := cookies.RefreshToken(req)
+	require.NoError(t, err)
+	assert.Equal(t, "refresh", refreshToken)
+
+	t.Run("values are bound to cookie names", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: "at", Value: byName["rt"].Value})
+
+		_, err := cookies.AccessToken(req)
+		require.ErrorIs(t, err, middlewares.ErrInvalidSessionCookie)
+	})
+
+	t.Run("tampered value", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: "at", Value: byName["at"].Value[:len(byName["at"].Value)-2] + "AA"})
+
+		_, err := cookies.AccessToken(req)
+		require.ErrorIs(t, err, middlewares.ErrInvalidSessionCookie)
+	})
+
+	t.Run("csrf", func(t *testing.T) {
+		require.ErrorIs(t, cookies.CheckCSRF(req), middlewares.ErrCSRFTokenMismatch)
+
+		req.Header.Set(cookies.CSRFHeader(), "other")
+		require.ErrorIs(t, cookies.CheckCSRF(req), middlewares.ErrCSRFTokenMismatch)
+
+		req.Header.Set(cookies.CSRFHeader(), csrfToken)
+		require.NoError(t, cookies.CheckCSRF(req))
+	})
+}
+
+func TestAuthMiddleware_SessionCookies(t *testing.T) {
+	ctx := context.Background()
+	// Токены живут меньше окна обновления, поэтому каждый запрос обновляет сессию
+	kc := fakekeycloak.New(t, fakekeycloak.WithTokenLifetime(30*time.Second))
+	client := kc.NewClient(t)
+	user := kc.AddUser(fakekeycloak.User{Email: "jane@example.com", Password: "password123", Enabled: true})
+
+	cookies := newTestSessionCookies(t, middlewares.WithRefreshBefore(time.Minute))
+	authMiddleware := middlewares.NewAuthMiddleware(client, middlewares.WithSessionCookies(cookies, client))
+	handler := authMiddleware.RequireAuth()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.GetUserID(r.Context())
+		_, _ = w.Write([]byte(userID.String()))
+	}))
+
+	tokens, err := client.LoginUser(ctx, user.Email, user.Password)
+	require.NoError(t, err)
+	session := httptest.NewRecorder()
+	require.NoError(t, cookies.SetTokens(session, tokens.AccessToken, tokens.RefreshToken, tokens.ExpiresIn))
+	csrfToken, err := cookies.IssueCSRFToken(session)
+	require.NoError(t, err)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, withCookies(req, session))
+		return w
+	}
+
+	t.Run("unsafe method requires csrf token", func(t *testing.T) {
+		w := serve(httptest.NewRequest(http.MethodPost, "/", nil))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("concurrent requests share one refresh", func(t *testing.T) {
+		refreshes := kc.Requests(fakekeycloak.EndpointToken)
+
+		var wg sync.WaitGroup
+		results := make([]*httptest.ResponseRecorder, 5)
+		for i := range results {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req := httptest.NewRequest(http.MethodPost, "/", nil)
+				req.Header.Set(cookies.CSRFHeader(), csrfToken)
+				results[i] = serve(req)
+			}()
+		}
+		wg.Wait()
+
+		for _, w := range results {
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, user.ID, w.Body.String())
+		}
+		assert.Equal(t, 1, kc.Requests(fakekeycloak.EndpointToken)-refreshes)
+	})
+
+	t.Run("refreshed cookies are set", func(t *testing.T) {
+		w := serve(httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		req := withCookies(httptest.NewRequest(http.MethodGet, "/", nil), w)
+		refreshed, err := cookies.AccessToken(req)
+		require.NoError(t, err)
+		assert.NotEqual(t, tokens.AccessToken, refreshed)
+	})
+
+	t.Run("header takes precedence over cookies", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Authorization", "Bearer garbage")
+
+		w := serve(req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("invalid session is cleared", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		bad := httptest.NewRecorder()
+		require.NoError(t, cookies.SetTokens(bad, "garbage", "garbage", 30))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, withCookies(req, bad))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		for _, cookie := range w.Result().Cookies() {
+			assert.Equal(t, -1, cookie.MaxAge, cookie.Name)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+)
+
+// refreshResultTTL сколько помнить результат обновления: параллельные запросы браузера
+// со старым refresh token получат те же новые токены, а не ошибку одноразового refresh token.
+const refreshResultTTL = 10 * time.Second
+
+// cookieToken возвращает access token из cookie сессии, при необходимости обновляя его.
+// Для небезопасных методов сначала проверяется CSRF токен.
+func (a *AuthMiddleware) cookieToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if !isSafeMethod(r.Method) {
+		if err := a.cookies.CheckCSRF(r); err != nil {
+			return "", err
+		}
+	}
+
+	accessToken, accessErr := a.cookies.AccessToken(r)
+	if accessErr == nil && !a.expiresSoon(accessToken) {
+		return accessToken, nil
+	}
+	if a.refresher == nil {
+		return accessToken, accessErr
+	}
+
+	refreshToken, err := a.cookies.RefreshToken(r)
+	if err != nil {
+		return accessToken, accessErr
+	}
+
+	tokens, err := a.refreshes.do(r.Context(), refreshToken, a.refresher.RefreshAccessToken)
+	if err != nil {
+		// Пока access token действителен, сбой Keycloak не должен разлогинивать пользователя
+		if accessErr == nil && !a.expired(accessToken) {
+			a.logger.Warn("Session refresh failed, using current access token", zap.Error(err))
+			return accessToken, nil
+		}
+		if !errors.Is(err, keycloakclient.ErrUnavailable) {
+			a.cookies.Clear(w)
+		}
+		return "", fmt.Errorf("refresh session: %w", err)
+	}
+
+	if err := a.cookies.SetTokens(w, tokens.AccessToken, tokens.RefreshToken, tokens.ExpiresIn); err != nil {
+		return "", err
+	}
+	a.logger.Debug("Session refreshed", zap.String("remote_addr", r.RemoteAddr))
+	return tokens.AccessToken, nil
+}
+
+// expiresSoon сообщает, что access token истечет в пределах окна обновления.
+// Подпись не проверяется: это делает authenticate, здесь нужен только срок.
+func (a *AuthMiddleware) expiresSoon(token string) bool {
+	return a.expiresWithin(token, a.cookies.refreshBefore)
+}
+
+func (a *AuthMiddleware) expired(token string) bool {
+	return a.expiresWithin(token, 0)
+}
+
+func (a *AuthMiddleware) expiresWithin(token string, d time.Duration) bool {
+	var claims jwt.StandardClaims
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, &claims); err != nil {
+		return true
+	}
+	return !time.Now().Add(d).Before(time.Unix(claims.ExpiresAt, 0))
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// refreshGroup объединяет одновременные обновления одного refresh token.
+type refreshGroup struct {
+	mu    sync.Mutex
+	calls map[[sha256.Size]byte]*refreshCall
+}
+
+type refreshCall struct {
+	done   chan struct{}
+	tokens *keycloakclient.TokenResponse
+	err    error
+	until  time.Time
+}
+
+func newRefreshGroup() *refreshGroup {
+	return &refreshGroup{calls: make(map[[sha256.Size]byte]*refreshCall)}
+}
+
+type refreshFunc func(ctx context.Context, refreshToken string) (*keycloakclient.TokenResponse, error)
+
+// do обновляет токены или дожидается уже идущего обновления того же refresh token.
+func (g *refreshGroup) do(ctx context.Context, refreshToken string, refresh refreshFunc) (*keycloakclient.TokenResponse, error) {
+	key := sha256.Sum256([]byte(refreshToken))
+	now := time.Now()
+
+	g.mu.Lock()
+	for k, call := range g.calls {
+		if !call.until.IsZero() && now.After(call.until) {
+			delete(g.calls, k)
+		}
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.tokens, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	call := &refreshCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	// Обновление не привязано к отмене запроса: его результат нужен и ожидающим запросам
+	call.tokens, call.err = refresh(context.WithoutCancel(ctx), refreshToken)
+
+	g.mu.Lock()
+	if call.err != nil {
+		delete(g.calls, key)
+	} else {
+		call.until = time.Now().Add(refreshResultTTL)
+	}
+	g.mu.Unlock()
+	close(call.done)
+
+	return call.tokens, call.err
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DateLayout формат календарной даты в API.
+const DateLayout = "2006-01-02"
+
+// Date календарная дата без времени и часового пояса (полночь UTC).
+// В JSON передается строкой "2006-01-02".
+type Date struct {
+	time.Time
+}
+
+// NewDate возвращает дату по году, месяцу и дню.
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// DateOf возвращает дату момента t в UTC.
+func DateOf(t time.Time) Date {
+	t = t.UTC()
+	return NewDate(t.Year(), t.Month(), t.Day())
+}
+
+// ParseDate разбирает дату в формате "2006-01-02".
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", s)
+	}
+	return Date{t}, nil
+}
+
+// AddDays возвращает дату через n дней (n может быть отрицательным).
+func (d Date) AddDays(n int) Date {
+	return Date{d.Time.AddDate(0, 0, n)}
+}
+
+// DaysSince возвращает число дней от other до d.
+func (d Date) DaysSince(other Date) int {
+	return int(d.Time.Sub(other.Time).Hours() / 24)
+}
+
+// String возвращает дату в формате "2006-01-02".
+func (d Date) String() string {
+	return d.Time.Format(DateLayout)
+}
+
+// MarshalJSON реализует json.Marshaler.
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON реализует json.Unmarshaler.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("date must be a string: %w", err)
+	}
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
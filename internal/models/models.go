@@ -15,4 +15,81 @@ type User struct {
 	Country   string    `json:"country"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
+	// KeycloakMissingSince время, с которого пользователя нет в Keycloak (заполняется сверкой).
+	KeycloakMissingSince *time.Time `json:"-"`
+	// EmailVerifiedAt время подтверждения email (заполняется при логине).
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
+}
+
+// Действия журнала сверки с Keycloak.
+const (
+	// ReconciliationActionDeleteUser пользователь Keycloak должен быть удален.
+	ReconciliationActionDeleteUser = "delete_user"
+)
+
+// ReconciliationRecord запись журнала сверки с Keycloak.
+type ReconciliationRecord struct {
+	ID             string    `json:"id"`
+	KeycloakUserID string    `json:"keycloakUserId"`
+	Email          string    `json:"email"`
+	Action         string    `json:"action"`
+	Reason         string    `json:"reason"`
+	Attempts       int       `json:"attempts"`
+	LastError      string    `json:"lastError"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// Действия журнала аудита.
+const (
+	// AuditActionLoginLockout вход временно заблокирован после серии неудачных попыток.
+	AuditActionLoginLockout = "login_lockout"
+	// AuditActionLoginUnlock блокировка входа снята администратором.
+	AuditActionLoginUnlock = "login_unlock"
+)
+
+// AuditEvent запись журнала аудита.
+type AuditEvent struct {
+	ID     string `json:"id"`
+	Action string `json:"action"`
+	// Subject кого касается событие: email или IP адрес.
+	Subject string `json:"subject"`
+	// Actor кто вызвал событие (пусто - система).
+	Actor     string    `json:"actor"`
+	Details   string    `json:"details"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// APIKey персональный API ключ пользователя. Сам ключ не хранится.
+type APIKey struct {
+	ID       string `json:"id"`
+	UserUUID string `json:"-"`
+	Name     string `json:"name"`
+	// Prefix открытая часть ключа, по которой он ищется и узнается в списке.
+	Prefix string `json:"prefix"`
+	// KeyHash sha256 секретной части ключа.
+	KeyHash string `json:"-"`
+	// Scopes роли realm, которые получает запрос с этим ключом.
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// Cycle менструальный цикл пользователя. Цикл начинается с первого дня менструации
+// и заканчивается днем перед началом следующего; у текущего цикла EndDate пуст.
+type Cycle struct {
+	ID       string `json:"id"`
+	UserUUID string `json:"-"`
+	// StartDate первый день менструации.
+	StartDate Date  `json:"startDate"`
+	EndDate   *Date `json:"endDate"`
+	// PeriodEndDate последний день менструации.
+	PeriodEndDate *Date  `json:"periodEndDate"`
+	Notes         string `json:"notes"`
+	// IsPredicted цикл рассчитан прогнозом, а не отмечен пользователем.
+	IsPredicted bool      `json:"isPredicted"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
 }
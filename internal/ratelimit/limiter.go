@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const cleanupInterval = time.Minute
+
+// Limiter ограничивает число попыток по ключу в скользящем окне.
+// Состояние хранится в памяти и действует в пределах одного экземпляра приложения.
+type Limiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu       sync.Mutex
+	attempts map[string][]time.Time
+}
+
+// New создает Limiter, разрешающий не более limit попыток за window.
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:    limit,
+		window:   window,
+		now:      time.Now,
+		attempts: make(map[string][]time.Time),
+	}
+}
+
+// Allow учитывает попытку по ключу. Если лимит исчерпан, попытка не учитывается
+// и возвращается время, через которое можно повторить.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	attempts := l.prune(key, now)
+	if len(attempts) >= l.limit {
+		return false, attempts[0].Add(l.window).Sub(now)
+	}
+
+	l.attempts[key] = append(attempts, now)
+	return true, 0
+}
+
+// Reset сбрасывает попытки по ключу.
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.attempts, key)
+}
+
+// Run периодически удаляет устаревшие попытки до отмены контекста.
+func (l *Limiter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			l.cleanup()
+		}
+	}
+}
+
+func (l *Limiter) cleanup() {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key := range l.attempts {
+		l.prune(key, now)
+	}
+}
+
+// prune удаляет попытки за пределами окна. Вызывается под mu.
+func (l *Limiter) prune(key string, now time.Time) []time.Time {
+	attempts := l.attempts[key]
+
+	i := 0
+	for i < len(attempts) && !now.Before(attempts[i].Add(l.window)) {
+		i++
+	}
+	attempts = attempts[i:]
+
+	if len(attempts) == 0 {
+		delete(l.attempts, key)
+		return nil
+	}
+	l.attempts[key] = attempts
+	return attempts
+}
@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	current := time.Unix(1_700_000_000, 0)
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return current }
+
+	ok, _ := l.Allow("a")
+	assert.True(t, ok)
+
+	current = current.Add(10 * time.Second)
+	ok, _ = l.Allow("a")
+	assert.True(t, ok)
+
+	ok, retryAfter := l.Allow("a")
+	assert.False(t, ok)
+	assert.Equal(t, 50*time.Second, retryAfter)
+
+	// Другой ключ считается отдельно
+	ok, _ = l.Allow("b")
+	assert.True(t, ok)
+
+	// Первая попытка вышла за окно
+	current = current.Add(50 * time.Second)
+	ok, _ = l.Allow("a")
+	assert.True(t, ok)
+
+	l.Reset("a")
+	assert.NotContains(t, l.attempts, "a")
+
+	current = current.Add(time.Hour)
+	l.cleanup()
+	assert.Empty(t, l.attempts)
+}
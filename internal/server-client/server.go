@@ -0,0 +1,71 @@
+package serverclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Server struct {
+	logger *zap.Logger
+	server *http.Server
+}
+
+type Options struct {
+	addr    string
+	handler http.Handler
+}
+
+func NewOptions(addr string, handler http.Handler) Options {
+	return Options{
+		addr:    addr,
+		handler: handler,
+	}
+}
+
+func New(opts Options) (*Server, error) {
+	if opts.handler == nil {
+		return nil, errors.New("handler is required")
+	}
+
+	server := &http.Server{
+		Addr:              opts.addr,
+		Handler:           opts.handler,
+		ReadHeaderTimeout: 10 * time.Second, // Защита от Slowloris
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      15 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	return &Server{
+		logger: zap.L().Named("client-server"),
+		server: server,
+	}, nil
+}
+
+func (s *Server) Run(ctx context.Context) error {
+	s.logger.Info("Starting client server", zap.String("addr", s.server.Addr))
+
+	errChan := make(chan error, 1)
+
+	go func() {
+		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errChan <- fmt.Errorf("client server listen: %w", err)
+		}
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		s.logger.Info("Shutting down client server")
+		// Родительский контекст уже отменён, поэтому даём серверу отдельный таймаут на завершение
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return s.server.Shutdown(shutdownCtx)
+	}
+}
@@ -2,6 +2,7 @@ package serverdebug
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"net/http"
 	"net/http/pprof"
@@ -35,6 +36,9 @@ func New(opts Options) (*Server, error) {
 	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
 	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
 
+	// Метрики приложения (счетчики кэшей, пулов и т.п.), опубликованные через expvar
+	mux.Handle("/debug/vars", expvar.Handler())
+
 	// Health check endpoint
 	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -74,7 +78,8 @@ func (s *Server) Run(ctx context.Context) error {
 		return err
 	case <-ctx.Done():
 		s.logger.Info("Shutting down debug server")
-		shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		// Родительский контекст уже отменён, поэтому даём серверу отдельный таймаут на завершение
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 		return s.server.Shutdown(shutdownCtx)
 	}
@@ -0,0 +1,392 @@
+package apikeyservice
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/Fisher-Development/woman-app-backend/api/apikey"
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	"github.com/Fisher-Development/woman-app-backend/internal/store"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+const (
+	// keyPrefix отличает API ключи от других секретов, например при поиске утечек в репозиториях.
+	keyPrefix = "wak_"
+
+	defaultMaxKeys = 20
+	// defaultRolesCacheTTL как долго снятая с пользователя роль еще действует в его ключах.
+	defaultRolesCacheTTL = time.Minute
+	// touchInterval как часто обновлять время последнего использования ключа.
+	touchInterval = time.Minute
+)
+
+// Storage методы хранилища, которые использует сервис API ключей.
+type Storage interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	ListAPIKeys(ctx context.Context, userUUID string) ([]models.APIKey, error)
+	UpdateAPIKeyScopes(ctx context.Context, userUUID, id string, scopes []string) (*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userUUID, id string, at time.Time) error
+	TouchAPIKey(ctx context.Context, id string, at time.Time) error
+	GetUserByUUID(ctx context.Context, uuid string) (*models.User, error)
+	WithTx(ctx context.Context, opts store.TxOptions, fn func(ctx context.Context) error) error
+}
+
+// RoleSource источник текущих ролей realm пользователя, обычно Admin API Keycloak.
+type RoleSource interface {
+	GetEffectiveRealmRoles(ctx context.Context, userID types.UserID) ([]keycloakclient.RoleRepresentation, error)
+}
+
+// APIKeyService выдает персональные API ключи и проверяет их для middleware авторизации.
+// Ключ имеет вид wak_<prefix>_<secret>: prefix хранится открыто для поиска,
+// от secret хранится только sha256.
+//
+// Роли ключа ограничены ролями, которые были у пользователя при выдаче. При проверке ключа
+// они пересекаются с текущими ролями пользователя: снятая в Keycloak роль перестает
+// действовать и в ключах, не дольше чем через rolesTTL.
+type APIKeyService struct {
+	storage  Storage
+	roles    RoleSource
+	maxKeys  int
+	rolesTTL time.Duration
+	now      func() time.Time
+
+	mu         sync.Mutex
+	ownerRoles map[types.UserID]ownerRoles
+}
+
+type ownerRoles struct {
+	roles     []string
+	expiresAt time.Time
+}
+
+// APIKeyServiceOption опция для настройки APIKeyService.
+type APIKeyServiceOption func(*APIKeyService)
+
+// WithMaxKeys задает максимальное число действующих ключей пользователя.
+func WithMaxKeys(n int) APIKeyServiceOption {
+	return func(s *APIKeyService) {
+		if n > 0 {
+			s.maxKeys = n
+		}
+	}
+}
+
+// WithRolesCacheTTL задает, как долго кэшируются текущие роли владельцев ключей.
+func WithRolesCacheTTL(ttl time.Duration) APIKeyServiceOption {
+	return func(s *APIKeyService) {
+		if ttl > 0 {
+			s.rolesTTL = ttl
+		}
+	}
+}
+
+// NewAPIKeyService создает новый APIKeyService.
+func NewAPIKeyService(storage Storage, roles RoleSource, opts ...APIKeyServiceOption) *APIKeyService {
+	s := &APIKeyService{
+		storage:    storage,
+		roles:      roles,
+		maxKeys:    defaultMaxKeys,
+		rolesTTL:   defaultRolesCacheTTL,
+		now:        time.Now,
+		ownerRoles: make(map[types.UserID]ownerRoles),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CreateAPIKey создает ключ. Секрет возвращается только в ответе и больше нигде не хранится.
+func (s *APIKeyService) CreateAPIKey(
+	ctx context.Context,
+	userID types.UserID,
+	req apikey.CreateRequest,
+) (*apikey.CreateResponse, error) {
+	logger := zap.L().Named("apikey-service")
+
+	if err := checkScopes(ctx, req.Scopes); err != nil {
+		return nil, err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.now()) {
+		return nil, apikey.ErrInvalidExpiration
+	}
+
+	prefix, secret, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+	key := &models.APIKey{
+		UserUUID:  userID.String(),
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hashSecret(secret),
+		Scopes:    normalizeScopes(req.Scopes),
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	// Проверка лимита и создание в serializable транзакции: параллельные запросы не превысят лимит
+	err = s.storage.WithTx(ctx, store.TxOptions{Isolation: store.Serializable}, func(ctx context.Context) error {
+		keys, err := s.storage.ListAPIKeys(ctx, userID.String())
+		if err != nil {
+			return fmt.Errorf("list api keys: %w", err)
+		}
+		if len(keys) >= s.maxKeys {
+			return apikey.ErrTooManyAPIKeys
+		}
+		return s.storage.CreateAPIKey(ctx, key)
+	})
+	if errors.Is(err, apikey.ErrTooManyAPIKeys) {
+		return nil, err
+	}
+	if err != nil {
+		logger.Error("Failed to create api key", zap.String("user_id", userID.String()), zap.Error(err))
+		return nil, fmt.Errorf("create api key: %w", err)
+	}
+
+	logger.Info("API key created",
+		zap.String("user_id", userID.String()),
+		zap.String("api_key_id", key.ID),
+		zap.Strings("scopes", key.Scopes))
+
+	return &apikey.CreateResponse{APIKey: *key, Key: keyPrefix + prefix + "_" + secret}, nil
+}
+
+// ListAPIKeys возвращает действующие ключи пользователя.
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, userID types.UserID) ([]models.APIKey, error) {
+	keys, err := s.storage.ListAPIKeys(ctx, userID.String())
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// UpdateAPIKeyScopes меняет роли ключа. Выдать можно только роли, которые есть у пользователя.
+func (s *APIKeyService) UpdateAPIKeyScopes(
+	ctx context.Context,
+	userID types.UserID,
+	keyID string,
+	req apikey.ScopesRequest,
+) (*models.APIKey, error) {
+	if err := checkScopes(ctx, req.Scopes); err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(keyID); err != nil {
+		return nil, apikey.ErrAPIKeyNotFound
+	}
+
+	key, err := s.storage.UpdateAPIKeyScopes(ctx, userID.String(), keyID, normalizeScopes(req.Scopes))
+	if errors.Is(err, store.ErrAPIKeyNotFound) {
+		return nil, apikey.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update api key scopes: %w", err)
+	}
+
+	zap.L().Named("apikey-service").Info("API key scopes updated",
+		zap.String("user_id", userID.String()),
+		zap.String("api_key_id", keyID),
+		zap.Strings("scopes", key.Scopes))
+	return key, nil
+}
+
+// RevokeAPIKey отзывает ключ. Отозванный ключ перестает приниматься сразу.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, userID types.UserID, keyID string) error {
+	if _, err := uuid.Parse(keyID); err != nil {
+		return apikey.ErrAPIKeyNotFound
+	}
+
+	err := s.storage.RevokeAPIKey(ctx, userID.String(), keyID, s.now())
+	if errors.Is(err, store.ErrAPIKeyNotFound) {
+		return apikey.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+
+	zap.L().Named("apikey-service").Info("API key revoked",
+		zap.String("user_id", userID.String()),
+		zap.String("api_key_id", keyID))
+	return nil
+}
+
+// AuthenticateAPIKey реализует middlewares.APIKeyAuthenticator.
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, raw string) (*middlewares.APIKeyIdentity, error) {
+	prefix, secret, ok := parseKey(raw)
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed key", middlewares.ErrInvalidAPIKey)
+	}
+
+	key, err := s.storage.GetAPIKeyByPrefix(ctx, prefix)
+	if errors.Is(err, store.ErrAPIKeyNotFound) {
+		return nil, fmt.Errorf("%w: unknown prefix", middlewares.ErrInvalidAPIKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get api key: %w", err)
+	}
+
+	now := s.now()
+	switch {
+	case subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashSecret(secret))) != 1:
+		return nil, fmt.Errorf("%w: secret mismatch", middlewares.ErrInvalidAPIKey)
+	case key.RevokedAt != nil:
+		return nil, fmt.Errorf("%w: revoked", middlewares.ErrInvalidAPIKey)
+	case key.ExpiresAt != nil && !now.Before(*key.ExpiresAt):
+		return nil, fmt.Errorf("%w: expired", middlewares.ErrInvalidAPIKey)
+	}
+
+	userID, err := types.Parse[types.UserID](key.UserUUID)
+	if err != nil {
+		return nil, fmt.Errorf("parse api key owner: %w", err)
+	}
+	user, err := s.storage.GetUserByUUID(ctx, key.UserUUID)
+	if errors.Is(err, store.ErrUserNotFound) {
+		return nil, fmt.Errorf("%w: owner not found", middlewares.ErrInvalidAPIKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get api key owner: %w", err)
+	}
+
+	current, err := s.currentRoles(ctx, userID)
+	if errors.Is(err, keycloakclient.ErrUserNotFound) {
+		return nil, fmt.Errorf("%w: owner not found", middlewares.ErrInvalidAPIKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get api key owner roles: %w", err)
+	}
+	roles := make([]string, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		if slices.Contains(current, scope) {
+			roles = append(roles, scope)
+		}
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= touchInterval {
+		if err := s.storage.TouchAPIKey(ctx, key.ID, now); err != nil {
+			zap.L().Named("apikey-service").Warn("Failed to record api key usage",
+				zap.String("api_key_id", key.ID),
+				zap.Error(err))
+		}
+	}
+
+	return &middlewares.APIKeyIdentity{
+		KeyID:         key.ID,
+		UserID:        userID,
+		Roles:         roles,
+		EmailVerified: user.EmailVerifiedAt != nil,
+	}, nil
+}
+
+// currentRoles возвращает текущие роли realm пользователя из кэша или из RoleSource.
+func (s *APIKeyService) currentRoles(ctx context.Context, userID types.UserID) ([]string, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	cached, ok := s.ownerRoles[userID]
+	s.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.roles, nil
+	}
+
+	representations, err := s.roles.GetEffectiveRealmRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	roles := make([]string, 0, len(representations))
+	for _, role := range representations {
+		roles = append(roles, role.Name)
+	}
+
+	s.mu.Lock()
+	s.ownerRoles[userID] = ownerRoles{roles: roles, expiresAt: now.Add(s.rolesTTL)}
+	s.mu.Unlock()
+
+	return roles, nil
+}
+
+// Run периодически удаляет устаревшие роли владельцев из кэша до отмены контекста.
+func (s *APIKeyService) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.rolesTTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			now := s.now()
+			s.mu.Lock()
+			for id, entry := range s.ownerRoles {
+				if !now.Before(entry.expiresAt) {
+					delete(s.ownerRoles, id)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// checkScopes проверяет, что пользователь текущего запроса обладает всеми выдаваемыми ролями.
+func checkScopes(ctx context.Context, scopes []string) error {
+	roles, _ := middlewares.GetUserRoles(ctx)
+	for _, scope := range scopes {
+		if !slices.Contains(roles, scope) {
+			return fmt.Errorf("%w: %s", apikey.ErrScopeNotAllowed, scope)
+		}
+	}
+	return nil
+}
+
+func normalizeScopes(scopes []string) []string {
+	normalized := slices.Clone(scopes)
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+	if normalized == nil {
+		normalized = []string{}
+	}
+	return normalized
+}
+
+func generateKey() (prefix, secret string, err error) {
+	var p [6]byte
+	var sec [32]byte
+	if _, err := rand.Read(p[:]); err != nil {
+		return "", "", fmt.Errorf("generate api key: %v", err)
+	}
+	if _, err := rand.Read(sec[:]); err != nil {
+		return "", "", fmt.Errorf("generate api key: %v", err)
+	}
+	return hex.EncodeToString(p[:]), base64.RawURLEncoding.EncodeToString(sec[:]), nil
+}
+
+// parseKey разбирает ключ wak_<prefix>_<secret>. prefix в hex, поэтому первый "_" после него - разделитель.
+func parseKey(raw string) (prefix, secret string, ok bool) {
+	rest, ok := strings.CutPrefix(raw, keyPrefix)
+	if !ok {
+		return "", "", false
+	}
+	prefix, secret, ok = strings.Cut(rest, "_")
+	return prefix, secret, ok && prefix != "" && secret != ""
+}
+
+// hashSecret хэширует секрет ключа. Секрет случайный и длинный, поэтому медленный хэш не нужен.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
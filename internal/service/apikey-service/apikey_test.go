@@ -0,0 +1,291 @@
+package apikeyservice_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fisher-Development/woman-app-backend/api/apikey"
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	apikeyservice "github.com/Fisher-Development/woman-app-backend/internal/service/apikey-service"
+	"github.com/Fisher-Development/woman-app-backend/internal/store"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+// fakeStorage хранилище API ключей в памяти.
+type fakeStorage struct {
+	mu    sync.Mutex
+	keys  map[string]*models.APIKey
+	users map[string]*models.User
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{keys: make(map[string]*models.APIKey), users: make(map[string]*models.User)}
+}
+
+func (f *fakeStorage) CreateAPIKey(_ context.Context, key *models.APIKey) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key.ID = uuid.NewString()
+	key.CreatedAt = time.Now()
+	stored := *key
+	f.keys[key.ID] = &stored
+	return nil
+}
+
+func (f *fakeStorage) GetAPIKeyByPrefix(_ context.Context, prefix string) (*models.APIKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, key := range f.keys {
+		if key.Prefix == prefix {
+			found := *key
+			return &found, nil
+		}
+	}
+	return nil, store.ErrAPIKeyNotFound
+}
+
+func (f *fakeStorage) ListAPIKeys(_ context.Context, userUUID string) ([]models.APIKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var keys []models.APIKey
+	for _, key := range f.keys {
+		if key.UserUUID == userUUID && key.RevokedAt == nil {
+			keys = append(keys, *key)
+		}
+	}
+	return keys, nil
+}
+
+func (f *fakeStorage) UpdateAPIKeyScopes(_ context.Context, userUUID, id string, scopes []string) (*models.APIKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key, ok := f.keys[id]
+	if !ok || key.UserUUID != userUUID || key.RevokedAt != nil {
+		return nil, store.ErrAPIKeyNotFound
+	}
+	key.Scopes = scopes
+	updated := *key
+	return &updated, nil
+}
+
+func (f *fakeStorage) RevokeAPIKey(_ context.Context, userUUID, id string, at time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key, ok := f.keys[id]
+	if !ok || key.UserUUID != userUUID || key.RevokedAt != nil {
+		return store.ErrAPIKeyNotFound
+	}
+	key.RevokedAt = &at
+	return nil
+}
+
+func (f *fakeStorage) TouchAPIKey(_ context.Context, id string, at time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if key, ok := f.keys[id]; ok {
+		key.LastUsedAt = &at
+	}
+	return nil
+}
+
+func (f *fakeStorage) GetUserByUUID(_ context.Context, uuid string) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	user, ok := f.users[uuid]
+	if !ok {
+		return nil, store.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (f *fakeStorage) WithTx(ctx context.Context, _ store.TxOptions, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// fakeRoles текущие роли realm пользователей в Keycloak.
+type fakeRoles struct {
+	mu    sync.Mutex
+	roles map[types.UserID][]string
+	calls int
+}
+
+func (f *fakeRoles) GetEffectiveRealmRoles(
+	_ context.Context,
+	userID types.UserID,
+) ([]keycloakclient.RoleRepresentation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	names, ok := f.roles[userID]
+	if !ok {
+		return nil, keycloakclient.ErrUserNotFound
+	}
+	roles := make([]keycloakclient.RoleRepresentation, 0, len(names))
+	for _, name := range names {
+		roles = append(roles, keycloakclient.RoleRepresentation{Name: name})
+	}
+	return roles, nil
+}
+
+func (f *fakeRoles) set(userID types.UserID, roles ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.roles[userID] = roles
+}
+
+func TestAPIKeyService(t *testing.T) {
+	storage := newFakeStorage()
+	roles := &fakeRoles{roles: make(map[types.UserID][]string)}
+	svc := apikeyservice.NewAPIKeyService(storage, roles, apikeyservice.WithMaxKeys(2))
+
+	userID := types.MustParse[types.UserID]("5cb40dc0-a249-4783-a301-9e1f3cf3ea41")
+	verifiedAt := time.Now()
+	storage.users[userID.String()] = &models.User{UUID: userID.String(), EmailVerifiedAt: &verifiedAt}
+	roles.set(userID, "offline_access", "qa")
+	// Контекст запроса пользователя с ролями realm, как после RequireAuth
+	ctx := middlewares.SetUserRoles(context.Background(), []string{"offline_access", "qa"})
+
+	created, err := svc.CreateAPIKey(ctx, userID, apikey.CreateRequest{Name: "ci", Scopes: []string{"qa", "qa"}})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(created.Key, "wak_"+created.APIKey.Prefix+"_"))
+	assert.Equal(t, []string{"qa"}, created.APIKey.Scopes)
+	assert.NotContains(t, storage.keys[created.APIKey.ID].KeyHash, created.Key[len(created.Key)-10:], "secret is not stored")
+
+	identity, err := svc.AuthenticateAPIKey(ctx, created.Key)
+	require.NoError(t, err)
+	assert.Equal(t, userID, identity.UserID)
+	assert.Equal(t, []string{"qa"}, identity.Roles)
+	assert.True(t, identity.EmailVerified)
+	assert.NotNil(t, storage.keys[created.APIKey.ID].LastUsedAt)
+
+	t.Run("invalid keys", func(t *testing.T) {
+		for _, key := range []string{"", "garbage", "wak_", created.Key + "x", "wak_" + created.APIKey.Prefix + "_other"} {
+			_, err := svc.AuthenticateAPIKey(ctx, key)
+			require.ErrorIs(t, err, middlewares.ErrInvalidAPIKey, key)
+		}
+	})
+
+	t.Run("scopes must be granted to the user", func(t *testing.T) {
+		_, err := svc.CreateAPIKey(ctx, userID, apikey.CreateRequest{Name: "admin", Scopes: []string{"admin"}})
+		require.ErrorIs(t, err, apikey.ErrScopeNotAllowed)
+
+		_, err = svc.UpdateAPIKeyScopes(ctx, userID, created.APIKey.ID, apikey.ScopesRequest{Scopes: []string{"admin"}})
+		require.ErrorIs(t, err, apikey.ErrScopeNotAllowed)
+
+		key, err := svc.UpdateAPIKeyScopes(ctx, userID, created.APIKey.ID, apikey.ScopesRequest{Scopes: nil})
+		require.NoError(t, err)
+		assert.Empty(t, key.Scopes)
+	})
+
+	t.Run("expiration", func(t *testing.T) {
+		past := time.Now().Add(-time.Minute)
+		_, err := svc.CreateAPIKey(ctx, userID, apikey.CreateRequest{Name: "old", ExpiresAt: &past})
+		require.ErrorIs(t, err, apikey.ErrInvalidExpiration)
+
+		future := time.Now().Add(time.Hour)
+		expiring, err := svc.CreateAPIKey(ctx, userID, apikey.CreateRequest{Name: "expiring", ExpiresAt: &future})
+		require.NoError(t, err)
+
+		storage.keys[expiring.APIKey.ID].ExpiresAt = &past
+		_, err = svc.AuthenticateAPIKey(ctx, expiring.Key)
+		require.ErrorIs(t, err, middlewares.ErrInvalidAPIKey)
+
+		_, err = svc.CreateAPIKey(ctx, userID, apikey.CreateRequest{Name: "third"})
+		require.ErrorIs(t, err, apikey.ErrTooManyAPIKeys)
+
+		require.NoError(t, svc.RevokeAPIKey(ctx, userID, expiring.APIKey.ID))
+	})
+
+	t.Run("keys of other users are not visible", func(t *testing.T) {
+		otherID := types.MustParse[types.UserID]("f47ac10b-58cc-4372-a567-0e02b2c3d479")
+
+		keys, err := svc.ListAPIKeys(ctx, otherID)
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+
+		err = svc.RevokeAPIKey(ctx, otherID, created.APIKey.ID)
+		require.ErrorIs(t, err, apikey.ErrAPIKeyNotFound)
+
+		err = svc.RevokeAPIKey(ctx, userID, "not-a-uuid")
+		require.ErrorIs(t, err, apikey.ErrAPIKeyNotFound)
+	})
+
+	t.Run("key of deleted user is rejected", func(t *testing.T) {
+		ownerID := types.MustParse[types.UserID]("0b6f4a3e-5d2c-4f1a-9e8b-7c6d5e4f3a2b")
+		storage.users[ownerID.String()] = &models.User{UUID: ownerID.String()}
+		roles.set(ownerID, "offline_access")
+		orphan, err := svc.CreateAPIKey(ctx, ownerID, apikey.CreateRequest{Name: "orphan"})
+		require.NoError(t, err)
+
+		delete(storage.users, ownerID.String())
+		_, err = svc.AuthenticateAPIKey(ctx, orphan.Key)
+		require.ErrorIs(t, err, middlewares.ErrInvalidAPIKey)
+	})
+
+	t.Run("revoked key is rejected", func(t *testing.T) {
+		require.NoError(t, svc.RevokeAPIKey(ctx, userID, created.APIKey.ID))
+
+		_, err := svc.AuthenticateAPIKey(ctx, created.Key)
+		require.ErrorIs(t, err, middlewares.ErrInvalidAPIKey)
+
+		keys, err := svc.ListAPIKeys(ctx, userID)
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+	})
+}
+
+func TestAPIKeyService_RolesFollowKeycloak(t *testing.T) {
+	storage := newFakeStorage()
+	roles := &fakeRoles{roles: make(map[types.UserID][]string)}
+
+	userID := types.MustParse[types.UserID]("5cb40dc0-a249-4783-a301-9e1f3cf3ea41")
+	storage.users[userID.String()] = &models.User{UUID: userID.String()}
+	roles.set(userID, "offline_access", "qa", "support")
+	ctx := middlewares.SetUserRoles(context.Background(), []string{"offline_access", "qa", "support"})
+
+	t.Run("current roles are cached", func(t *testing.T) {
+		svc := apikeyservice.NewAPIKeyService(storage, roles)
+		created, err := svc.CreateAPIKey(ctx, userID, apikey.CreateRequest{Name: "ci", Scopes: []string{"qa", "support"}})
+		require.NoError(t, err)
+
+		for range 3 {
+			identity, err := svc.AuthenticateAPIKey(ctx, created.Key)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"qa", "support"}, identity.Roles)
+		}
+		assert.Equal(t, 1, roles.calls)
+	})
+
+	t.Run("revoked role is dropped from the key", func(t *testing.T) {
+		svc := apikeyservice.NewAPIKeyService(storage, roles, apikeyservice.WithRolesCacheTTL(time.Nanosecond))
+		created, err := svc.CreateAPIKey(ctx, userID, apikey.CreateRequest{Name: "ci", Scopes: []string{"qa", "support"}})
+		require.NoError(t, err)
+
+		roles.set(userID, "offline_access", "qa")
+		identity, err := svc.AuthenticateAPIKey(ctx, created.Key)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"qa"}, identity.Roles)
+
+		// Пользователь удален в Keycloak
+		delete(roles.roles, userID)
+		_, err = svc.AuthenticateAPIKey(ctx, created.Key)
+		require.ErrorIs(t, err, middlewares.ErrInvalidAPIKey)
+	})
+}
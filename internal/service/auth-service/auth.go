@@ -4,32 +4,79 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Fisher-Development/woman-app-backend/api/auth"
 	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
 	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	"github.com/Fisher-Development/woman-app-backend/internal/ratelimit"
 	"github.com/Fisher-Development/woman-app-backend/internal/store"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+	"github.com/golang-jwt/jwt"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 )
 
 // AuthService реализует IAuthService интерфейс.
 type AuthService struct {
-	storage             *store.Storage
-	keycloakClient      *keycloakclient.Client // для аутентификации
-	keycloakAdminClient *keycloakclient.Client // для создания пользователей
+	storage             UserStorage
+	keycloakClient      KeycloakAuthClient     // для аутентификации
+	keycloakAdminClient KeycloakAdminClient    // для создания пользователей
+	revoker             SessionRevoker         // для мгновенного отзыва токенов при logout (опционально)
+	attemptLimiter      *ratelimit.Limiter     // лимит попыток операций с паролем и отправки писем
+	codeFlow            *authorizationCodeFlow // вход через authorization code flow (опционально)
+	authorizationStates *authorizationStates
+	loginGuard          LoginGuard // защита входа по паролю от перебора (опционально)
+}
+
+// AuthServiceOption опция для настройки AuthService.
+type AuthServiceOption func(*AuthService)
+
+// WithPasswordAttempts задает лимит попыток сброса и смены пароля
+// (и повторной отправки письма подтверждения) за окно времени.
+func WithPasswordAttempts(limit int, window time.Duration) AuthServiceOption {
+	return func(s *AuthService) {
+		if limit <= 0 {
+			limit = defaultPasswordAttempts
+		}
+		if window <= 0 {
+			window = defaultPasswordAttemptsWindow
+		}
+		s.attemptLimiter = ratelimit.New(limit, window)
+	}
 }
 
 // NewAuthService создает новый AuthService.
 func NewAuthService(
-	storage *store.Storage,
-	keycloakClient *keycloakclient.Client,
-	keycloakAdminClient *keycloakclient.Client,
+	storage UserStorage,
+	keycloakClient KeycloakAuthClient,
+	keycloakAdminClient KeycloakAdminClient,
+	revoker SessionRevoker,
+	opts ...AuthServiceOption,
 ) *AuthService {
-	return &AuthService{
+	s := &AuthService{
 		storage:             storage,
 		keycloakClient:      keycloakClient,
 		keycloakAdminClient: keycloakAdminClient,
+		revoker:             revoker,
+		attemptLimiter:      ratelimit.New(defaultPasswordAttempts, defaultPasswordAttemptsWindow),
+		authorizationStates: newAuthorizationStates(defaultAuthorizationStateTTL),
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Run выполняет фоновое обслуживание сервиса до отмены контекста.
+func (s *AuthService) Run(ctx context.Context) error {
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.Go(func() error { return s.attemptLimiter.Run(ctx) })
+	eg.Go(func() error { return s.authorizationStates.Run(ctx) })
+	return eg.Wait()
 }
 
 // RegisterUser регистрирует нового пользователя.
@@ -67,7 +114,7 @@ func (s *AuthService) RegisterUser(ctx context.Context, req auth.RegisterRequest
 	keycloakUser, err := s.keycloakAdminClient.CreateUser(ctx, createReq)
 	if err != nil {
 		logger.Error("Failed to create user in Keycloak", zap.Error(err))
-		return fmt.Errorf("failed to create user in Keycloak: %v", err)
+		return fmt.Errorf("failed to create user in Keycloak: %w", mapKeycloakError(err))
 	}
 
 	logger.Info("User created in Keycloak",
@@ -83,13 +130,21 @@ func (s *AuthService) RegisterUser(ctx context.Context, req auth.RegisterRequest
 
 	if err := s.storage.CreateUser(ctx, user); err != nil {
 		logger.Error("Failed to create user in database", zap.Error(err))
-		// СДЕЛАТЬ В идеале здесь нужно откатить создание в Keycloak
-		return fmt.Errorf("failed to create user in database: %v", err)
+		// Откатываем создание пользователя в Keycloak, иначе email больше не получится зарегистрировать
+		if !s.compensateKeycloakUser(ctx, keycloakUser.UserID, req.Email, err) {
+			if errors.Is(err, store.ErrEmailAlreadyExists) {
+				return fmt.Errorf("%w: %v", auth.ErrUserAlreadyExists, err)
+			}
+			return fmt.Errorf("failed to create user in database: %w", err)
+		}
 	}
 
 	logger.Info("User registration completed successfully",
 		zap.String("user_id", keycloakUser.UserID.String()))
 
+	// Ошибка отправки письма не отменяет регистрацию: письмо можно запросить повторно
+	s.sendVerificationEmail(ctx, keycloakUser.UserID)
+
 	return nil
 }
 
@@ -97,18 +152,31 @@ func (s *AuthService) RegisterUser(ctx context.Context, req auth.RegisterRequest
 func (s *AuthService) LoginUser(ctx context.Context, req auth.LoginRequest) (*auth.LoginResponse, error) {
 	logger := zap.L().Named("auth-service")
 
-	logger.Info("User login attempt", zap.String("email", req.Email))
+	attempt, err := s.checkLogin(ctx, req)
+	if err != nil {
+		return nil, err
+	}
 
 	// Аутентифицируем через Keycloak
 	tokenResp, err := s.keycloakClient.LoginUser(ctx, req.Email, req.Password)
 	if err != nil {
-		logger.Warn("Login failed",
-			zap.String("email", req.Email),
-			zap.Error(err))
-		return nil, fmt.Errorf("authentication failed: %v", err)
+		logger.Debug("Login failed", zap.String("email", req.Email), zap.Error(err))
+		// Прочие отказы password grant (например, незавершенная настройка учетной записи)
+		// не раскрываем клиенту и считаем неверными учетными данными
+		if errors.Is(err, keycloakclient.ErrInvalidCredentials) || errors.Is(err, keycloakclient.ErrInvalidGrant) {
+			logger.Info("Login failed: invalid credentials", zap.String("ip", req.ClientIP))
+			s.recordLoginFailure(ctx, attempt)
+			return nil, fmt.Errorf("authentication failed: %w: %v", auth.ErrInvalidCredentials, err)
+		}
+		logger.Warn("Login failed", zap.String("ip", req.ClientIP), zap.Error(err))
+		s.releaseLogin(ctx, attempt)
+		return nil, fmt.Errorf("authentication failed: %w", mapKeycloakError(err))
 	}
 
-	logger.Info("User logged in successfully", zap.String("email", req.Email))
+	s.recordLoginSuccess(ctx, attempt)
+	logger.Debug("User logged in successfully", zap.String("email", req.Email))
+
+	s.recordEmailVerification(ctx, tokenResp.AccessToken)
 
 	return &auth.LoginResponse{
 		AccessToken:  tokenResp.AccessToken,
@@ -126,7 +194,7 @@ func (s *AuthService) RefreshAccessToken(ctx context.Context, refreshToken strin
 	tokenResp, err := s.keycloakClient.RefreshAccessToken(ctx, refreshToken)
 	if err != nil {
 		logger.Warn("Token refresh failed", zap.Error(err))
-		return nil, fmt.Errorf("token refresh failed: %v", err)
+		return nil, fmt.Errorf("token refresh failed: %w", mapTokenError(err))
 	}
 
 	logger.Info("Access token refreshed successfully")
@@ -137,3 +205,56 @@ func (s *AuthService) RefreshAccessToken(ctx context.Context, refreshToken strin
 		ExpiresIn:    tokenResp.ExpiresIn,
 	}, nil
 }
+
+// Logout завершает сессию refresh token: отзывает его в Keycloak, а сессию
+// в локальном списке отзыва. Сессия берется из refresh token, а не из access token
+// запроса: они могут принадлежать разным сессиям.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	logger := zap.L().Named("auth-service")
+
+	if err := s.keycloakClient.Logout(ctx, refreshToken); err != nil {
+		logger.Warn("Logout failed", zap.Error(err))
+		return fmt.Errorf("logout failed: %w", mapTokenError(err))
+	}
+
+	if s.revoker != nil {
+		if session := refreshTokenSession(refreshToken); session != "" {
+			s.revoker.RevokeSession(session)
+		} else if claims, ok := middlewares.GetClaims(ctx); ok {
+			// Без сессии в refresh token можно отозвать только access token текущего запроса
+			s.revoker.RevokeToken(claims.Id, time.Unix(claims.ExpiresAt, 0))
+		}
+	}
+
+	logger.Info("User logged out")
+	return nil
+}
+
+// refreshTokenSession возвращает сессию Keycloak из refresh token.
+// Keycloak только что принял токен, поэтому подпись не проверяется.
+func refreshTokenSession(refreshToken string) string {
+	var claims middlewares.Claims
+	if _, _, err := new(jwt.Parser).ParseUnverified(refreshToken, &claims); err != nil {
+		return ""
+	}
+	return claims.Session()
+}
+
+// LogoutAll завершает все сессии пользователя через Admin API.
+func (s *AuthService) LogoutAll(ctx context.Context, userID types.UserID) error {
+	logger := zap.L().Named("auth-service")
+
+	if err := s.keycloakAdminClient.LogoutUser(ctx, userID); err != nil {
+		logger.Error("Logout all sessions failed",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+		return fmt.Errorf("logout all sessions failed: %w", mapKeycloakError(err))
+	}
+
+	if s.revoker != nil {
+		s.revoker.RevokeSubject(userID.String())
+	}
+
+	logger.Info("All user sessions logged out", zap.String("user_id", userID.String()))
+	return nil
+}
@@ -0,0 +1,550 @@
+package authservice_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fisher-Development/woman-app-backend/api/auth"
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	authservice "github.com/Fisher-Development/woman-app-backend/internal/service/auth-service"
+	"github.com/Fisher-Development/woman-app-backend/internal/store"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+var errUnavailable = errors.New("service unavailable")
+
+// fakeKeycloak хранит пользователей в памяти и позволяет внедрять ошибки удаления.
+type fakeKeycloak struct {
+	mu             sync.Mutex
+	users          map[types.UserID]keycloakclient.CreateUserRequest
+	deleteFailures int // сколько первых вызовов DeleteUser завершатся ошибкой
+	deleteCalls    int
+	loggedOut      []string       // refresh токены, переданные в Logout
+	loggedOutUsers []types.UserID // пользователи, переданные в LogoutUser
+	logoutErr      error
+	passwords      map[types.UserID]keycloakclient.UserCredentialRepresentation
+	actionEmails   map[types.UserID][]string // действия, отправленные через ExecuteActionsEmail
+}
+
+func newFakeKeycloak() *fakeKeycloak {
+	return &fakeKeycloak{
+		users:        make(map[types.UserID]keycloakclient.CreateUserRequest),
+		passwords:    make(map[types.UserID]keycloakclient.UserCredentialRepresentation),
+		actionEmails: make(map[types.UserID][]string),
+	}
+}
+
+func (f *fakeKeycloak) CreateUser(
+	_ context.Context,
+	req keycloakclient.CreateUserRequest,
+) (*keycloakclient.CreateUserResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	userID := types.NewUserID()
+	f.users[userID] = req
+	for _, credential := range req.Credentials {
+		f.passwords[userID] = credential
+	}
+	return &keycloakclient.CreateUserResponse{
+		UserID:    userID,
+		Email:     req.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+	}, nil
+}
+
+func (f *fakeKeycloak) DeleteUser(_ context.Context, userID types.UserID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.deleteCalls++
+	if f.deleteCalls <= f.deleteFailures {
+		return errUnavailable
+	}
+	if _, ok := f.users[userID]; !ok {
+		return keycloakclient.ErrUserNotFound
+	}
+	delete(f.users, userID)
+	return nil
+}
+
+func (f *fakeKeycloak) LoginUser(_ context.Context, email, password string) (*keycloakclient.TokenResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for userID, user := range f.users {
+		if user.Email == email && f.passwords[userID].Value == password {
+			// Подпись не важна: сервис читает из токена только claims
+			accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &middlewares.Claims{
+				StandardClaims: jwt.StandardClaims{Subject: userID.String()},
+				EmailVerified:  user.EmailVerified,
+			}).SignedString([]byte("secret"))
+			if err != nil {
+				return nil, err
+			}
+			return &keycloakclient.TokenResponse{AccessToken: accessToken, RefreshToken: "refresh", ExpiresIn: 300}, nil
+		}
+	}
+	return nil, errors.New("invalid_grant")
+}
+
+func (f *fakeKeycloak) RefreshAccessToken(context.Context, string) (*keycloakclient.TokenResponse, error) {
+	return &keycloakclient.TokenResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 300}, nil
+}
+
+func (f *fakeKeycloak) Logout(_ context.Context, refreshToken string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.logoutErr != nil {
+		return f.logoutErr
+	}
+	f.loggedOut = append(f.loggedOut, refreshToken)
+	return nil
+}
+
+func (f *fakeKeycloak) LogoutUser(_ context.Context, userID types.UserID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.logoutErr != nil {
+		return f.logoutErr
+	}
+	f.loggedOutUsers = append(f.loggedOutUsers, userID)
+	return nil
+}
+
+func (f *fakeKeycloak) GetUser(_ context.Context, userID types.UserID) (*keycloakclient.UserRepresentation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	user, ok := f.users[userID]
+	if !ok {
+		return nil, keycloakclient.ErrUserNotFound
+	}
+	return &keycloakclient.UserRepresentation{
+		ID:            userID.String(),
+		Email:         user.Email,
+		Enabled:       user.Enabled,
+		EmailVerified: user.EmailVerified,
+	}, nil
+}
+
+func (f *fakeKeycloak) FindUsersByEmail(_ context.Context, email string) ([]keycloakclient.UserRepresentation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var users []keycloakclient.UserRepresentation
+	for userID, user := range f.users {
+		if user.Email == email {
+			users = append(users, keycloakclient.UserRepresentation{
+				ID:      userID.String(),
+				Email:   user.Email,
+				Enabled: user.Enabled,
+			})
+		}
+	}
+	return users, nil
+}
+
+func (f *fakeKeycloak) ResetPassword(_ context.Context, userID types.UserID, password string, temporary bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.users[userID]; !ok {
+		return keycloakclient.ErrUserNotFound
+	}
+	f.passwords[userID] = keycloakclient.UserCredentialRepresentation{
+		Type:      "password",
+		Value:     password,
+		Temporary: temporary,
+	}
+	return nil
+}
+
+func (f *fakeKeycloak) ExecuteActionsEmail(_ context.Context, userID types.UserID, actions []string, _ time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.users[userID]; !ok {
+		return keycloakclient.ErrUserNotFound
+	}
+	f.actionEmails[userID] = append(f.actionEmails[userID], actions...)
+	return nil
+}
+
+// fakeStorage хранит пользователей и журнал сверки в памяти.
+type fakeStorage struct {
+	mu            sync.Mutex
+	users         map[string]*models.User
+	records       map[string]*models.ReconciliationRecord
+	createUserErr error
+	// createUserStored строка сохраняется, несмотря на createUserErr (коммит с потерянным ответом)
+	createUserStored bool
+	getUserErr       error
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{
+		users:   make(map[string]*models.User),
+		records: make(map[string]*models.ReconciliationRecord),
+	}
+}
+
+func (f *fakeStorage) CreateUser(_ context.Context, user *models.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.createUserErr != nil && !f.createUserStored {
+		return f.createUserErr
+	}
+	f.users[user.UUID] = user
+	return f.createUserErr
+}
+
+func (f *fakeStorage) GetUserByUUID(_ context.Context, uuid string) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.getUserErr != nil {
+		return nil, f.getUserErr
+	}
+	user, ok := f.users[uuid]
+	if !ok {
+		return nil, store.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (f *fakeStorage) MarkEmailVerified(_ context.Context, uuid string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	user, ok := f.users[uuid]
+	if !ok {
+		return store.ErrUserNotFound
+	}
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	return nil
+}
+
+func (f *fakeStorage) CreateReconciliationRecord(_ context.Context, record *models.ReconciliationRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	record.ID = types.NewRequestID().String()
+	f.records[record.ID] = record
+	return nil
+}
+
+func (f *fakeStorage) ListReconciliationRecords(
+	_ context.Context,
+	action string,
+	_ int,
+) ([]models.ReconciliationRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var records []models.ReconciliationRecord
+	for _, record := range f.records {
+		if record.Action == action {
+			records = append(records, *record)
+		}
+	}
+	return records, nil
+}
+
+func (f *fakeStorage) RecordReconciliationAttempt(_ context.Context, id, lastError string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records[id].Attempts++
+	f.records[id].LastError = lastError
+	return nil
+}
+
+func (f *fakeStorage) DeleteReconciliationRecord(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.records, id)
+	return nil
+}
+
+var registerRequest = auth.RegisterRequest{
+	Email:     "jane@example.com",
+	Password:  "password123",
+	FirstName: "Jane",
+}
+
+func TestRegisterUser_Success(t *testing.T) {
+	kc, storage := newFakeKeycloak(), newFakeStorage()
+	svc := authservice.NewAuthService(storage, kc, kc, nil)
+
+	require.NoError(t, svc.RegisterUser(context.Background(), registerRequest))
+
+	assert.Len(t, kc.users, 1)
+	assert.Len(t, storage.users, 1)
+	assert.Empty(t, storage.records)
+	for userID := range kc.users {
+		assert.Equal(t, []string{keycloakclient.RequiredActionVerifyEmail}, kc.actionEmails[userID])
+	}
+}
+
+func TestRegisterUser_DatabaseFailureRollsBackKeycloakUser(t *testing.T) {
+	kc, storage := newFakeKeycloak(), newFakeStorage()
+	storage.createUserErr = store.ErrEmailAlreadyExists
+	// Первая попытка удаления падает, вторая проходит
+	kc.deleteFailures = 1
+	svc := authservice.NewAuthService(storage, kc, kc, nil)
+
+	err := svc.RegisterUser(context.Background(), registerRequest)
+	require.ErrorIs(t, err, auth.ErrUserAlreadyExists)
+
+	assert.Empty(t, kc.users, "Keycloak user must be compensated")
+	assert.Equal(t, 2, kc.deleteCalls)
+	assert.Empty(t, storage.records)
+}
+
+func TestRegisterUser_UnresolvedCompensationIsRecorded(t *testing.T) {
+	kc, storage := newFakeKeycloak(), newFakeStorage()
+	storage.createUserErr = errors.New("connection reset")
+	kc.deleteFailures = 100
+	svc := authservice.NewAuthService(storage, kc, kc, nil)
+
+	err := svc.RegisterUser(context.Background(), registerRequest)
+	require.Error(t, err)
+
+	assert.Len(t, kc.users, 1)
+	assert.Equal(t, 3, kc.deleteCalls)
+	require.Len(t, storage.records, 1)
+	for userID := range kc.users {
+		for _, record := range storage.records {
+			assert.Equal(t, userID.String(), record.KeycloakUserID)
+			assert.Equal(t, models.ReconciliationActionDeleteUser, record.Action)
+			assert.Equal(t, registerRequest.Email, record.Email)
+			assert.Equal(t, "connection reset", record.Reason)
+		}
+	}
+}
+
+func TestRegisterUser_ExistingLocalUserIsKept(t *testing.T) {
+	for name, storage := range map[string]*fakeStorage{
+		// Строку уже вставил reconciler
+		"duplicate key": {createUserErr: store.ErrUserAlreadyExists, createUserStored: true},
+		// Коммит прошел, но ответ потерян
+		"unknown commit result": {createUserErr: errors.New("connection reset"), createUserStored: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			storage.users = make(map[string]*models.User)
+			storage.records = make(map[string]*models.ReconciliationRecord)
+			kc := newFakeKeycloak()
+			svc := authservice.NewAuthService(storage, kc, kc, nil)
+
+			require.NoError(t, svc.RegisterUser(context.Background(), registerRequest))
+
+			assert.Len(t, kc.users, 1, "live Keycloak user must not be deleted")
+			assert.Zero(t, kc.deleteCalls)
+			assert.Empty(t, storage.records)
+		})
+	}
+}
+
+func TestRegisterUser_DuplicateKeyWithoutLocalUserRollsBack(t *testing.T) {
+	kc, storage := newFakeKeycloak(), newFakeStorage()
+	// Ошибка говорит о конфликте UUID, но строки в БД нет
+	storage.createUserErr = store.ErrUserAlreadyExists
+	svc := authservice.NewAuthService(storage, kc, kc, nil)
+
+	require.Error(t, svc.RegisterUser(context.Background(), registerRequest))
+
+	assert.Empty(t, kc.users, "Keycloak user without local row must be compensated")
+	assert.Equal(t, 1, kc.deleteCalls)
+	assert.Empty(t, storage.records)
+}
+
+func TestRegisterUser_UnknownLocalStateIsReconciledLater(t *testing.T) {
+	kc, storage := newFakeKeycloak(), newFakeStorage()
+	storage.createUserErr = errors.New("connection reset")
+	storage.getUserErr = errors.New("connection reset")
+	svc := authservice.NewAuthService(storage, kc, kc, nil)
+
+	require.Error(t, svc.RegisterUser(context.Background(), registerRequest))
+
+	// Удаление откладывается до OrphanCleaner, который сначала проверит БД
+	assert.Len(t, kc.users, 1)
+	assert.Zero(t, kc.deleteCalls)
+	assert.Len(t, storage.records, 1)
+}
+
+func TestOrphanCleaner_CleanUp(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("orphan is deleted", func(t *testing.T) {
+		kc, storage := newFakeKeycloak(), newFakeStorage()
+		created, err := kc.CreateUser(ctx, keycloakclient.CreateUserRequest{Email: "orphan@example.com"})
+		require.NoError(t, err)
+		require.NoError(t, storage.CreateReconciliationRecord(ctx, &models.ReconciliationRecord{
+			KeycloakUserID: created.UserID.String(),
+			Action:         models.ReconciliationActionDeleteUser,
+		}))
+
+		resolved, err := authservice.NewOrphanCleaner(storage, kc, 0).CleanUp(ctx)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, resolved)
+		assert.Empty(t, kc.users)
+		assert.Empty(t, storage.records)
+	})
+
+	t.Run("user already gone from keycloak", func(t *testing.T) {
+		kc, storage := newFakeKeycloak(), newFakeStorage()
+		require.NoError(t, storage.CreateReconciliationRecord(ctx, &models.ReconciliationRecord{
+			KeycloakUserID: types.NewUserID().String(),
+			Action:         models.ReconciliationActionDeleteUser,
+		}))
+
+		resolved, err := authservice.NewOrphanCleaner(storage, kc, 0).CleanUp(ctx)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, resolved)
+		assert.Empty(t, storage.records)
+	})
+
+	t.Run("user exists locally is kept", func(t *testing.T) {
+		kc, storage := newFakeKeycloak(), newFakeStorage()
+		created, err := kc.CreateUser(ctx, keycloakclient.CreateUserRequest{Email: "jane@example.com"})
+		require.NoError(t, err)
+		require.NoError(t, storage.CreateUser(ctx, &models.User{UUID: created.UserID.String()}))
+		require.NoError(t, storage.CreateReconciliationRecord(ctx, &models.ReconciliationRecord{
+			KeycloakUserID: created.UserID.String(),
+			Action:         models.ReconciliationActionDeleteUser,
+		}))
+
+		resolved, err := authservice.NewOrphanCleaner(storage, kc, 0).CleanUp(ctx)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, resolved)
+		assert.Len(t, kc.users, 1)
+		assert.Empty(t, storage.records)
+	})
+
+	t.Run("failed deletion is retried later", func(t *testing.T) {
+		kc, storage := newFakeKeycloak(), newFakeStorage()
+		kc.deleteFailures = 1
+		created, err := kc.CreateUser(ctx, keycloakclient.CreateUserRequest{Email: "orphan@example.com"})
+		require.NoError(t, err)
+		record := &models.ReconciliationRecord{
+			KeycloakUserID: created.UserID.String(),
+			Action:         models.ReconciliationActionDeleteUser,
+		}
+		require.NoError(t, storage.CreateReconciliationRecord(ctx, record))
+
+		cleaner := authservice.NewOrphanCleaner(storage, kc, 0)
+
+		resolved, err := cleaner.CleanUp(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, resolved)
+		assert.Equal(t, 1, storage.records[record.ID].Attempts)
+		assert.Equal(t, errUnavailable.Error(), storage.records[record.ID].LastError)
+
+		resolved, err = cleaner.CleanUp(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, resolved)
+		assert.Empty(t, kc.users)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	userID := types.NewUserID()
+	claims := &middlewares.Claims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   userID.String(),
+			Id:        "token-id",
+			IssuedAt:  time.Now().Add(-time.Minute).Unix(),
+			ExpiresAt: time.Now().Add(time.Minute).Unix(),
+		},
+		SessionState: "session-1",
+	}
+	ctx := middlewares.SetJWTToken(context.Background(), &jwt.Token{Claims: claims})
+	// refreshToken refresh token Keycloak, подписанный секретом realm
+	refreshToken := func(session string) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &middlewares.Claims{
+			StandardClaims: jwt.StandardClaims{Subject: userID.String()},
+			SessionID:      session,
+		}).SignedString([]byte("realm-secret"))
+		require.NoError(t, err)
+		return token
+	}
+
+	t.Run("session is revoked", func(t *testing.T) {
+		kc, revocations := newFakeKeycloak(), middlewares.NewRevocationList(time.Minute)
+		svc := authservice.NewAuthService(newFakeStorage(), kc, kc, revocations)
+
+		refresh := refreshToken("session-1")
+		require.NoError(t, svc.Logout(ctx, refresh))
+
+		assert.Equal(t, []string{refresh}, kc.loggedOut)
+		assert.True(t, revocations.IsRevoked(claims))
+	})
+
+	t.Run("session of the refresh token is revoked", func(t *testing.T) {
+		kc, revocations := newFakeKeycloak(), middlewares.NewRevocationList(time.Minute)
+		svc := authservice.NewAuthService(newFakeStorage(), kc, kc, revocations)
+
+		require.NoError(t, svc.Logout(ctx, refreshToken("session-2")))
+
+		other := *claims
+		other.SessionState = "session-2"
+		other.Id = "other-token"
+		assert.True(t, revocations.IsRevoked(&other))
+		assert.False(t, revocations.IsRevoked(claims), "session of the access token stays active")
+	})
+
+	t.Run("refresh token without session revokes the access token", func(t *testing.T) {
+		kc, revocations := newFakeKeycloak(), middlewares.NewRevocationList(time.Minute)
+		svc := authservice.NewAuthService(newFakeStorage(), kc, kc, revocations)
+
+		require.NoError(t, svc.Logout(ctx, "opaque-refresh"))
+
+		assert.True(t, revocations.IsRevoked(claims))
+		other := *claims
+		other.Id = "other-token"
+		assert.False(t, revocations.IsRevoked(&other))
+	})
+
+	t.Run("keycloak error keeps session", func(t *testing.T) {
+		kc, revocations := newFakeKeycloak(), middlewares.NewRevocationList(time.Minute)
+		kc.logoutErr = errUnavailable
+		svc := authservice.NewAuthService(newFakeStorage(), kc, kc, revocations)
+
+		require.Error(t, svc.Logout(ctx, "refresh"))
+		assert.False(t, revocations.IsRevoked(claims))
+	})
+
+	t.Run("logout all revokes every user token", func(t *testing.T) {
+		kc, revocations := newFakeKeycloak(), middlewares.NewRevocationList(time.Minute)
+		svc := authservice.NewAuthService(newFakeStorage(), kc, kc, revocations)
+
+		require.NoError(t, svc.LogoutAll(ctx, userID))
+
+		assert.Equal(t, []types.UserID{userID}, kc.loggedOutUsers)
+		other := *claims
+		other.SessionState = "session-2"
+		other.Id = "other-token"
+		assert.True(t, revocations.IsRevoked(&other))
+	})
+}
@@ -0,0 +1,243 @@
+package authservice
+
+import (
+	"container/list"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Fisher-Development/woman-app-backend/api/auth"
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+)
+
+const (
+	// Сколько пользователь может провести на странице входа Keycloak.
+	defaultAuthorizationStateTTL    = 10 * time.Minute
+	authorizationStateCleanupPeriod = time.Minute
+	// Вход начинается без аутентификации, поэтому число хранимых state ограничено.
+	defaultMaxPendingAuthorizations = 10000
+)
+
+// authorizationCodeFlow настройки входа через authorization code flow.
+type authorizationCodeFlow struct {
+	client      KeycloakAuthorizationCodeClient
+	redirectURL string
+	scopes      []string
+}
+
+// WithAuthorizationCode включает вход через authorization code flow с PKCE.
+// redirectURL - адрес callback этого API, разрешенный в настройках клиента Keycloak.
+func WithAuthorizationCode(
+	client KeycloakAuthorizationCodeClient,
+	redirectURL string,
+	scopes []string,
+	stateTTL time.Duration,
+) AuthServiceOption {
+	return func(s *AuthService) {
+		if stateTTL <= 0 {
+			stateTTL = defaultAuthorizationStateTTL
+		}
+		s.codeFlow = &authorizationCodeFlow{client: client, redirectURL: redirectURL, scopes: scopes}
+		s.authorizationStates.ttl = stateTTL
+	}
+}
+
+// WithMaxPendingAuthorizations ограничивает число незавершенных входов через authorization code.
+// При переполнении вытесняются самые старые state.
+func WithMaxPendingAuthorizations(n int) AuthServiceOption {
+	return func(s *AuthService) {
+		if n > 0 {
+			s.authorizationStates.maxPending = n
+		}
+	}
+}
+
+// StartAuthorization создает state и PKCE verifier и возвращает адрес страницы входа Keycloak.
+// Verifier не покидает сервер: клиент получает только state и code_challenge в адресе.
+func (s *AuthService) StartAuthorization(ctx context.Context, req auth.AuthorizeRequest) (*auth.AuthorizeResponse, error) {
+	if s.codeFlow == nil {
+		return nil, auth.ErrAuthorizationCodeDisabled
+	}
+
+	state, err := randomState()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := keycloakclient.NewCodeVerifier()
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := s.codeFlow.client.AuthorizationURL(ctx, keycloakclient.AuthorizationRequest{
+		RedirectURI:   s.codeFlow.redirectURL,
+		State:         state,
+		CodeChallenge: keycloakclient.CodeChallenge(verifier),
+		Scopes:        s.codeFlow.scopes,
+		LoginHint:     req.LoginHint,
+	})
+	if err != nil {
+		zap.L().Named("auth-service").Error("Failed to build authorization url", zap.Error(err))
+		return nil, fmt.Errorf("build authorization url: %w", mapKeycloakError(err))
+	}
+
+	expiresAt := s.authorizationStates.put(state, pendingAuthorization{
+		codeVerifier: verifier,
+		redirectURI:  s.codeFlow.redirectURL,
+	})
+
+	return &auth.AuthorizeResponse{URL: url, State: state, ExpiresAt: expiresAt}, nil
+}
+
+// CompleteAuthorization проверяет state и обменивает code на токены.
+// State погашается при первой же попытке, поэтому повтор callback отклоняется.
+func (s *AuthService) CompleteAuthorization(ctx context.Context, req auth.CallbackRequest) (*auth.LoginResponse, error) {
+	logger := zap.L().Named("auth-service")
+
+	if s.codeFlow == nil {
+		return nil, auth.ErrAuthorizationCodeDisabled
+	}
+
+	pending, ok := s.authorizationStates.consume(req.State)
+	if !ok {
+		logger.Warn("Unknown, expired or replayed authorization state")
+		return nil, auth.ErrInvalidAuthorizationState
+	}
+
+	if req.Error != "" {
+		logger.Info("Authorization denied by Keycloak",
+			zap.String("error", req.Error),
+			zap.String("error_description", req.ErrorDescription))
+		return nil, fmt.Errorf("%w: %s", auth.ErrAuthorizationDenied, req.Error)
+	}
+
+	tokenResp, err := s.codeFlow.client.ExchangeCode(ctx, req.Code, pending.redirectURI, pending.codeVerifier)
+	if err != nil {
+		logger.Warn("Authorization code exchange failed", zap.Error(err))
+		if errors.Is(err, keycloakclient.ErrInvalidGrant) {
+			return nil, fmt.Errorf("exchange code: %w: %v", auth.ErrInvalidAuthorizationCode, err)
+		}
+		return nil, fmt.Errorf("exchange code: %w", mapKeycloakError(err))
+	}
+
+	logger.Info("User logged in via authorization code")
+
+	s.recordEmailVerification(ctx, tokenResp.AccessToken)
+
+	return &auth.LoginResponse{
+		AccessToken:  tokenResp.AccessToken,
+		RefreshToken: tokenResp.RefreshToken,
+		ExpiresIn:    tokenResp.ExpiresIn,
+	}, nil
+}
+
+// pendingAuthorization начатый, но еще не завершенный вход.
+type pendingAuthorization struct {
+	state        string
+	codeVerifier string
+	redirectURI  string
+	expiresAt    time.Time
+}
+
+// authorizationStates одноразовые state запросов авторизации.
+// Хранятся в памяти, поэтому callback должен прийти на тот же экземпляр приложения.
+// TTL у всех state одинаковый, поэтому порядок добавления совпадает с порядком истечения.
+type authorizationStates struct {
+	ttl        time.Duration
+	maxPending int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]*list.Element
+	order   *list.List // *pendingAuthorization от самого старого к самому новому
+}
+
+func newAuthorizationStates(ttl time.Duration) *authorizationStates {
+	return &authorizationStates{
+		ttl:        ttl,
+		maxPending: defaultMaxPendingAuthorizations,
+		now:        time.Now,
+		pending:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// put сохраняет state и возвращает время, до которого его можно погасить.
+// Если хранилище заполнено, вытесняется самый старый state.
+func (a *authorizationStates) put(state string, pending pendingAuthorization) time.Time {
+	pending.state = state
+	pending.expiresAt = a.now().Add(a.ttl)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for a.order.Len() >= a.maxPending {
+		a.remove(a.order.Front())
+	}
+	a.pending[state] = a.order.PushBack(&pending)
+	return pending.expiresAt
+}
+
+// consume погашает state. Повторный вызов с тем же state вернет false.
+func (a *authorizationStates) consume(state string) (pendingAuthorization, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	elem, ok := a.pending[state]
+	if !ok {
+		return pendingAuthorization{}, false
+	}
+	pending := a.remove(elem)
+	if !a.now().Before(pending.expiresAt) {
+		return pendingAuthorization{}, false
+	}
+	return *pending, true
+}
+
+// remove удаляет state. Вызывается под mu.
+func (a *authorizationStates) remove(elem *list.Element) *pendingAuthorization {
+	pending := a.order.Remove(elem).(*pendingAuthorization)
+	delete(a.pending, pending.state)
+	return pending
+}
+
+// Run периодически удаляет истекшие state до отмены контекста.
+func (a *authorizationStates) Run(ctx context.Context) error {
+	ticker := time.NewTicker(authorizationStateCleanupPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			a.cleanup()
+		}
+	}
+}
+
+func (a *authorizationStates) cleanup() {
+	now := a.now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for elem := a.order.Front(); elem != nil && !now.Before(elem.Value.(*pendingAuthorization).expiresAt); {
+		next := elem.Next()
+		a.remove(elem)
+		elem = next
+	}
+}
+
+func randomState() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generate state: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,216 @@
+package authservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	"github.com/Fisher-Development/woman-app-backend/internal/store"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+const (
+	compensationAttempts     = 3
+	compensationInitialDelay = 100 * time.Millisecond
+	compensationTimeout      = 10 * time.Second
+
+	defaultOrphanCleanupInterval = 5 * time.Minute
+	orphanCleanupBatchSize       = 50
+)
+
+// compensateKeycloakUser удаляет пользователя Keycloak после неудачной записи в БД.
+// Если удалить не удалось, пользователь записывается в журнал сверки для OrphanCleaner.
+// Возвращает true, если строка пользователя все-таки есть в БД (ее вставил reconciler
+// или коммит прошел, но ответ потерян) - тогда учетная запись Keycloak жива и удалять ее нельзя.
+// Наличие строки всегда проверяется в БД: по ошибке записи этого не понять.
+func (s *AuthService) compensateKeycloakUser(ctx context.Context, userID types.UserID, email string, cause error) bool {
+	logger := zap.L().Named("auth-service")
+
+	// Компенсация не должна прерываться, если клиент уже отключился
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), compensationTimeout)
+	defer cancel()
+
+	_, err := s.storage.GetUserByUUID(ctx, userID.String())
+	switch {
+	case err == nil:
+		logger.Info("User exists locally despite the error, keeping Keycloak user",
+			zap.String("user_id", userID.String()),
+			zap.NamedError("cause", cause))
+		return true
+	case !errors.Is(err, store.ErrUserNotFound):
+		// Результат записи неизвестен: решение откладывается до OrphanCleaner, который сверит БД позже
+		logger.Error("Failed to check local user, scheduling reconciliation",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+		s.recordOrphan(ctx, userID, email, cause, 0, err)
+		return false
+	}
+
+	err = deleteKeycloakUserWithRetry(ctx, s.keycloakAdminClient, userID)
+	if err == nil {
+		logger.Info("Keycloak user rolled back after failed registration",
+			zap.String("user_id", userID.String()))
+		return false
+	}
+
+	logger.Error("Failed to roll back Keycloak user, scheduling reconciliation",
+		zap.String("user_id", userID.String()),
+		zap.Error(err))
+	s.recordOrphan(ctx, userID, email, cause, compensationAttempts, err)
+	return false
+}
+
+// recordOrphan записывает пользователя Keycloak в журнал сверки для OrphanCleaner.
+func (s *AuthService) recordOrphan(
+	ctx context.Context,
+	userID types.UserID,
+	email string,
+	cause error,
+	attempts int,
+	lastErr error,
+) {
+	record := &models.ReconciliationRecord{
+		KeycloakUserID: userID.String(),
+		Email:          email,
+		Action:         models.ReconciliationActionDeleteUser,
+		Reason:         cause.Error(),
+		Attempts:       attempts,
+		LastError:      lastErr.Error(),
+	}
+	if err := s.storage.CreateReconciliationRecord(ctx, record); err != nil {
+		// Последний рубеж: в логах остается всё, что нужно для ручной очистки
+		zap.L().Named("auth-service").Error("Failed to record orphaned Keycloak user",
+			zap.String("user_id", userID.String()),
+			zap.String("email", email),
+			zap.Error(err))
+	}
+}
+
+// deleteKeycloakUserWithRetry удаляет пользователя с экспоненциальной задержкой между попытками.
+// Отсутствие пользователя в Keycloak считается успехом.
+func deleteKeycloakUserWithRetry(ctx context.Context, client KeycloakAdminClient, userID types.UserID) error {
+	delay := compensationInitialDelay
+
+	var err error
+	for attempt := 1; attempt <= compensationAttempts; attempt++ {
+		err = client.DeleteUser(ctx, userID)
+		if err == nil || errors.Is(err, keycloakclient.ErrUserNotFound) {
+			return nil
+		}
+		if attempt == compensationAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("delete keycloak user: %w (last error: %v)", ctx.Err(), err)
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+
+	return fmt.Errorf("delete keycloak user after %d attempts: %w", compensationAttempts, err)
+}
+
+// OrphanCleaner периодически удаляет из Keycloak пользователей, записанных в журнал сверки.
+type OrphanCleaner struct {
+	storage  UserStorage
+	keycloak KeycloakAdminClient
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+// NewOrphanCleaner создает задачу очистки. Нулевой interval заменяется значением по умолчанию.
+func NewOrphanCleaner(storage UserStorage, keycloak KeycloakAdminClient, interval time.Duration) *OrphanCleaner {
+	if interval <= 0 {
+		interval = defaultOrphanCleanupInterval
+	}
+	return &OrphanCleaner{
+		storage:  storage,
+		keycloak: keycloak,
+		interval: interval,
+		logger:   zap.L().Named("orphan-cleaner"),
+	}
+}
+
+// Run выполняет очистку с заданным интервалом до отмены контекста.
+func (c *OrphanCleaner) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := c.CleanUp(ctx); err != nil {
+				c.logger.Error("Orphaned users cleanup failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// CleanUp обрабатывает одну пачку записей журнала и возвращает количество закрытых записей.
+func (c *OrphanCleaner) CleanUp(ctx context.Context) (int, error) {
+	records, err := c.storage.ListReconciliationRecords(ctx, models.ReconciliationActionDeleteUser, orphanCleanupBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("list reconciliation records: %w", err)
+	}
+
+	resolved := 0
+	for _, record := range records {
+		if err := c.resolve(ctx, record); err != nil {
+			c.logger.Warn("Failed to resolve orphaned user",
+				zap.String("user_id", record.KeycloakUserID),
+				zap.Error(err))
+			if err := c.storage.RecordReconciliationAttempt(ctx, record.ID, err.Error()); err != nil {
+				c.logger.Error("Failed to record reconciliation attempt", zap.Error(err))
+			}
+			continue
+		}
+
+		if err := c.storage.DeleteReconciliationRecord(ctx, record.ID); err != nil {
+			c.logger.Error("Failed to delete reconciliation record", zap.Error(err))
+			continue
+		}
+		resolved++
+	}
+
+	if len(records) > 0 {
+		c.logger.Info("Orphaned users cleanup finished",
+			zap.Int("processed", len(records)),
+			zap.Int("resolved", resolved))
+	}
+	return resolved, nil
+}
+
+// resolve удаляет пользователя из Keycloak, если он так и не появился в локальной БД.
+func (c *OrphanCleaner) resolve(ctx context.Context, record models.ReconciliationRecord) error {
+	userID, err := types.Parse[types.UserID](record.KeycloakUserID)
+	if err != nil {
+		return fmt.Errorf("parse user id: %w", err)
+	}
+
+	// Пользователь все-таки есть в БД (например, повторная регистрация прошла) - удалять нельзя
+	_, err = c.storage.GetUserByUUID(ctx, record.KeycloakUserID)
+	if err == nil {
+		c.logger.Info("User exists locally, dropping reconciliation record",
+			zap.String("user_id", record.KeycloakUserID))
+		return nil
+	}
+	if !errors.Is(err, store.ErrUserNotFound) {
+		return fmt.Errorf("get local user: %w", err)
+	}
+
+	if err := c.keycloak.DeleteUser(ctx, userID); err != nil && !errors.Is(err, keycloakclient.ErrUserNotFound) {
+		return err
+	}
+
+	c.logger.Info("Orphaned Keycloak user deleted", zap.String("user_id", record.KeycloakUserID))
+	return nil
+}
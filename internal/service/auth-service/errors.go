@@ -0,0 +1,40 @@
+package authservice
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Fisher-Development/woman-app-backend/api/auth"
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+)
+
+// mapKeycloakError заменяет ошибку Keycloak на ошибку API, которую хендлер умеет показать клиенту.
+// Исходная ошибка остается в тексте только для логов.
+func mapKeycloakError(err error) error {
+	var target error
+	switch {
+	case errors.Is(err, keycloakclient.ErrUnavailable):
+		target = auth.ErrServiceUnavailable
+	case errors.Is(err, keycloakclient.ErrInvalidCredentials):
+		target = auth.ErrInvalidCredentials
+	case errors.Is(err, keycloakclient.ErrUserDisabled):
+		target = auth.ErrUserDisabled
+	case errors.Is(err, keycloakclient.ErrUserExists):
+		target = auth.ErrUserAlreadyExists
+	case errors.Is(err, keycloakclient.ErrUserNotFound):
+		target = auth.ErrUserNotFound
+	default:
+		return err
+	}
+	return fmt.Errorf("%w: %v", target, err)
+}
+
+// mapTokenError то же, что mapKeycloakError, но любой отказ Keycloak в операции
+// с refresh token означает, что токен недействителен.
+func mapTokenError(err error) error {
+	var apiErr *keycloakclient.APIError
+	if errors.As(err, &apiErr) && !errors.Is(err, keycloakclient.ErrUnavailable) {
+		return fmt.Errorf("%w: %v", auth.ErrInvalidRefreshToken, err)
+	}
+	return mapKeycloakError(err)
+}
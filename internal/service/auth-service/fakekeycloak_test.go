@@ -0,0 +1,236 @@
+package authservice_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fisher-Development/woman-app-backend/api/auth"
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/loginguard"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	authservice "github.com/Fisher-Development/woman-app-backend/internal/service/auth-service"
+	"github.com/Fisher-Development/woman-app-backend/internal/testingh/fakekeycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+// TestAuthService_FakeKeycloak проверяет сервис вместе с настоящим клиентом Keycloak без сети.
+func TestAuthService_FakeKeycloak(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t)
+	storage := newFakeStorage()
+	svc := authservice.NewAuthService(storage, client, client, nil)
+
+	require.NoError(t, svc.RegisterUser(ctx, registerRequest))
+
+	users := kc.Users()
+	require.Len(t, users, 1)
+	assert.Contains(t, storage.users, users[0].ID)
+	assert.Equal(t, []string{keycloakclient.RequiredActionVerifyEmail}, users[0].ActionEmails)
+
+	tokens, err := svc.LoginUser(ctx, auth.LoginRequest{Email: registerRequest.Email, Password: registerRequest.Password})
+	require.NoError(t, err)
+	assert.Nil(t, storage.users[users[0].ID].EmailVerifiedAt)
+
+	_, err = svc.RefreshAccessToken(ctx, tokens.RefreshToken)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.ForgotPassword(ctx, auth.ForgotPasswordRequest{Email: registerRequest.Email}))
+	user, _ := kc.User(users[0].ID)
+	assert.Contains(t, user.ActionEmails, keycloakclient.RequiredActionUpdatePassword)
+
+	t.Run("keycloak unavailable", func(t *testing.T) {
+		kc.Fail(fakekeycloak.EndpointToken, fakekeycloak.Fault{Times: 1})
+
+		_, err := svc.LoginUser(ctx, auth.LoginRequest{Email: registerRequest.Email, Password: registerRequest.Password})
+		require.ErrorIs(t, err, auth.ErrServiceUnavailable)
+	})
+
+	t.Run("keycloak errors are mapped", func(t *testing.T) {
+		_, err := svc.LoginUser(ctx, auth.LoginRequest{Email: registerRequest.Email, Password: "wrong-password"})
+		require.ErrorIs(t, err, auth.ErrInvalidCredentials)
+
+		kc.UpdateUser(users[0].ID, func(user *fakekeycloak.User) { user.Enabled = false })
+		_, err = svc.LoginUser(ctx, auth.LoginRequest{Email: registerRequest.Email, Password: registerRequest.Password})
+		require.ErrorIs(t, err, auth.ErrUserDisabled)
+
+		_, err = svc.RefreshAccessToken(ctx, "garbage")
+		require.ErrorIs(t, err, auth.ErrInvalidRefreshToken)
+
+		err = svc.RegisterUser(ctx, registerRequest)
+		require.ErrorIs(t, err, auth.ErrUserAlreadyExists)
+	})
+}
+
+// TestAuthService_AuthorizationCode проверяет вход через страницу Keycloak с PKCE и защиту state от повтора.
+func TestAuthService_AuthorizationCode(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t)
+	storage := newFakeStorage()
+	user := kc.AddUser(fakekeycloak.User{Email: "jane@example.com", Password: "password123", Enabled: true, EmailVerified: true})
+	storage.users[user.ID] = &models.User{UUID: user.ID, Email: user.Email}
+
+	const redirectURL = "https://api.example.com/api/v1/auth/callback"
+	svc := authservice.NewAuthService(storage, client, client, nil,
+		authservice.WithAuthorizationCode(client, redirectURL, nil, time.Minute))
+
+	start, err := svc.StartAuthorization(ctx, auth.AuthorizeRequest{LoginHint: user.Email})
+	require.NoError(t, err)
+	assert.NotContains(t, start.URL, "code_verifier")
+
+	callback := followAuthorization(t, start.URL)
+	require.Equal(t, start.State, callback.Get("state"))
+
+	req := auth.CallbackRequest{State: callback.Get("state"), Code: callback.Get("code")}
+	tokens, err := svc.CompleteAuthorization(ctx, req)
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotNil(t, storage.users[user.ID].EmailVerifiedAt)
+
+	t.Run("state is single use", func(t *testing.T) {
+		_, err := svc.CompleteAuthorization(ctx, req)
+		require.ErrorIs(t, err, auth.ErrInvalidAuthorizationState)
+	})
+
+	t.Run("unknown state", func(t *testing.T) {
+		_, err := svc.CompleteAuthorization(ctx, auth.CallbackRequest{State: "forged", Code: "code"})
+		require.ErrorIs(t, err, auth.ErrInvalidAuthorizationState)
+	})
+
+	t.Run("denied by keycloak", func(t *testing.T) {
+		start, err := svc.StartAuthorization(ctx, auth.AuthorizeRequest{LoginHint: "nobody@example.com"})
+		require.NoError(t, err)
+		callback := followAuthorization(t, start.URL)
+
+		_, err = svc.CompleteAuthorization(ctx, auth.CallbackRequest{
+			State: callback.Get("state"),
+			Error: callback.Get("error"),
+		})
+		require.ErrorIs(t, err, auth.ErrAuthorizationDenied)
+	})
+
+	t.Run("invalid code", func(t *testing.T) {
+		start, err := svc.StartAuthorization(ctx, auth.AuthorizeRequest{LoginHint: user.Email})
+		require.NoError(t, err)
+
+		_, err = svc.CompleteAuthorization(ctx, auth.CallbackRequest{State: start.State, Code: "forged"})
+		require.ErrorIs(t, err, auth.ErrInvalidAuthorizationCode)
+	})
+
+	t.Run("oldest pending states are evicted", func(t *testing.T) {
+		svc := authservice.NewAuthService(storage, client, client, nil,
+			authservice.WithMaxPendingAuthorizations(2),
+			authservice.WithAuthorizationCode(client, redirectURL, nil, time.Minute))
+
+		starts := make([]*auth.AuthorizeResponse, 0, 3)
+		for range 3 {
+			start, err := svc.StartAuthorization(ctx, auth.AuthorizeRequest{LoginHint: user.Email})
+			require.NoError(t, err)
+			starts = append(starts, start)
+		}
+
+		callback := followAuthorization(t, starts[0].URL)
+		_, err := svc.CompleteAuthorization(ctx, auth.CallbackRequest{State: starts[0].State, Code: callback.Get("code")})
+		require.ErrorIs(t, err, auth.ErrInvalidAuthorizationState, "evicted by newer states")
+
+		callback = followAuthorization(t, starts[2].URL)
+		_, err = svc.CompleteAuthorization(ctx, auth.CallbackRequest{State: starts[2].State, Code: callback.Get("code")})
+		require.NoError(t, err)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		svc := authservice.NewAuthService(storage, client, client, nil)
+
+		_, err := svc.StartAuthorization(ctx, auth.AuthorizeRequest{})
+		require.ErrorIs(t, err, auth.ErrAuthorizationCodeDisabled)
+	})
+}
+
+// TestAuthService_LoginProtection проверяет блокировку входа после серии неверных паролей.
+func TestAuthService_LoginProtection(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t)
+	user := kc.AddUser(fakekeycloak.User{Email: "jane@example.com", Password: "password123", Enabled: true})
+	audit := &fakeAuditLog{}
+	guard := loginguard.New(loginguard.NewMemoryStore(), audit,
+		loginguard.WithAccountPolicy(3, time.Hour),
+		loginguard.WithDelays(10, time.Second, time.Second),
+	)
+	svc := authservice.NewAuthService(newFakeStorage(), client, client, nil, authservice.WithLoginGuard(guard))
+
+	login := func(password string) error {
+		_, err := svc.LoginUser(ctx, auth.LoginRequest{Email: user.Email, Password: password, ClientIP: "203.0.113.7"})
+		return err
+	}
+
+	for range 3 {
+		require.ErrorIs(t, login("wrong-password"), auth.ErrInvalidCredentials)
+	}
+
+	tokenRequests := kc.Requests(fakekeycloak.EndpointToken)
+	var tooManyAttempts *auth.TooManyAttemptsError
+	require.ErrorAs(t, login(user.Password), &tooManyAttempts)
+	assert.Greater(t, tooManyAttempts.RetryAfter, time.Duration(0))
+	assert.Equal(t, tokenRequests, kc.Requests(fakekeycloak.EndpointToken), "locked out attempts do not reach keycloak")
+
+	t.Run("keycloak outage is not counted", func(t *testing.T) {
+		kc.Fail(fakekeycloak.EndpointToken, fakekeycloak.Fault{Times: 1})
+		_, err := svc.LoginUser(ctx, auth.LoginRequest{Email: "john@example.com", Password: "password123"})
+		require.ErrorIs(t, err, auth.ErrServiceUnavailable)
+
+		attempt, wait, err := guard.Check(ctx, "", "john@example.com")
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+		require.NoError(t, guard.Release(ctx, attempt))
+	})
+
+	adminID := types.MustParse[types.UserID]("f47ac10b-58cc-4372-a567-0e02b2c3d479")
+	require.NoError(t, svc.ClearLoginLockout(ctx, adminID, auth.ClearLoginLockoutRequest{Email: user.Email}))
+	require.NoError(t, login(user.Password))
+
+	require.Len(t, audit.events, 2)
+	assert.Equal(t, models.AuditActionLoginLockout, audit.events[0].Action)
+	assert.Equal(t, models.AuditActionLoginUnlock, audit.events[1].Action)
+	assert.Equal(t, adminID.String(), audit.events[1].Actor)
+
+	t.Run("disabled", func(t *testing.T) {
+		svc := authservice.NewAuthService(newFakeStorage(), client, client, nil)
+
+		err := svc.ClearLoginLockout(ctx, adminID, auth.ClearLoginLockoutRequest{Email: user.Email})
+		require.ErrorIs(t, err, auth.ErrLoginProtectionDisabled)
+	})
+}
+
+type fakeAuditLog struct {
+	events []models.AuditEvent
+}
+
+func (f *fakeAuditLog) CreateAuditEvent(_ context.Context, event *models.AuditEvent) error {
+	f.events = append(f.events, *event)
+	return nil
+}
+
+// followAuthorization проходит страницу входа fake Keycloak и возвращает параметры callback.
+func followAuthorization(t *testing.T, authURL string) url.Values {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return location.Query()
+}
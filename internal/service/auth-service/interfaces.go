@@ -0,0 +1,67 @@
+package authservice
+
+import (
+	"context"
+	"time"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/loginguard"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+// UserStorage методы хранилища, которые использует сервис авторизации.
+type UserStorage interface {
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByUUID(ctx context.Context, uuid string) (*models.User, error)
+	MarkEmailVerified(ctx context.Context, uuid string) error
+	ReconciliationStorage
+}
+
+// ReconciliationStorage журнал действий, которые нужно довести до конца в Keycloak.
+type ReconciliationStorage interface {
+	CreateReconciliationRecord(ctx context.Context, record *models.ReconciliationRecord) error
+	ListReconciliationRecords(ctx context.Context, action string, limit int) ([]models.ReconciliationRecord, error)
+	RecordReconciliationAttempt(ctx context.Context, id, lastError string) error
+	DeleteReconciliationRecord(ctx context.Context, id string) error
+}
+
+// KeycloakAuthClient клиент Keycloak для аутентификации пользователей.
+type KeycloakAuthClient interface {
+	LoginUser(ctx context.Context, email, password string) (*keycloakclient.TokenResponse, error)
+	RefreshAccessToken(ctx context.Context, refreshToken string) (*keycloakclient.TokenResponse, error)
+	Logout(ctx context.Context, refreshToken string) error
+}
+
+// KeycloakAuthorizationCodeClient клиент Keycloak для входа через authorization code flow с PKCE.
+type KeycloakAuthorizationCodeClient interface {
+	AuthorizationURL(ctx context.Context, req keycloakclient.AuthorizationRequest) (string, error)
+	ExchangeCode(ctx context.Context, code, redirectURI, codeVerifier string) (*keycloakclient.TokenResponse, error)
+}
+
+// KeycloakAdminClient клиент Keycloak Admin API.
+type KeycloakAdminClient interface {
+	CreateUser(ctx context.Context, req keycloakclient.CreateUserRequest) (*keycloakclient.CreateUserResponse, error)
+	DeleteUser(ctx context.Context, userID types.UserID) error
+	LogoutUser(ctx context.Context, userID types.UserID) error
+	GetUser(ctx context.Context, userID types.UserID) (*keycloakclient.UserRepresentation, error)
+	FindUsersByEmail(ctx context.Context, email string) ([]keycloakclient.UserRepresentation, error)
+	ResetPassword(ctx context.Context, userID types.UserID, password string, temporary bool) error
+	ExecuteActionsEmail(ctx context.Context, userID types.UserID, actions []string, lifespan time.Duration) error
+}
+
+// SessionRevoker список отозванных сессий, который проверяет middleware авторизации.
+type SessionRevoker interface {
+	RevokeSession(sessionID string)
+	RevokeToken(jti string, expiresAt time.Time)
+	RevokeSubject(subject string)
+}
+
+// LoginGuard учет неудачных попыток входа и временные блокировки.
+type LoginGuard interface {
+	Check(ctx context.Context, ip, email string) (loginguard.Attempt, time.Duration, error)
+	Failure(ctx context.Context, attempt loginguard.Attempt) error
+	Success(ctx context.Context, attempt loginguard.Attempt) error
+	Release(ctx context.Context, attempt loginguard.Attempt) error
+	Clear(ctx context.Context, actor, ip, email string) error
+}
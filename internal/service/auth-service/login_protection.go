@@ -0,0 +1,89 @@
+package authservice
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/Fisher-Development/woman-app-backend/api/auth"
+	"github.com/Fisher-Development/woman-app-backend/internal/loginguard"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+// WithLoginGuard включает защиту входа по паролю от перебора.
+func WithLoginGuard(guard LoginGuard) AuthServiceOption {
+	return func(s *AuthService) {
+		s.loginGuard = guard
+	}
+}
+
+// checkLogin возвращает TooManyAttemptsError, если попытка входа сейчас не разрешена.
+// Разрешенная попытка зарезервирована и должна быть завершена одним из record* методов.
+// Сбой хранилища попыток не должен закрывать вход всем пользователям, поэтому попытка пропускается.
+func (s *AuthService) checkLogin(ctx context.Context, req auth.LoginRequest) (loginguard.Attempt, error) {
+	if s.loginGuard == nil {
+		return loginguard.Attempt{}, nil
+	}
+
+	attempt, retryAfter, err := s.loginGuard.Check(ctx, req.ClientIP, req.Email)
+	if err != nil {
+		zap.L().Named("auth-service").Error("Failed to check login attempts", zap.Error(err))
+		return loginguard.Attempt{}, nil
+	}
+	if retryAfter > 0 {
+		zap.L().Named("auth-service").Warn("Login attempt rejected by brute-force protection",
+			zap.String("ip", req.ClientIP),
+			zap.Duration("retry_after", retryAfter))
+		return loginguard.Attempt{}, &auth.TooManyAttemptsError{RetryAfter: retryAfter}
+	}
+	return attempt, nil
+}
+
+// recordLoginFailure учитывает неудачную попытку входа.
+func (s *AuthService) recordLoginFailure(ctx context.Context, attempt loginguard.Attempt) {
+	if s.loginGuard == nil {
+		return
+	}
+	if err := s.loginGuard.Failure(ctx, attempt); err != nil {
+		zap.L().Named("auth-service").Error("Failed to record login failure", zap.Error(err))
+	}
+}
+
+// recordLoginSuccess сбрасывает неудачные попытки учетной записи.
+func (s *AuthService) recordLoginSuccess(ctx context.Context, attempt loginguard.Attempt) {
+	if s.loginGuard == nil {
+		return
+	}
+	if err := s.loginGuard.Success(ctx, attempt); err != nil {
+		zap.L().Named("auth-service").Error("Failed to reset login failures", zap.Error(err))
+	}
+}
+
+// releaseLogin снимает резерв попытки, исход которой неизвестен (ошибка не из-за учетных данных).
+func (s *AuthService) releaseLogin(ctx context.Context, attempt loginguard.Attempt) {
+	if s.loginGuard == nil {
+		return
+	}
+	// Резерв нужно снять, даже если клиент уже отключился
+	if err := s.loginGuard.Release(context.WithoutCancel(ctx), attempt); err != nil {
+		zap.L().Named("auth-service").Error("Failed to release login attempt", zap.Error(err))
+	}
+}
+
+// ClearLoginLockout снимает блокировку входа по email и (или) IP адресу (только для администраторов).
+func (s *AuthService) ClearLoginLockout(ctx context.Context, actor types.UserID, req auth.ClearLoginLockoutRequest) error {
+	if s.loginGuard == nil {
+		return auth.ErrLoginProtectionDisabled
+	}
+
+	if err := s.loginGuard.Clear(ctx, actor.String(), req.IP, req.Email); err != nil {
+		zap.L().Named("auth-service").Error("Failed to clear login lockout",
+			zap.String("actor", actor.String()),
+			zap.Error(err))
+		return fmt.Errorf("clear login lockout: %w", err)
+	}
+
+	zap.L().Named("auth-service").Info("Login lockout cleared", zap.String("actor", actor.String()))
+	return nil
+}
@@ -0,0 +1,155 @@
+package authservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Fisher-Development/woman-app-backend/api/auth"
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/store"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+const (
+	defaultPasswordAttempts       = 5
+	defaultPasswordAttemptsWindow = 15 * time.Minute
+
+	// Время жизни ссылки из письма о сбросе пароля.
+	passwordResetLinkLifespan = time.Hour
+)
+
+// ForgotPassword отправляет письмо Keycloak со ссылкой на смену пароля.
+// Если пользователь с таким email не найден, ошибка не возвращается,
+// чтобы ответ не раскрывал, зарегистрирован ли email.
+func (s *AuthService) ForgotPassword(ctx context.Context, req auth.ForgotPasswordRequest) error {
+	logger := zap.L().Named("auth-service")
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if err := s.allowAttempt("forgot:" + email); err != nil {
+		return err
+	}
+
+	users, err := s.keycloakAdminClient.FindUsersByEmail(ctx, email)
+	if err != nil {
+		logger.Error("Failed to find user for password reset", zap.Error(err))
+		return fmt.Errorf("find user by email: %w", mapKeycloakError(err))
+	}
+
+	for _, user := range users {
+		if !user.Enabled || !strings.EqualFold(user.Email, email) {
+			continue
+		}
+
+		userID, err := types.Parse[types.UserID](user.ID)
+		if err != nil {
+			logger.Warn("Invalid Keycloak user ID", zap.String("user_id", user.ID), zap.Error(err))
+			continue
+		}
+
+		// Ошибку отправки не возвращаем: иначе ответ отличался бы для существующих пользователей
+		err = s.keycloakAdminClient.ExecuteActionsEmail(ctx, userID,
+			[]string{keycloakclient.RequiredActionUpdatePassword},
+			passwordResetLinkLifespan,
+		)
+		if err != nil {
+			logger.Error("Failed to send password reset email",
+				zap.String("user_id", userID.String()),
+				zap.Error(err))
+			return nil
+		}
+
+		logger.Info("Password reset email sent", zap.String("user_id", userID.String()))
+		return nil
+	}
+
+	logger.Debug("Password reset requested for unknown email")
+	return nil
+}
+
+// ChangePassword меняет пароль пользователя после проверки текущего пароля.
+func (s *AuthService) ChangePassword(ctx context.Context, userID types.UserID, req auth.ChangePasswordRequest) error {
+	logger := zap.L().Named("auth-service")
+
+	key := "change:" + userID.String()
+	if err := s.allowAttempt(key); err != nil {
+		return err
+	}
+
+	user, err := s.storage.GetUserByUUID(ctx, userID.String())
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			return auth.ErrUserNotFound
+		}
+		return fmt.Errorf("get user: %v", err)
+	}
+
+	// Проверяем текущий пароль входом через Keycloak
+	verification, err := s.keycloakClient.LoginUser(ctx, user.Email, req.CurrentPassword)
+	if err != nil {
+		logger.Warn("Current password verification failed",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+		if errors.Is(err, keycloakclient.ErrUnavailable) {
+			return fmt.Errorf("verify current password: %w", mapKeycloakError(err))
+		}
+		return auth.ErrInvalidCurrentPassword
+	}
+	// Вход нужен только для проверки пароля: закрываем созданную им SSO сессию,
+	// даже если клиент уже отключился
+	defer func() {
+		if err := s.keycloakClient.Logout(context.WithoutCancel(ctx), verification.RefreshToken); err != nil {
+			logger.Warn("Failed to close password verification session",
+				zap.String("user_id", userID.String()),
+				zap.Error(err))
+		}
+	}()
+
+	if err := s.keycloakAdminClient.ResetPassword(ctx, userID, req.NewPassword, false); err != nil {
+		logger.Error("Failed to change password",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+		return fmt.Errorf("reset password: %w", mapKeycloakError(err))
+	}
+
+	s.attemptLimiter.Reset(key)
+
+	logger.Info("Password changed", zap.String("user_id", userID.String()))
+	return nil
+}
+
+// SetTemporaryPassword устанавливает временный пароль, который пользователь должен сменить при следующем входе.
+func (s *AuthService) SetTemporaryPassword(
+	ctx context.Context,
+	userID types.UserID,
+	req auth.TemporaryPasswordRequest,
+) error {
+	logger := zap.L().Named("auth-service")
+
+	if err := s.allowAttempt("temporary:" + userID.String()); err != nil {
+		return err
+	}
+
+	if err := s.keycloakAdminClient.ResetPassword(ctx, userID, req.Password, true); err != nil {
+		logger.Error("Failed to set temporary password",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+		return fmt.Errorf("reset password: %w", mapKeycloakError(err))
+	}
+
+	logger.Info("Temporary password set", zap.String("user_id", userID.String()))
+	return nil
+}
+
+// allowAttempt учитывает попытку и возвращает ошибку при превышении лимита.
+func (s *AuthService) allowAttempt(key string) error {
+	if ok, retryAfter := s.attemptLimiter.Allow(key); !ok {
+		zap.L().Named("auth-service").Warn("Password attempts limit exceeded")
+		return &auth.TooManyAttemptsError{RetryAfter: retryAfter}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package authservice_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fisher-Development/woman-app-backend/api/auth"
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	authservice "github.com/Fisher-Development/woman-app-backend/internal/service/auth-service"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+// registerFakeUser создает пользователя в fake Keycloak и локальном хранилище.
+func registerFakeUser(t *testing.T, kc *fakeKeycloak, storage *fakeStorage, email, password string) types.UserID {
+	t.Helper()
+
+	created, err := kc.CreateUser(context.Background(), keycloakclient.CreateUserRequest{
+		Email:       email,
+		Enabled:     true,
+		Credentials: []keycloakclient.UserCredentialRepresentation{{Type: "password", Value: password}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, storage.CreateUser(context.Background(), &models.User{
+		UUID:  created.UserID.String(),
+		Email: email,
+	}))
+	return created.UserID
+}
+
+func TestForgotPassword(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("known email receives update password action", func(t *testing.T) {
+		kc, storage := newFakeKeycloak(), newFakeStorage()
+		userID := registerFakeUser(t, kc, storage, "jane@example.com", "password123")
+		svc := authservice.NewAuthService(storage, kc, kc, nil)
+
+		require.NoError(t, svc.ForgotPassword(ctx, auth.ForgotPasswordRequest{Email: "Jane@Example.com"}))
+
+		assert.Equal(t, []string{keycloakclient.RequiredActionUpdatePassword}, kc.actionEmails[userID])
+	})
+
+	t.Run("unknown email is indistinguishable", func(t *testing.T) {
+		kc, storage := newFakeKeycloak(), newFakeStorage()
+		svc := authservice.NewAuthService(storage, kc, kc, nil)
+
+		require.NoError(t, svc.ForgotPassword(ctx, auth.ForgotPasswordRequest{Email: "nobody@example.com"}))
+		assert.Empty(t, kc.actionEmails)
+	})
+
+	t.Run("attempts are rate limited", func(t *testing.T) {
+		kc, storage := newFakeKeycloak(), newFakeStorage()
+		svc := authservice.NewAuthService(storage, kc, kc, nil, authservice.WithPasswordAttempts(2, time.Minute))
+		req := auth.ForgotPasswordRequest{Email: "nobody@example.com"}
+
+		require.NoError(t, svc.ForgotPassword(ctx, req))
+		require.NoError(t, svc.ForgotPassword(ctx, req))
+
+		var tooManyAttempts *auth.TooManyAttemptsError
+		require.ErrorAs(t, svc.ForgotPassword(ctx, req), &tooManyAttempts)
+		assert.Positive(t, tooManyAttempts.RetryAfter)
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		current  string
+		wantErr  error
+		wantPass string
+		// сессии Keycloak, закрытые после проверки текущего пароля
+		wantLogouts []string
+	}{
+		{
+			name:        "valid current password",
+			current:     "password123",
+			wantPass:    "new-password",
+			wantLogouts: []string{"refresh"},
+		},
+		{
+			name:     "invalid current password",
+			current:  "wrong",
+			wantErr:  auth.ErrInvalidCurrentPassword,
+			wantPass: "password123",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kc, storage := newFakeKeycloak(), newFakeStorage()
+			userID := registerFakeUser(t, kc, storage, "jane@example.com", "password123")
+			svc := authservice.NewAuthService(storage, kc, kc, nil)
+
+			err := svc.ChangePassword(ctx, userID, auth.ChangePasswordRequest{
+				CurrentPassword: tt.current,
+				NewPassword:     "new-password",
+			})
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantPass, kc.passwords[userID].Value)
+			assert.False(t, kc.passwords[userID].Temporary)
+			assert.Equal(t, tt.wantLogouts, kc.loggedOut)
+		})
+	}
+
+	t.Run("wrong passwords are rate limited", func(t *testing.T) {
+		kc, storage := newFakeKeycloak(), newFakeStorage()
+		userID := registerFakeUser(t, kc, storage, "jane@example.com", "password123")
+		svc := authservice.NewAuthService(storage, kc, kc, nil, authservice.WithPasswordAttempts(1, time.Minute))
+		req := auth.ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "new-password"}
+
+		require.ErrorIs(t, svc.ChangePassword(ctx, userID, req), auth.ErrInvalidCurrentPassword)
+
+		var tooManyAttempts *auth.TooManyAttemptsError
+		require.ErrorAs(t, svc.ChangePassword(ctx, userID, req), &tooManyAttempts)
+	})
+}
+
+func TestSetTemporaryPassword(t *testing.T) {
+	ctx := context.Background()
+	kc, storage := newFakeKeycloak(), newFakeStorage()
+	userID := registerFakeUser(t, kc, storage, "jane@example.com", "password123")
+	svc := authservice.NewAuthService(storage, kc, kc, nil)
+
+	require.NoError(t, svc.SetTemporaryPassword(ctx, userID, auth.TemporaryPasswordRequest{Password: "temporary1"}))
+	assert.Equal(t, "temporary1", kc.passwords[userID].Value)
+	assert.True(t, kc.passwords[userID].Temporary)
+
+	err := svc.SetTemporaryPassword(ctx, types.NewUserID(), auth.TemporaryPasswordRequest{Password: "temporary1"})
+	require.ErrorIs(t, err, auth.ErrUserNotFound)
+}
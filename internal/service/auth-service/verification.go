@@ -0,0 +1,76 @@
+package authservice
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+
+	"github.com/Fisher-Development/woman-app-backend/api/auth"
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+// ResendVerificationEmail повторно отправляет письмо для подтверждения email.
+func (s *AuthService) ResendVerificationEmail(ctx context.Context, userID types.UserID) error {
+	logger := zap.L().Named("auth-service")
+
+	if err := s.allowAttempt("verify:" + userID.String()); err != nil {
+		return err
+	}
+
+	user, err := s.keycloakAdminClient.GetUser(ctx, userID)
+	if err != nil {
+		logger.Error("Failed to get user for email verification",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+		return fmt.Errorf("get user: %w", mapKeycloakError(err))
+	}
+	if user.EmailVerified {
+		return auth.ErrEmailAlreadyVerified
+	}
+
+	err = s.keycloakAdminClient.ExecuteActionsEmail(ctx, userID, []string{keycloakclient.RequiredActionVerifyEmail}, 0)
+	if err != nil {
+		logger.Error("Failed to send verification email",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+		return fmt.Errorf("send verification email: %w", mapKeycloakError(err))
+	}
+
+	logger.Info("Verification email sent", zap.String("user_id", userID.String()))
+	return nil
+}
+
+// sendVerificationEmail отправляет письмо с действием VERIFY_EMAIL после регистрации.
+func (s *AuthService) sendVerificationEmail(ctx context.Context, userID types.UserID) {
+	err := s.keycloakAdminClient.ExecuteActionsEmail(ctx, userID, []string{keycloakclient.RequiredActionVerifyEmail}, 0)
+	if err != nil {
+		zap.L().Named("auth-service").Warn("Failed to send verification email",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+	}
+}
+
+// recordEmailVerification сохраняет время подтверждения email, если токен содержит email_verified=true.
+// Токен только что выдан Keycloak, поэтому подпись не проверяется.
+func (s *AuthService) recordEmailVerification(ctx context.Context, accessToken string) {
+	logger := zap.L().Named("auth-service")
+
+	var claims middlewares.Claims
+	if _, _, err := new(jwt.Parser).ParseUnverified(accessToken, &claims); err != nil {
+		logger.Debug("Failed to parse access token claims", zap.Error(err))
+		return
+	}
+	if !claims.EmailVerified || claims.Subject == "" {
+		return
+	}
+
+	if err := s.storage.MarkEmailVerified(ctx, claims.Subject); err != nil {
+		logger.Warn("Failed to record email verification",
+			zap.String("user_id", claims.Subject),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,89 @@
+package authservice_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fisher-Development/woman-app-backend/api/auth"
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	authservice "github.com/Fisher-Development/woman-app-backend/internal/service/auth-service"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+func TestResendVerificationEmail(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unverified user receives verify email action", func(t *testing.T) {
+		kc, storage := newFakeKeycloak(), newFakeStorage()
+		userID := registerFakeUser(t, kc, storage, "jane@example.com", "password123")
+		svc := authservice.NewAuthService(storage, kc, kc, nil)
+
+		require.NoError(t, svc.ResendVerificationEmail(ctx, userID))
+		assert.Equal(t, []string{keycloakclient.RequiredActionVerifyEmail}, kc.actionEmails[userID])
+	})
+
+	t.Run("verified user", func(t *testing.T) {
+		kc, storage := newFakeKeycloak(), newFakeStorage()
+		userID := registerFakeUser(t, kc, storage, "jane@example.com", "password123")
+		verifyFakeEmail(kc, userID)
+		svc := authservice.NewAuthService(storage, kc, kc, nil)
+
+		require.ErrorIs(t, svc.ResendVerificationEmail(ctx, userID), auth.ErrEmailAlreadyVerified)
+		assert.Empty(t, kc.actionEmails)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		kc, storage := newFakeKeycloak(), newFakeStorage()
+		svc := authservice.NewAuthService(storage, kc, kc, nil)
+
+		require.ErrorIs(t, svc.ResendVerificationEmail(ctx, types.NewUserID()), auth.ErrUserNotFound)
+	})
+
+	t.Run("attempts are rate limited", func(t *testing.T) {
+		kc, storage := newFakeKeycloak(), newFakeStorage()
+		userID := registerFakeUser(t, kc, storage, "jane@example.com", "password123")
+		svc := authservice.NewAuthService(storage, kc, kc, nil, authservice.WithPasswordAttempts(1, time.Minute))
+
+		require.NoError(t, svc.ResendVerificationEmail(ctx, userID))
+
+		var tooManyAttempts *auth.TooManyAttemptsError
+		require.ErrorAs(t, svc.ResendVerificationEmail(ctx, userID), &tooManyAttempts)
+	})
+}
+
+func TestLoginUser_RecordsEmailVerification(t *testing.T) {
+	ctx := context.Background()
+	kc, storage := newFakeKeycloak(), newFakeStorage()
+	userID := registerFakeUser(t, kc, storage, "jane@example.com", "password123")
+	svc := authservice.NewAuthService(storage, kc, kc, nil)
+	login := auth.LoginRequest{Email: "jane@example.com", Password: "password123"}
+
+	_, err := svc.LoginUser(ctx, login)
+	require.NoError(t, err)
+	assert.Nil(t, storage.users[userID.String()].EmailVerifiedAt)
+
+	verifyFakeEmail(kc, userID)
+	_, err = svc.LoginUser(ctx, login)
+	require.NoError(t, err)
+	require.NotNil(t, storage.users[userID.String()].EmailVerifiedAt)
+
+	// Повторный логин не меняет время подтверждения
+	verifiedAt := *storage.users[userID.String()].EmailVerifiedAt
+	_, err = svc.LoginUser(ctx, login)
+	require.NoError(t, err)
+	assert.Equal(t, verifiedAt, *storage.users[userID.String()].EmailVerifiedAt)
+}
+
+// verifyFakeEmail помечает email пользователя fake Keycloak как подтвержденный.
+func verifyFakeEmail(kc *fakeKeycloak, userID types.UserID) {
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+
+	user := kc.users[userID]
+	user.EmailVerified = true
+	kc.users[userID] = user
+}
@@ -0,0 +1,288 @@
+package cycleservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/Fisher-Development/woman-app-backend/api/cycle"
+	"github.com/Fisher-Development/woman-app-backend/api/prediction"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	"github.com/Fisher-Development/woman-app-backend/internal/store"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+// Дата на устройстве пользователя может отличаться от даты UTC: в часовом поясе UTC+14
+// новый день наступает на 14 часов раньше, в UTC-12 - на 12 часов позже.
+const (
+	maxClockAhead  = 14 * time.Hour
+	maxClockBehind = 12 * time.Hour
+)
+
+// Storage методы хранилища, которые использует сервис циклов.
+type Storage interface {
+	CreateCycle(ctx context.Context, cycle *models.Cycle) error
+	GetCycle(ctx context.Context, userUUID, id string) (*models.Cycle, error)
+	ListCycles(ctx context.Context, userUUID string, from, to models.Date) ([]models.Cycle, error)
+	UpdateCycle(ctx context.Context, cycle *models.Cycle) error
+	DeleteCycle(ctx context.Context, userUUID, id string) error
+	HasOverlappingCycle(ctx context.Context, userUUID string, start models.Date, end *models.Date, excludeID string) (bool, error)
+	WithTx(ctx context.Context, opts store.TxOptions, fn func(ctx context.Context) error) error
+}
+
+// Predictor пересчитывает прогнозные циклы пользователя.
+type Predictor interface {
+	Recompute(ctx context.Context, userID types.UserID) (*prediction.Forecast, error)
+}
+
+// CycleService ведет менструальные циклы пользователя и проверяет их согласованность:
+// циклы не пересекаются, менструация лежит внутри цикла, даты не в будущем.
+type CycleService struct {
+	storage   Storage
+	predictor Predictor // пересчет прогноза после изменения циклов (опционально)
+	now       func() time.Time
+}
+
+// CycleServiceOption опция для настройки CycleService.
+type CycleServiceOption func(*CycleService)
+
+// WithPredictor включает пересчет прогнозных циклов после каждого изменения отмеченных циклов.
+func WithPredictor(predictor Predictor) CycleServiceOption {
+	return func(s *CycleService) {
+		s.predictor = predictor
+	}
+}
+
+// NewCycleService создает новый CycleService.
+func NewCycleService(storage Storage, opts ...CycleServiceOption) *CycleService {
+	s := &CycleService{
+		storage: storage,
+		now:     time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// CreateCycle создает цикл, отмеченный пользователем.
+func (s *CycleService) CreateCycle(ctx context.Context, userID types.UserID, req cycle.CycleRequest) (*models.Cycle, error) {
+	c := &models.Cycle{
+		UserUUID:      userID.String(),
+		StartDate:     *req.StartDate,
+		EndDate:       req.EndDate,
+		PeriodEndDate: req.PeriodEndDate,
+		Notes:         req.Notes,
+	}
+	if err := s.validate(c, req.Today); err != nil {
+		return nil, err
+	}
+
+	err := s.storage.WithTx(ctx, store.TxOptions{Isolation: store.Serializable}, func(ctx context.Context) error {
+		if err := s.checkOverlap(ctx, c); err != nil {
+			return err
+		}
+		return s.storage.CreateCycle(ctx, c)
+	})
+	if err != nil {
+		return nil, s.wrapError("create cycle", userID, err)
+	}
+
+	zap.L().Named("cycle-service").Debug("Cycle created",
+		zap.String("user_id", userID.String()),
+		zap.String("cycle_id", c.ID))
+	s.recomputePredictions(ctx, userID)
+	return c, nil
+}
+
+// GetCycle возвращает цикл пользователя.
+func (s *CycleService) GetCycle(ctx context.Context, userID types.UserID, cycleID string) (*models.Cycle, error) {
+	if _, err := uuid.Parse(cycleID); err != nil {
+		return nil, cycle.ErrCycleNotFound
+	}
+
+	c, err := s.storage.GetCycle(ctx, userID.String(), cycleID)
+	if err != nil {
+		return nil, s.wrapError("get cycle", userID, err)
+	}
+	return c, nil
+}
+
+// ListCycles возвращает циклы пользователя, пересекающиеся с периодом [from, to].
+func (s *CycleService) ListCycles(ctx context.Context, userID types.UserID, from, to models.Date) ([]models.Cycle, error) {
+	cycles, err := s.storage.ListCycles(ctx, userID.String(), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("list cycles: %w", err)
+	}
+	return cycles, nil
+}
+
+// UpdateCycle изменяет даты и заметки цикла.
+func (s *CycleService) UpdateCycle(
+	ctx context.Context,
+	userID types.UserID,
+	cycleID string,
+	req cycle.CycleRequest,
+) (*models.Cycle, error) {
+	return s.modify(ctx, userID, cycleID, "update cycle", req.Today, func(c *models.Cycle) error {
+		c.StartDate = *req.StartDate
+		c.EndDate = req.EndDate
+		c.PeriodEndDate = req.PeriodEndDate
+		c.Notes = req.Notes
+		return nil
+	})
+}
+
+// CloseCycle закрывает текущий цикл датой окончания.
+func (s *CycleService) CloseCycle(
+	ctx context.Context,
+	userID types.UserID,
+	cycleID string,
+	req cycle.CloseRequest,
+) (*models.Cycle, error) {
+	return s.modify(ctx, userID, cycleID, "close cycle", req.Today, func(c *models.Cycle) error {
+		if c.EndDate != nil {
+			return cycle.ErrCycleClosed
+		}
+		c.EndDate = req.EndDate
+		return nil
+	})
+}
+
+// DeleteCycle удаляет цикл, отмеченный пользователем.
+func (s *CycleService) DeleteCycle(ctx context.Context, userID types.UserID, cycleID string) error {
+	if _, err := uuid.Parse(cycleID); err != nil {
+		return cycle.ErrCycleNotFound
+	}
+
+	if err := s.storage.DeleteCycle(ctx, userID.String(), cycleID); err != nil {
+		return s.wrapError("delete cycle", userID, err)
+	}
+	s.recomputePredictions(ctx, userID)
+	return nil
+}
+
+// modify читает цикл, применяет к нему изменения и сохраняет его в одной транзакции.
+func (s *CycleService) modify(
+	ctx context.Context,
+	userID types.UserID,
+	cycleID string,
+	action string,
+	today *models.Date,
+	apply func(c *models.Cycle) error,
+) (*models.Cycle, error) {
+	if _, err := uuid.Parse(cycleID); err != nil {
+		return nil, cycle.ErrCycleNotFound
+	}
+
+	var c *models.Cycle
+	err := s.storage.WithTx(ctx, store.TxOptions{Isolation: store.Serializable}, func(ctx context.Context) error {
+		var err error
+		if c, err = s.storage.GetCycle(ctx, userID.String(), cycleID); err != nil {
+			return err
+		}
+		if c.IsPredicted {
+			return cycle.ErrCycleNotFound
+		}
+		if err := apply(c); err != nil {
+			return err
+		}
+		if err := s.validate(c, today); err != nil {
+			return err
+		}
+		if err := s.checkOverlap(ctx, c); err != nil {
+			return err
+		}
+		return s.storage.UpdateCycle(ctx, c)
+	})
+	if err != nil {
+		return nil, s.wrapError(action, userID, err)
+	}
+	s.recomputePredictions(ctx, userID)
+	return c, nil
+}
+
+// recomputePredictions пересчитывает прогноз после изменения циклов. Сбой прогноза не должен
+// отменять отмеченный пользователем цикл, поэтому ошибка только логируется.
+func (s *CycleService) recomputePredictions(ctx context.Context, userID types.UserID) {
+	if s.predictor == nil {
+		return
+	}
+	if _, err := s.predictor.Recompute(ctx, userID); err != nil && !errors.Is(err, prediction.ErrNoCycles) {
+		zap.L().Named("cycle-service").Error("Failed to recompute predictions",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+	}
+}
+
+// validate проверяет даты цикла. clientToday - текущая дата на устройстве пользователя.
+func (s *CycleService) validate(c *models.Cycle, clientToday *models.Date) error {
+	today, err := s.today(clientToday)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case c.StartDate.After(today.Time):
+		return fmt.Errorf("%w: start date %s is in the future", cycle.ErrInvalidCycle, c.StartDate)
+	case c.EndDate != nil && c.EndDate.Before(c.StartDate.Time):
+		return fmt.Errorf("%w: end date %s is before start date %s", cycle.ErrInvalidCycle, c.EndDate, c.StartDate)
+	case c.EndDate != nil && c.EndDate.After(today.Time):
+		return fmt.Errorf("%w: end date %s is in the future", cycle.ErrInvalidCycle, c.EndDate)
+	case c.PeriodEndDate != nil && c.PeriodEndDate.After(today.Time):
+		return fmt.Errorf("%w: period end date %s is in the future", cycle.ErrInvalidCycle, c.PeriodEndDate)
+	case c.PeriodEndDate != nil && c.PeriodEndDate.Before(c.StartDate.Time):
+		return fmt.Errorf("%w: period end date %s is before start date %s", cycle.ErrInvalidCycle, c.PeriodEndDate, c.StartDate)
+	case c.PeriodEndDate != nil && c.EndDate != nil && c.PeriodEndDate.After(c.EndDate.Time):
+		return fmt.Errorf("%w: period end date %s is after end date %s", cycle.ErrInvalidCycle, c.PeriodEndDate, c.EndDate)
+	}
+	return nil
+}
+
+// today возвращает текущую дату пользователя. Дата с устройства принимается,
+// только если она совпадает с датой UTC в каком-то из часовых поясов.
+func (s *CycleService) today(client *models.Date) (models.Date, error) {
+	now := s.now()
+	if client == nil {
+		return models.DateOf(now), nil
+	}
+	if client.Before(models.DateOf(now.Add(-maxClockBehind)).Time) || client.After(models.DateOf(now.Add(maxClockAhead)).Time) {
+		return models.Date{}, fmt.Errorf("%w: today %s does not match the current date", cycle.ErrInvalidCycle, client)
+	}
+	return *client, nil
+}
+
+func (s *CycleService) checkOverlap(ctx context.Context, c *models.Cycle) error {
+	overlaps, err := s.storage.HasOverlappingCycle(ctx, c.UserUUID, c.StartDate, c.EndDate, c.ID)
+	if err != nil {
+		return fmt.Errorf("check overlapping cycles: %w", err)
+	}
+	if overlaps {
+		return cycle.ErrCycleOverlap
+	}
+	return nil
+}
+
+// wrapError преобразует ошибки хранилища в ошибки API и логирует непредвиденные.
+func (s *CycleService) wrapError(action string, userID types.UserID, err error) error {
+	switch {
+	case errors.Is(err, store.ErrCycleNotFound):
+		return cycle.ErrCycleNotFound
+	case errors.Is(err, cycle.ErrCycleNotFound),
+		errors.Is(err, cycle.ErrCycleOverlap),
+		errors.Is(err, cycle.ErrCycleClosed),
+		errors.Is(err, cycle.ErrInvalidCycle):
+		return err
+	}
+
+	zap.L().Named("cycle-service").Error("Failed to "+action,
+		zap.String("user_id", userID.String()),
+		zap.Error(err))
+	return fmt.Errorf("%s: %w", action, err)
+}
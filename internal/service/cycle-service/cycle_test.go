@@ -0,0 +1,285 @@
+package cycleservice_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fisher-Development/woman-app-backend/api/cycle"
+	"github.com/Fisher-Development/woman-app-backend/api/prediction"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	cycleservice "github.com/Fisher-Development/woman-app-backend/internal/service/cycle-service"
+	"github.com/Fisher-Development/woman-app-backend/internal/store"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+// fakeStorage хранилище циклов в памяти с той же семантикой, что и Postgres.
+type fakeStorage struct {
+	mu     sync.Mutex
+	cycles map[string]models.Cycle
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{cycles: make(map[string]models.Cycle)}
+}
+
+func (f *fakeStorage) CreateCycle(_ context.Context, c *models.Cycle) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c.ID = uuid.NewString()
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+	f.cycles[c.ID] = *c
+	return nil
+}
+
+func (f *fakeStorage) GetCycle(_ context.Context, userUUID, id string) (*models.Cycle, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.cycles[id]
+	if !ok || c.UserUUID != userUUID {
+		return nil, store.ErrCycleNotFound
+	}
+	return &c, nil
+}
+
+func (f *fakeStorage) ListCycles(_ context.Context, userUUID string, from, to models.Date) ([]models.Cycle, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var cycles []models.Cycle
+	for _, c := range f.cycles {
+		if c.UserUUID != userUUID {
+			continue
+		}
+		if !from.IsZero() && c.EndDate != nil && c.EndDate.Before(from.Time) {
+			continue
+		}
+		if !to.IsZero() && c.StartDate.After(to.Time) {
+			continue
+		}
+		cycles = append(cycles, c)
+	}
+	slices.SortFunc(cycles, func(a, b models.Cycle) int { return a.StartDate.Compare(b.StartDate.Time) })
+	return cycles, nil
+}
+
+func (f *fakeStorage) UpdateCycle(_ context.Context, c *models.Cycle) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stored, ok := f.cycles[c.ID]
+	if !ok || stored.UserUUID != c.UserUUID || stored.IsPredicted {
+		return store.ErrCycleNotFound
+	}
+	c.UpdatedAt = time.Now()
+	f.cycles[c.ID] = *c
+	return nil
+}
+
+func (f *fakeStorage) DeleteCycle(_ context.Context, userUUID, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.cycles[id]
+	if !ok || c.UserUUID != userUUID || c.IsPredicted {
+		return store.ErrCycleNotFound
+	}
+	delete(f.cycles, id)
+	return nil
+}
+
+func (f *fakeStorage) HasOverlappingCycle(
+	_ context.Context,
+	userUUID string,
+	start models.Date,
+	end *models.Date,
+	excludeID string,
+) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, c := range f.cycles {
+		if c.UserUUID != userUUID || c.IsPredicted || c.ID == excludeID {
+			continue
+		}
+		if (c.EndDate == nil || !c.EndDate.Before(start.Time)) && (end == nil || !c.StartDate.After(end.Time)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeStorage) WithTx(ctx context.Context, _ store.TxOptions, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// fakePredictor считает пересчеты прогноза по пользователям.
+type fakePredictor struct {
+	mu    sync.Mutex
+	calls map[types.UserID]int
+}
+
+func (p *fakePredictor) Recompute(_ context.Context, userID types.UserID) (*prediction.Forecast, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.calls[userID]++
+	return nil, errors.New("predictions are unavailable")
+}
+
+func TestCycleService(t *testing.T) {
+	ctx := context.Background()
+	storage := newFakeStorage()
+	svc := cycleservice.NewCycleService(storage)
+
+	userID := types.MustParse[types.UserID]("5cb40dc0-a249-4783-a301-9e1f3cf3ea41")
+	otherID := types.MustParse[types.UserID]("f47ac10b-58cc-4372-a567-0e02b2c3d479")
+	today := models.DateOf(time.Now())
+	day := func(offset int) *models.Date {
+		d := today.AddDays(offset)
+		return &d
+	}
+
+	first, err := svc.CreateCycle(ctx, userID, cycle.CycleRequest{
+		StartDate:     day(-60),
+		EndDate:       day(-33),
+		PeriodEndDate: day(-55),
+		Notes:         "first",
+	})
+	require.NoError(t, err)
+	current, err := svc.CreateCycle(ctx, userID, cycle.CycleRequest{StartDate: day(-32), PeriodEndDate: day(-28)})
+	require.NoError(t, err)
+
+	t.Run("validation", func(t *testing.T) {
+		for name, req := range map[string]cycle.CycleRequest{
+			"start in the future":          {StartDate: day(2)},
+			"start tomorrow in UTC":        {StartDate: day(1)},
+			"start after client today":     {StartDate: day(1), Today: day(0)},
+			"client today is not today":    {StartDate: day(-3), Today: day(3)},
+			"period end in the future":     {StartDate: day(-3), PeriodEndDate: day(1)},
+			"end before start":             {StartDate: day(-100), EndDate: day(-101)},
+			"end in the future":            {StartDate: day(-100), EndDate: day(3)},
+			"period end before start":      {StartDate: day(-100), PeriodEndDate: day(-101)},
+			"period end after cycle end":   {StartDate: day(-100), EndDate: day(-80), PeriodEndDate: day(-79)},
+			"period end after cycle end 2": {StartDate: day(-100), EndDate: day(-100), PeriodEndDate: day(-99)},
+		} {
+			_, err := svc.CreateCycle(ctx, userID, req)
+			require.ErrorIs(t, err, cycle.ErrInvalidCycle, name)
+		}
+	})
+
+	t.Run("cycles must not overlap", func(t *testing.T) {
+		for name, req := range map[string]cycle.CycleRequest{
+			"inside closed cycle":   {StartDate: day(-50), EndDate: day(-40)},
+			"touches closed cycle":  {StartDate: day(-70), EndDate: day(-60)},
+			"after open cycle":      {StartDate: day(-1)},
+			"open before all":       {StartDate: day(-70)},
+			"covers closed cycle":   {StartDate: day(-90), EndDate: day(-20)},
+			"ends on current start": {StartDate: day(-40), EndDate: day(-32)},
+		} {
+			_, err := svc.CreateCycle(ctx, userID, req)
+			require.ErrorIs(t, err, cycle.ErrCycleOverlap, name)
+		}
+
+		// Соседний цикл без пересечения и цикл другого пользователя допустимы
+		_, err := svc.CreateCycle(ctx, userID, cycle.CycleRequest{StartDate: day(-90), EndDate: day(-61)})
+		require.NoError(t, err)
+		_, err = svc.CreateCycle(ctx, otherID, cycle.CycleRequest{StartDate: day(-50)})
+		require.NoError(t, err)
+	})
+
+	t.Run("update and close", func(t *testing.T) {
+		updated, err := svc.UpdateCycle(ctx, userID, first.ID, cycle.CycleRequest{
+			StartDate:     day(-60),
+			EndDate:       day(-34),
+			PeriodEndDate: day(-56),
+			Notes:         "edited",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "edited", updated.Notes)
+		assert.Equal(t, *day(-34), *updated.EndDate)
+
+		_, err = svc.UpdateCycle(ctx, userID, first.ID, cycle.CycleRequest{StartDate: day(-60), EndDate: day(-30)})
+		require.ErrorIs(t, err, cycle.ErrCycleOverlap)
+
+		_, err = svc.CloseCycle(ctx, userID, current.ID, cycle.CloseRequest{EndDate: day(-33)})
+		require.ErrorIs(t, err, cycle.ErrInvalidCycle, "end before start")
+
+		_, err = svc.CloseCycle(ctx, userID, current.ID, cycle.CloseRequest{EndDate: day(1), Today: day(0)})
+		require.ErrorIs(t, err, cycle.ErrInvalidCycle, "end after client today")
+
+		closed, err := svc.CloseCycle(ctx, userID, current.ID, cycle.CloseRequest{EndDate: day(-5)})
+		require.NoError(t, err)
+		assert.Equal(t, *day(-5), *closed.EndDate)
+
+		_, err = svc.CloseCycle(ctx, userID, current.ID, cycle.CloseRequest{EndDate: day(-4)})
+		require.ErrorIs(t, err, cycle.ErrCycleClosed)
+
+		// После закрытия можно начать следующий цикл
+		_, err = svc.CreateCycle(ctx, userID, cycle.CycleRequest{StartDate: day(-4)})
+		require.NoError(t, err)
+	})
+
+	t.Run("list by range", func(t *testing.T) {
+		cycles, err := svc.ListCycles(ctx, userID, today.AddDays(-35), today.AddDays(-10))
+		require.NoError(t, err)
+		require.Len(t, cycles, 2)
+		assert.Equal(t, first.ID, cycles[0].ID)
+		assert.Equal(t, current.ID, cycles[1].ID)
+
+		cycles, err = svc.ListCycles(ctx, userID, models.Date{}, models.Date{})
+		require.NoError(t, err)
+		assert.Len(t, cycles, 4)
+	})
+
+	t.Run("cycles are scoped to the user", func(t *testing.T) {
+		_, err := svc.GetCycle(ctx, otherID, first.ID)
+		require.ErrorIs(t, err, cycle.ErrCycleNotFound)
+
+		_, err = svc.UpdateCycle(ctx, otherID, first.ID, cycle.CycleRequest{StartDate: day(-60)})
+		require.ErrorIs(t, err, cycle.ErrCycleNotFound)
+
+		require.ErrorIs(t, svc.DeleteCycle(ctx, otherID, first.ID), cycle.ErrCycleNotFound)
+		require.ErrorIs(t, svc.DeleteCycle(ctx, userID, "not-a-uuid"), cycle.ErrCycleNotFound)
+
+		require.NoError(t, svc.DeleteCycle(ctx, userID, first.ID))
+		_, err = svc.GetCycle(ctx, userID, first.ID)
+		require.ErrorIs(t, err, cycle.ErrCycleNotFound)
+	})
+}
+
+func TestCycleService_RecomputesPredictions(t *testing.T) {
+	ctx := context.Background()
+	predictor := &fakePredictor{calls: make(map[types.UserID]int)}
+	svc := cycleservice.NewCycleService(newFakeStorage(), cycleservice.WithPredictor(predictor))
+
+	userID := types.MustParse[types.UserID]("5cb40dc0-a249-4783-a301-9e1f3cf3ea41")
+	today := models.DateOf(time.Now())
+	start := today.AddDays(-30)
+	end := today.AddDays(-2)
+
+	// Сбой прогноза не мешает отмечать циклы
+	c, err := svc.CreateCycle(ctx, userID, cycle.CycleRequest{StartDate: &start})
+	require.NoError(t, err)
+	_, err = svc.CloseCycle(ctx, userID, c.ID, cycle.CloseRequest{EndDate: &end})
+	require.NoError(t, err)
+	_, err = svc.UpdateCycle(ctx, userID, c.ID, cycle.CycleRequest{StartDate: &start, EndDate: &end, Notes: "note"})
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteCycle(ctx, userID, c.ID))
+
+	// Отклоненные изменения прогноз не пересчитывают
+	_, err = svc.CloseCycle(ctx, userID, c.ID, cycle.CloseRequest{EndDate: &end})
+	require.ErrorIs(t, err, cycle.ErrCycleNotFound)
+
+	assert.Equal(t, 4, predictor.calls[userID])
+}
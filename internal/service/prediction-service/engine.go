@@ -0,0 +1,257 @@
+package predictionservice
+
+import (
+	"math"
+	"slices"
+
+	"github.com/Fisher-Development/woman-app-backend/api/prediction"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+)
+
+const (
+	// historyCycles сколько последних циклов учитывается в прогнозе.
+	historyCycles = 12
+	// minHistory при меньшем числе циклов прогноз дополняется средними значениями из профиля.
+	minHistory = 3
+	// recencyDecay вес каждого следующего (более старого) цикла относительно предыдущего.
+	recencyDecay = 0.8
+	// outlierMADs выброс отличается от медианы больше чем на столько медианных абсолютных отклонений.
+	outlierMADs = 3.0
+	// madToSigma приводит медианное абсолютное отклонение к стандартному отклонению нормального распределения.
+	madToSigma = 1.4826
+
+	// lutealPhaseDays длительность лютеиновой фазы: овуляция наступает за столько дней до менструации.
+	lutealPhaseDays = 14
+	// Фертильное окно: пять дней до овуляции (время жизни сперматозоидов) и день после нее.
+	fertileDaysBefore = 5
+	fertileDaysAfter  = 1
+)
+
+// lengthModel ограничения и значения по умолчанию для одной оцениваемой длительности.
+type lengthModel struct {
+	defaultDays int // используется, если в профиле нет среднего значения
+	minDays     int // более короткие значения считаются ошибкой ввода
+	maxDays     int // более длинные значения считаются ошибкой ввода
+	// minDeviation отклонение от медианы, которое никогда не считается выбросом.
+	minDeviation int
+	// fallbackSpread минимальный разброс прогноза, пока истории мало.
+	fallbackSpread int
+}
+
+var (
+	cycleModel  = lengthModel{defaultDays: 28, minDays: 15, maxDays: 90, minDeviation: 5, fallbackSpread: 3}
+	periodModel = lengthModel{defaultDays: 5, minDays: 1, maxDays: 15, minDeviation: 2, fallbackSpread: 1}
+)
+
+// Defaults средние длительности из профиля пользователя. Ноль означает, что значение не задано.
+type Defaults struct {
+	CycleLength  int
+	PeriodLength int
+}
+
+// estimate оценка длительности по истории.
+type estimate struct {
+	days        int
+	spread      int // полуширина диапазона достоверности в днях
+	samples     int // число учтенных значений истории
+	usedDefault bool
+}
+
+func (e estimate) lengthEstimate(m lengthModel) prediction.LengthEstimate {
+	return prediction.LengthEstimate{
+		Days: e.days,
+		Min:  max(e.days-e.spread, m.minDays),
+		Max:  min(e.days+e.spread, m.maxDays),
+	}
+}
+
+// Predict строит прогноз на horizon циклов вперед по истории циклов пользователя.
+// Прогнозные циклы в history игнорируются. Результат зависит только от аргументов.
+func Predict(history []models.Cycle, defaults Defaults, today models.Date, horizon int) (*prediction.Forecast, error) {
+	cycles := make([]models.Cycle, 0, len(history))
+	for _, c := range history {
+		if !c.IsPredicted {
+			cycles = append(cycles, c)
+		}
+	}
+	if len(cycles) == 0 {
+		return nil, prediction.ErrNoCycles
+	}
+	slices.SortStableFunc(cycles, func(a, b models.Cycle) int { return a.StartDate.Compare(b.StartDate.Time) })
+
+	cycleLengths, periodLengths := lengthSamples(cycles)
+	cycleLen := estimateLength(cycleLengths, defaults.CycleLength, cycleModel)
+	periodLen := estimateLength(periodLengths, defaults.PeriodLength, periodModel)
+
+	forecast := &prediction.Forecast{
+		CycleLength:         cycleLen.lengthEstimate(cycleModel),
+		PeriodLength:        periodLen.lengthEstimate(periodModel),
+		BasedOnCycles:       cycleLen.samples,
+		UsesProfileDefaults: cycleLen.usedDefault || periodLen.usedDefault,
+		Cycles:              make([]prediction.PredictedCycle, 0, horizon),
+	}
+
+	start := nextPeriodStart(cycles[len(cycles)-1], cycleLen.days, today)
+	for k := range horizon {
+		// Неопределенность накапливается с каждым следующим циклом
+		spread := int(math.Round(float64(cycleLen.spread) * math.Sqrt(float64(k+1))))
+		ovulation := start.AddDays(cycleLen.days - lutealPhaseDays)
+
+		forecast.Cycles = append(forecast.Cycles, prediction.PredictedCycle{
+			PeriodStart: start,
+			PeriodStartRange: prediction.DateRange{
+				From: latest(start.AddDays(-spread), today),
+				To:   start.AddDays(spread),
+			},
+			PeriodEnd: start.AddDays(periodLen.days - 1),
+			Ovulation: ovulation,
+			OvulationRange: prediction.DateRange{
+				From: ovulation.AddDays(-spread),
+				To:   ovulation.AddDays(spread),
+			},
+			FertileWindow: prediction.DateRange{
+				From: ovulation.AddDays(-fertileDaysBefore),
+				To:   ovulation.AddDays(fertileDaysAfter),
+			},
+		})
+		start = start.AddDays(cycleLen.days)
+	}
+	return forecast, nil
+}
+
+// PredictedCycles преобразует прогноз в циклы для сохранения с признаком IsPredicted.
+func PredictedCycles(userUUID string, forecast *prediction.Forecast) []models.Cycle {
+	cycles := make([]models.Cycle, 0, len(forecast.Cycles))
+	for _, p := range forecast.Cycles {
+		end := p.PeriodStart.AddDays(forecast.CycleLength.Days - 1)
+		periodEnd := p.PeriodEnd
+		cycles = append(cycles, models.Cycle{
+			UserUUID:      userUUID,
+			StartDate:     p.PeriodStart,
+			EndDate:       &end,
+			PeriodEndDate: &periodEnd,
+			IsPredicted:   true,
+		})
+	}
+	return cycles
+}
+
+// lengthSamples возвращает длительности циклов и менструаций, начиная с самых последних.
+// Длительность цикла берется из даты окончания, а если она не отмечена - из начала следующего цикла.
+func lengthSamples(cycles []models.Cycle) (cycleLengths, periodLengths []int) {
+	for i := len(cycles) - 1; i >= 0; i-- {
+		c := cycles[i]
+		switch {
+		case c.EndDate != nil:
+			cycleLengths = append(cycleLengths, c.EndDate.DaysSince(c.StartDate)+1)
+		case i+1 < len(cycles):
+			cycleLengths = append(cycleLengths, cycles[i+1].StartDate.DaysSince(c.StartDate))
+		}
+		if c.PeriodEndDate != nil {
+			periodLengths = append(periodLengths, c.PeriodEndDate.DaysSince(c.StartDate)+1)
+		}
+	}
+	return cycleLengths, periodLengths
+}
+
+// estimateLength оценивает длительность взвешенным средним: недавние значения весят больше,
+// невозможные значения и выбросы отбрасываются. Пока истории меньше minHistory значений,
+// недостающие значения заменяются средним из профиля (или значением по умолчанию).
+func estimateLength(samples []int, profileDays int, m lengthModel) estimate {
+	samples = slices.DeleteFunc(slices.Clone(samples), func(days int) bool {
+		return days < m.minDays || days > m.maxDays
+	})
+	if len(samples) > historyCycles {
+		samples = samples[:historyCycles]
+	}
+	if len(samples) >= minHistory {
+		samples = rejectOutliers(samples, m)
+	}
+
+	var sum, weightSum float64
+	weight := 1.0
+	for _, days := range samples {
+		sum += weight * float64(days)
+		weightSum += weight
+		weight *= recencyDecay
+	}
+	samplesMean := sum / max(weightSum, 1)
+
+	var variance float64
+	weight = 1.0
+	for _, days := range samples {
+		variance += weight * math.Pow(float64(days)-samplesMean, 2)
+		weight *= recencyDecay
+	}
+	spread := max(int(math.Round(math.Sqrt(variance/max(weightSum, 1)))), 1)
+
+	e := estimate{samples: len(samples)}
+	if missing := minHistory - len(samples); missing > 0 {
+		prior := m.defaultDays
+		if profileDays >= m.minDays && profileDays <= m.maxDays {
+			prior = profileDays
+		}
+		sum += float64(missing * prior)
+		weightSum += float64(missing)
+		spread = max(spread, m.fallbackSpread)
+		e.usedDefault = true
+	}
+
+	e.days = int(math.Round(sum / weightSum))
+	e.spread = spread
+	return e
+}
+
+// rejectOutliers отбрасывает значения, далекие от медианы (по медианному абсолютному отклонению).
+func rejectOutliers(samples []int, m lengthModel) []int {
+	center := median(samples)
+	deviations := make([]float64, len(samples))
+	for i, days := range samples {
+		deviations[i] = math.Abs(float64(days) - center)
+	}
+	limit := max(outlierMADs*madToSigma*medianFloat(deviations), float64(m.minDeviation))
+
+	return slices.DeleteFunc(samples, func(days int) bool {
+		return math.Abs(float64(days)-center) > limit
+	})
+}
+
+// nextPeriodStart возвращает ожидаемое начало следующей менструации после последнего цикла.
+func nextPeriodStart(last models.Cycle, cycleDays int, today models.Date) models.Date {
+	if last.EndDate == nil {
+		// Если задержка, менструация ожидается в любой день, начиная с сегодняшнего
+		return latest(last.StartDate.AddDays(cycleDays), today)
+	}
+
+	// Следующий цикл начинается после закрытого; пропущенные циклы не отмечены
+	next := last.EndDate.AddDays(1)
+	for next.Before(today.Time) {
+		next = next.AddDays(cycleDays)
+	}
+	return next
+}
+
+func latest(a, b models.Date) models.Date {
+	if a.Before(b.Time) {
+		return b
+	}
+	return a
+}
+
+func median(samples []int) float64 {
+	values := make([]float64, len(samples))
+	for i, days := range samples {
+		values[i] = float64(days)
+	}
+	return medianFloat(values)
+}
+
+func medianFloat(values []float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,222 @@
+package predictionservice_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fisher-Development/woman-app-backend/api/prediction"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	predictionservice "github.com/Fisher-Development/woman-app-backend/internal/service/prediction-service"
+)
+
+var base = models.NewDate(2026, 1, 1)
+
+// history строит подряд идущие закрытые циклы заданных длительностей, начиная с start.
+func history(start models.Date, periodDays int, cycleDays ...int) []models.Cycle {
+	cycles := make([]models.Cycle, 0, len(cycleDays))
+	for _, days := range cycleDays {
+		end := start.AddDays(days - 1)
+		periodEnd := start.AddDays(periodDays - 1)
+		cycles = append(cycles, models.Cycle{StartDate: start, EndDate: &end, PeriodEndDate: &periodEnd})
+		start = start.AddDays(days)
+	}
+	return cycles
+}
+
+// open возвращает незакрытый цикл.
+func open(start models.Date, periodDays int) models.Cycle {
+	periodEnd := start.AddDays(periodDays - 1)
+	return models.Cycle{StartDate: start, PeriodEndDate: &periodEnd}
+}
+
+func TestPredict(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		history  []models.Cycle
+		defaults predictionservice.Defaults
+		today    models.Date
+
+		wantCycle    prediction.LengthEstimate
+		wantPeriod   prediction.LengthEstimate
+		wantBasedOn  int
+		wantDefaults bool
+		wantStart    models.Date
+		wantRange    prediction.DateRange
+	}{
+		{
+			name:        "regular history",
+			history:     history(base, 5, 28, 28, 28, 28, 28, 28),
+			today:       base.AddDays(168),
+			wantCycle:   prediction.LengthEstimate{Days: 28, Min: 27, Max: 29},
+			wantPeriod:  prediction.LengthEstimate{Days: 5, Min: 4, Max: 6},
+			wantBasedOn: 6,
+			wantStart:   base.AddDays(168),
+			// Начало диапазона не раньше сегодняшнего дня
+			wantRange: prediction.DateRange{From: base.AddDays(168), To: base.AddDays(169)},
+		},
+		{
+			name: "recent cycles weigh more",
+			// Среднее без весов было бы 28 дней
+			history:     history(base, 5, 26, 26, 26, 30, 30, 30),
+			today:       base.AddDays(168),
+			wantCycle:   prediction.LengthEstimate{Days: 29, Min: 27, Max: 31},
+			wantPeriod:  prediction.LengthEstimate{Days: 5, Min: 4, Max: 6},
+			wantBasedOn: 6,
+			wantStart:   base.AddDays(168),
+			wantRange:   prediction.DateRange{From: base.AddDays(168), To: base.AddDays(170)},
+		},
+		{
+			name: "outlier is rejected",
+			// Цикл в 60 дней - скорее всего пропущенная отметка
+			history:     history(base, 5, 28, 28, 28, 60, 28),
+			today:       base.AddDays(172),
+			wantCycle:   prediction.LengthEstimate{Days: 28, Min: 27, Max: 29},
+			wantPeriod:  prediction.LengthEstimate{Days: 5, Min: 4, Max: 6},
+			wantBasedOn: 4,
+			wantStart:   base.AddDays(172),
+			wantRange:   prediction.DateRange{From: base.AddDays(172), To: base.AddDays(173)},
+		},
+		{
+			name:         "implausible lengths are ignored",
+			history:      history(base, 20, 10, 28, 120),
+			today:        base.AddDays(158),
+			wantCycle:    prediction.LengthEstimate{Days: 28, Min: 25, Max: 31},
+			wantPeriod:   prediction.LengthEstimate{Days: 5, Min: 4, Max: 6},
+			wantBasedOn:  1,
+			wantDefaults: true,
+			wantStart:    base.AddDays(158),
+			wantRange:    prediction.DateRange{From: base.AddDays(158), To: base.AddDays(161)},
+		},
+		{
+			name:         "short history is blended with profile averages",
+			history:      history(base, 6, 32),
+			defaults:     predictionservice.Defaults{CycleLength: 30, PeriodLength: 4},
+			today:        base.AddDays(32),
+			wantCycle:    prediction.LengthEstimate{Days: 31, Min: 28, Max: 34},
+			wantPeriod:   prediction.LengthEstimate{Days: 5, Min: 4, Max: 6},
+			wantBasedOn:  1,
+			wantDefaults: true,
+			wantStart:    base.AddDays(32),
+			wantRange:    prediction.DateRange{From: base.AddDays(32), To: base.AddDays(35)},
+		},
+		{
+			name:         "no history uses profile averages",
+			history:      []models.Cycle{open(base, 4)},
+			defaults:     predictionservice.Defaults{CycleLength: 33, PeriodLength: 4},
+			today:        base.AddDays(10),
+			wantCycle:    prediction.LengthEstimate{Days: 33, Min: 30, Max: 36},
+			wantPeriod:   prediction.LengthEstimate{Days: 4, Min: 3, Max: 5},
+			wantBasedOn:  0,
+			wantDefaults: true,
+			wantStart:    base.AddDays(33),
+			wantRange:    prediction.DateRange{From: base.AddDays(30), To: base.AddDays(36)},
+		},
+		{
+			name:         "implausible profile averages are replaced by defaults",
+			history:      []models.Cycle{open(base, 5)},
+			defaults:     predictionservice.Defaults{CycleLength: 200, PeriodLength: 40},
+			today:        base,
+			wantCycle:    prediction.LengthEstimate{Days: 28, Min: 25, Max: 31},
+			wantPeriod:   prediction.LengthEstimate{Days: 5, Min: 4, Max: 6},
+			wantDefaults: true,
+			wantStart:    base.AddDays(28),
+			wantRange:    prediction.DateRange{From: base.AddDays(25), To: base.AddDays(31)},
+		},
+		{
+			name:        "late period is expected from today",
+			history:     append(history(base.AddDays(-84), 5, 28, 28, 28), open(base, 5)),
+			today:       base.AddDays(35),
+			wantCycle:   prediction.LengthEstimate{Days: 28, Min: 27, Max: 29},
+			wantPeriod:  prediction.LengthEstimate{Days: 5, Min: 4, Max: 6},
+			wantBasedOn: 3,
+			wantStart:   base.AddDays(35),
+			wantRange:   prediction.DateRange{From: base.AddDays(35), To: base.AddDays(36)},
+		},
+		{
+			name:        "unrecorded cycles are skipped",
+			history:     history(base, 5, 28, 28, 28),
+			today:       base.AddDays(130),
+			wantCycle:   prediction.LengthEstimate{Days: 28, Min: 27, Max: 29},
+			wantPeriod:  prediction.LengthEstimate{Days: 5, Min: 4, Max: 6},
+			wantBasedOn: 3,
+			wantStart:   base.AddDays(140),
+			wantRange:   prediction.DateRange{From: base.AddDays(139), To: base.AddDays(141)},
+		},
+		{
+			name: "predicted cycles are ignored",
+			history: append(history(base, 5, 28, 28, 28), models.Cycle{
+				StartDate:   base.AddDays(84),
+				IsPredicted: true,
+			}),
+			today:       base.AddDays(80),
+			wantCycle:   prediction.LengthEstimate{Days: 28, Min: 27, Max: 29},
+			wantPeriod:  prediction.LengthEstimate{Days: 5, Min: 4, Max: 6},
+			wantBasedOn: 3,
+			wantStart:   base.AddDays(84),
+			wantRange:   prediction.DateRange{From: base.AddDays(83), To: base.AddDays(85)},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			forecast, err := predictionservice.Predict(tt.history, tt.defaults, tt.today, 3)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantCycle, forecast.CycleLength)
+			assert.Equal(t, tt.wantPeriod, forecast.PeriodLength)
+			assert.Equal(t, tt.wantBasedOn, forecast.BasedOnCycles)
+			assert.Equal(t, tt.wantDefaults, forecast.UsesProfileDefaults)
+			require.Len(t, forecast.Cycles, 3)
+			assert.Equal(t, tt.wantStart.String(), forecast.Cycles[0].PeriodStart.String())
+			assert.Equal(t, tt.wantRange.From.String(), forecast.Cycles[0].PeriodStartRange.From.String())
+			assert.Equal(t, tt.wantRange.To.String(), forecast.Cycles[0].PeriodStartRange.To.String())
+		})
+	}
+}
+
+func TestPredict_Phases(t *testing.T) {
+	today := base.AddDays(168)
+	forecast, err := predictionservice.Predict(history(base, 5, 28, 28, 28, 28, 28, 28), predictionservice.Defaults{}, today, 3)
+	require.NoError(t, err)
+
+	for i, want := range []struct {
+		start, periodEnd, ovulation  int
+		spread                       int
+		fertileFrom, fertileTo       int
+		startRangeFrom, startRangeTo int
+	}{
+		{start: 168, periodEnd: 172, ovulation: 182, spread: 1, fertileFrom: 177, fertileTo: 183, startRangeFrom: 168, startRangeTo: 169},
+		{start: 196, periodEnd: 200, ovulation: 210, spread: 1, fertileFrom: 205, fertileTo: 211, startRangeFrom: 195, startRangeTo: 197},
+		// Неопределенность растет с удаленностью прогноза
+		{start: 224, periodEnd: 228, ovulation: 238, spread: 2, fertileFrom: 233, fertileTo: 239, startRangeFrom: 222, startRangeTo: 226},
+	} {
+		got := forecast.Cycles[i]
+		assert.Equal(t, base.AddDays(want.start).String(), got.PeriodStart.String(), "cycle %d", i)
+		assert.Equal(t, base.AddDays(want.startRangeFrom).String(), got.PeriodStartRange.From.String(), "cycle %d", i)
+		assert.Equal(t, base.AddDays(want.startRangeTo).String(), got.PeriodStartRange.To.String(), "cycle %d", i)
+		assert.Equal(t, base.AddDays(want.periodEnd).String(), got.PeriodEnd.String(), "cycle %d", i)
+		assert.Equal(t, base.AddDays(want.ovulation).String(), got.Ovulation.String(), "cycle %d", i)
+		assert.Equal(t, base.AddDays(want.ovulation-want.spread).String(), got.OvulationRange.From.String(), "cycle %d", i)
+		assert.Equal(t, base.AddDays(want.ovulation+want.spread).String(), got.OvulationRange.To.String(), "cycle %d", i)
+		assert.Equal(t, base.AddDays(want.fertileFrom).String(), got.FertileWindow.From.String(), "cycle %d", i)
+		assert.Equal(t, base.AddDays(want.fertileTo).String(), got.FertileWindow.To.String(), "cycle %d", i)
+	}
+
+	cycles := predictionservice.PredictedCycles("user", forecast)
+	require.Len(t, cycles, 3)
+	for i, c := range cycles {
+		assert.True(t, c.IsPredicted)
+		assert.Equal(t, "user", c.UserUUID)
+		assert.Equal(t, forecast.Cycles[i].PeriodStart, c.StartDate)
+		assert.Equal(t, c.StartDate.AddDays(27), *c.EndDate)
+		assert.Equal(t, forecast.Cycles[i].PeriodEnd, *c.PeriodEndDate)
+	}
+}
+
+func TestPredict_NoCycles(t *testing.T) {
+	_, err := predictionservice.Predict(nil, predictionservice.Defaults{CycleLength: 28}, base, 3)
+	require.ErrorIs(t, err, prediction.ErrNoCycles)
+
+	_, err = predictionservice.Predict([]models.Cycle{{StartDate: base, IsPredicted: true}}, predictionservice.Defaults{}, base, 3)
+	require.ErrorIs(t, err, prediction.ErrNoCycles)
+}
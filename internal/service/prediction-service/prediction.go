@@ -0,0 +1,106 @@
+package predictionservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Fisher-Development/woman-app-backend/api/prediction"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	"github.com/Fisher-Development/woman-app-backend/internal/store"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+// predictedCycles на сколько циклов вперед строится прогноз.
+const predictedCycles = 3
+
+// Storage методы хранилища, которые использует сервис прогнозов.
+type Storage interface {
+	ListCycles(ctx context.Context, userUUID string, from, to models.Date) ([]models.Cycle, error)
+	GetCycleAverages(ctx context.Context, userUUID string) (cycleLength, periodLength int, err error)
+	ReplacePredictedCycles(ctx context.Context, userUUID string, cycles []models.Cycle) error
+	WithTx(ctx context.Context, opts store.TxOptions, fn func(ctx context.Context) error) error
+}
+
+// PredictionService строит прогноз циклов по истории пользователя
+// и хранит его в виде прогнозных циклов (is_predicted).
+type PredictionService struct {
+	storage Storage
+	now     func() time.Time
+}
+
+// NewPredictionService создает новый PredictionService.
+func NewPredictionService(storage Storage) *PredictionService {
+	return &PredictionService{
+		storage: storage,
+		now:     time.Now,
+	}
+}
+
+// Forecast возвращает прогноз циклов пользователя, не изменяя сохраненные прогнозные циклы.
+func (s *PredictionService) Forecast(ctx context.Context, userID types.UserID) (*prediction.Forecast, error) {
+	forecast, err := s.forecast(ctx, userID)
+	if err != nil {
+		return nil, s.wrapError("forecast cycles", userID, err)
+	}
+	return forecast, nil
+}
+
+// Recompute пересчитывает прогноз и заменяет им прогнозные циклы пользователя.
+// Если отмеченных циклов не осталось, прогнозные циклы удаляются.
+func (s *PredictionService) Recompute(ctx context.Context, userID types.UserID) (*prediction.Forecast, error) {
+	var forecast *prediction.Forecast
+	err := s.storage.WithTx(ctx, store.TxOptions{Isolation: store.Serializable}, func(ctx context.Context) error {
+		var err error
+		forecast, err = s.forecast(ctx, userID)
+		if errors.Is(err, prediction.ErrNoCycles) {
+			// Удаление прогноза должно зафиксироваться, поэтому ошибка возвращается после транзакции
+			forecast = nil
+			return s.storage.ReplacePredictedCycles(ctx, userID.String(), nil)
+		}
+		if err != nil {
+			return err
+		}
+		return s.storage.ReplacePredictedCycles(ctx, userID.String(), PredictedCycles(userID.String(), forecast))
+	})
+	if err != nil {
+		return nil, s.wrapError("recompute predictions", userID, err)
+	}
+	if forecast == nil {
+		return nil, prediction.ErrNoCycles
+	}
+
+	zap.L().Named("prediction-service").Debug("Predictions recomputed",
+		zap.String("user_id", userID.String()),
+		zap.Int("based_on_cycles", forecast.BasedOnCycles))
+	return forecast, nil
+}
+
+func (s *PredictionService) forecast(ctx context.Context, userID types.UserID) (*prediction.Forecast, error) {
+	history, err := s.storage.ListCycles(ctx, userID.String(), models.Date{}, models.Date{})
+	if err != nil {
+		return nil, fmt.Errorf("list cycles: %w", err)
+	}
+	cycleLength, periodLength, err := s.storage.GetCycleAverages(ctx, userID.String())
+	if err != nil {
+		return nil, fmt.Errorf("get cycle averages: %w", err)
+	}
+
+	defaults := Defaults{CycleLength: cycleLength, PeriodLength: periodLength}
+	return Predict(history, defaults, models.DateOf(s.now()), predictedCycles)
+}
+
+// wrapError логирует непредвиденные ошибки.
+func (s *PredictionService) wrapError(action string, userID types.UserID, err error) error {
+	if errors.Is(err, prediction.ErrNoCycles) {
+		return err
+	}
+
+	zap.L().Named("prediction-service").Error("Failed to "+action,
+		zap.String("user_id", userID.String()),
+		zap.Error(err))
+	return fmt.Errorf("%s: %w", action, err)
+}
@@ -0,0 +1,117 @@
+package predictionservice_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fisher-Development/woman-app-backend/api/prediction"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	predictionservice "github.com/Fisher-Development/woman-app-backend/internal/service/prediction-service"
+	"github.com/Fisher-Development/woman-app-backend/internal/store"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+type fakeStorage struct {
+	cycles       []models.Cycle
+	cycleLength  int
+	periodLength int
+	failReplace  bool
+}
+
+func (f *fakeStorage) ListCycles(_ context.Context, _ string, _, _ models.Date) ([]models.Cycle, error) {
+	return f.cycles, nil
+}
+
+func (f *fakeStorage) GetCycleAverages(_ context.Context, _ string) (int, int, error) {
+	return f.cycleLength, f.periodLength, nil
+}
+
+func (f *fakeStorage) ReplacePredictedCycles(_ context.Context, userUUID string, cycles []models.Cycle) error {
+	if f.failReplace {
+		return errors.New("replace failed")
+	}
+
+	var kept []models.Cycle
+	for _, c := range f.cycles {
+		if !c.IsPredicted {
+			kept = append(kept, c)
+		}
+	}
+	for _, c := range cycles {
+		c.UserUUID = userUUID
+		c.IsPredicted = true
+		kept = append(kept, c)
+	}
+	f.cycles = kept
+	return nil
+}
+
+func (f *fakeStorage) WithTx(ctx context.Context, _ store.TxOptions, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func predicted(cycles []models.Cycle) []models.Cycle {
+	var result []models.Cycle
+	for _, c := range cycles {
+		if c.IsPredicted {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func TestPredictionService(t *testing.T) {
+	ctx := context.Background()
+	userID := types.MustParse[types.UserID]("5cb40dc0-a249-4783-a301-9e1f3cf3ea41")
+	today := models.DateOf(time.Now())
+
+	storage := &fakeStorage{
+		cycles:      history(today.AddDays(-84), 5, 28, 28, 28),
+		cycleLength: 30,
+	}
+	svc := predictionservice.NewPredictionService(storage)
+
+	t.Run("forecast does not materialise cycles", func(t *testing.T) {
+		forecast, err := svc.Forecast(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, 28, forecast.CycleLength.Days)
+		assert.Equal(t, today.String(), forecast.Cycles[0].PeriodStart.String())
+		assert.Empty(t, predicted(storage.cycles))
+	})
+
+	t.Run("recompute replaces predicted cycles", func(t *testing.T) {
+		_, err := svc.Recompute(ctx, userID)
+		require.NoError(t, err)
+		first := predicted(storage.cycles)
+		require.Len(t, first, 3)
+		assert.Equal(t, today.String(), first[0].StartDate.String())
+
+		// Новый отмеченный цикл сдвигает прогноз, старые прогнозные циклы не накапливаются
+		storage.cycles = append(storage.cycles, history(today, 1, 1)...)
+		_, err = svc.Recompute(ctx, userID)
+		require.NoError(t, err)
+		second := predicted(storage.cycles)
+		require.Len(t, second, 3)
+		assert.Equal(t, today.AddDays(1).String(), second[0].StartDate.String())
+	})
+
+	t.Run("recompute without cycles removes predictions", func(t *testing.T) {
+		storage.cycles = predicted(storage.cycles)
+		_, err := svc.Recompute(ctx, userID)
+		require.ErrorIs(t, err, prediction.ErrNoCycles)
+		assert.Empty(t, storage.cycles)
+	})
+
+	t.Run("storage failure", func(t *testing.T) {
+		storage.cycles = history(today.AddDays(-28), 5, 28)
+		storage.failReplace = true
+		_, err := svc.Recompute(ctx, userID)
+		require.Error(t, err)
+		require.NotErrorIs(t, err, prediction.ErrNoCycles)
+	})
+}
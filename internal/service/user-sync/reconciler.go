@@ -0,0 +1,294 @@
+package usersync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+)
+
+const (
+	keycloakPageSize = 100
+	localPageSize    = 500
+
+	// serviceAccountPrefix префикс служебных пользователей клиентов Keycloak.
+	serviceAccountPrefix = "service-account-"
+)
+
+// Типы действий сверки.
+const (
+	ActionCreated   = "created"
+	ActionUpdated   = "updated"
+	ActionFlagged   = "flagged_missing"
+	ActionUnflagged = "unflagged_missing"
+	ActionSkipped   = "skipped"
+	ActionFailed    = "failed"
+)
+
+// KeycloakUsers источник пользователей Keycloak.
+type KeycloakUsers interface {
+	ListUsers(ctx context.Context, first, limit int) ([]keycloakclient.UserRepresentation, error)
+}
+
+// Storage методы хранилища, которые использует сверка.
+type Storage interface {
+	ListUsers(ctx context.Context, afterUUID string, limit int) ([]models.User, error)
+	CreateUser(ctx context.Context, user *models.User) error
+	SyncUserIdentity(ctx context.Context, user *models.User) error
+	MarkKeycloakMissing(ctx context.Context, uuid string, missing bool) error
+}
+
+// Action одно действие сверки.
+type Action struct {
+	Type    string `json:"type"`
+	UserID  string `json:"userId"`
+	Email   string `json:"email"`
+	Details string `json:"details,omitempty"`
+}
+
+// Report итог одного прохода сверки.
+type Report struct {
+	StartedAt     time.Time     `json:"startedAt"`
+	Duration      time.Duration `json:"duration"`
+	DryRun        bool          `json:"dryRun"`
+	KeycloakUsers int           `json:"keycloakUsers"`
+	LocalUsers    int           `json:"localUsers"`
+	Created       int           `json:"created"`
+	Updated       int           `json:"updated"`
+	Flagged       int           `json:"flagged"`
+	Unflagged     int           `json:"unflagged"`
+	Skipped       int           `json:"skipped"`
+	Failed        int           `json:"failed"`
+	Actions       []Action      `json:"actions"`
+}
+
+// Reconciler сверяет пользователей Keycloak с таблицей users.
+type Reconciler struct {
+	keycloak KeycloakUsers
+	storage  Storage
+	dryRun   bool
+	logger   *zap.Logger
+}
+
+// NewReconciler создает сверку. В режиме dryRun изменения только логируются.
+func NewReconciler(keycloak KeycloakUsers, storage Storage, dryRun bool) *Reconciler {
+	return &Reconciler{
+		keycloak: keycloak,
+		storage:  storage,
+		dryRun:   dryRun,
+		logger:   zap.L().Named("user-sync"),
+	}
+}
+
+// Run выполняет сверку с заданным интервалом до отмены контекста.
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := r.Reconcile(ctx); err != nil {
+				r.logger.Error("User reconciliation failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Reconcile выполняет один проход сверки:
+// - создает локальные записи для пользователей, которые есть только в Keycloak;
+// - обновляет email и имя, если они изменились в Keycloak;
+// - помечает локальных пользователей, которых нет в Keycloak.
+func (r *Reconciler) Reconcile(ctx context.Context) (*Report, error) {
+	report := &Report{StartedAt: time.Now(), DryRun: r.dryRun}
+
+	remote, err := r.loadKeycloakUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.KeycloakUsers = len(remote)
+
+	afterUUID := ""
+	for {
+		page, err := r.storage.ListUsers(ctx, afterUUID, localPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("list local users: %w", err)
+		}
+		for _, local := range page {
+			report.LocalUsers++
+
+			kcUser, ok := remote[local.UUID]
+			if !ok {
+				r.flagMissing(ctx, report, local)
+				continue
+			}
+			// Пользователь обработан, оставшиеся в remote отсутствуют локально
+			delete(remote, local.UUID)
+
+			r.syncExisting(ctx, report, local, kcUser)
+		}
+
+		if len(page) < localPageSize {
+			break
+		}
+		afterUUID = page[len(page)-1].UUID
+	}
+
+	for _, kcUser := range remote {
+		r.createMissing(ctx, report, kcUser)
+	}
+
+	report.Duration = time.Since(report.StartedAt)
+	r.logger.Info("User reconciliation finished",
+		zap.Bool("dry_run", report.DryRun),
+		zap.Int("keycloak_users", report.KeycloakUsers),
+		zap.Int("local_users", report.LocalUsers),
+		zap.Int("created", report.Created),
+		zap.Int("updated", report.Updated),
+		zap.Int("flagged", report.Flagged),
+		zap.Int("unflagged", report.Unflagged),
+		zap.Int("skipped", report.Skipped),
+		zap.Int("failed", report.Failed),
+		zap.Duration("duration", report.Duration))
+
+	return report, nil
+}
+
+// loadKeycloakUsers постранично загружает всех пользователей realm.
+func (r *Reconciler) loadKeycloakUsers(ctx context.Context) (map[string]keycloakclient.UserRepresentation, error) {
+	all, err := keycloakclient.ListAll(ctx, keycloakPageSize, r.keycloak.ListUsers)
+	if err != nil {
+		return nil, fmt.Errorf("list keycloak users: %w", err)
+	}
+
+	users := make(map[string]keycloakclient.UserRepresentation, len(all))
+	for _, user := range all {
+		users[user.ID] = user
+	}
+	return users, nil
+}
+
+func (r *Reconciler) flagMissing(ctx context.Context, report *Report, local models.User) {
+	if local.KeycloakMissingSince != nil {
+		return // уже помечен
+	}
+	if !r.dryRun {
+		if err := r.storage.MarkKeycloakMissing(ctx, local.UUID, true); err != nil {
+			r.fail(report, local.UUID, local.Email, fmt.Errorf("flag missing: %w", err))
+			return
+		}
+	}
+	report.Flagged++
+	r.record(report, Action{Type: ActionFlagged, UserID: local.UUID, Email: local.Email})
+}
+
+func (r *Reconciler) syncExisting(
+	ctx context.Context,
+	report *Report,
+	local models.User,
+	kcUser keycloakclient.UserRepresentation,
+) {
+	if local.KeycloakMissingSince != nil {
+		if !r.dryRun {
+			if err := r.storage.MarkKeycloakMissing(ctx, local.UUID, false); err != nil {
+				r.fail(report, local.UUID, local.Email, fmt.Errorf("unflag missing: %w", err))
+				return
+			}
+		}
+		report.Unflagged++
+		r.record(report, Action{Type: ActionUnflagged, UserID: local.UUID, Email: local.Email})
+	}
+
+	var changes []string
+	if kcUser.Email != "" && !strings.EqualFold(kcUser.Email, local.Email) {
+		changes = append(changes, fmt.Sprintf("email %q -> %q", local.Email, kcUser.Email))
+	}
+	if kcUser.FirstName != local.FirstName {
+		changes = append(changes, fmt.Sprintf("firstName %q -> %q", local.FirstName, kcUser.FirstName))
+	}
+	if kcUser.LastName != local.LastName {
+		changes = append(changes, fmt.Sprintf("lastName %q -> %q", local.LastName, kcUser.LastName))
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	updated := models.User{
+		UUID:      local.UUID,
+		Email:     local.Email,
+		FirstName: kcUser.FirstName,
+		LastName:  kcUser.LastName,
+	}
+	if kcUser.Email != "" {
+		updated.Email = kcUser.Email
+	}
+	if !r.dryRun {
+		if err := r.storage.SyncUserIdentity(ctx, &updated); err != nil {
+			r.fail(report, local.UUID, local.Email, fmt.Errorf("sync identity: %w", err))
+			return
+		}
+	}
+	report.Updated++
+	r.record(report, Action{
+		Type:    ActionUpdated,
+		UserID:  local.UUID,
+		Email:   updated.Email,
+		Details: strings.Join(changes, "; "),
+	})
+}
+
+func (r *Reconciler) createMissing(ctx context.Context, report *Report, kcUser keycloakclient.UserRepresentation) {
+	if kcUser.Email == "" {
+		reason := "no email"
+		if strings.HasPrefix(kcUser.Username, serviceAccountPrefix) {
+			reason = "service account"
+		}
+		report.Skipped++
+		r.record(report, Action{Type: ActionSkipped, UserID: kcUser.ID, Details: reason})
+		return
+	}
+
+	user := &models.User{
+		UUID:      kcUser.ID,
+		Email:     kcUser.Email,
+		FirstName: kcUser.FirstName,
+		LastName:  kcUser.LastName,
+	}
+	if !r.dryRun {
+		if err := r.storage.CreateUser(ctx, user); err != nil {
+			r.fail(report, kcUser.ID, kcUser.Email, fmt.Errorf("create user: %w", err))
+			return
+		}
+	}
+	report.Created++
+	r.record(report, Action{Type: ActionCreated, UserID: user.UUID, Email: user.Email})
+}
+
+func (r *Reconciler) fail(report *Report, userID, email string, err error) {
+	report.Failed++
+	r.record(report, Action{Type: ActionFailed, UserID: userID, Email: email, Details: err.Error()})
+}
+
+func (r *Reconciler) record(report *Report, action Action) {
+	report.Actions = append(report.Actions, action)
+
+	fields := []zap.Field{
+		zap.String("action", action.Type),
+		zap.String("user_id", action.UserID),
+		zap.String("email", action.Email),
+		zap.String("details", action.Details),
+		zap.Bool("dry_run", r.dryRun),
+	}
+	if action.Type == ActionFailed {
+		r.logger.Error("User reconciliation action failed", fields...)
+		return
+	}
+	r.logger.Info("User reconciliation action", fields...)
+}
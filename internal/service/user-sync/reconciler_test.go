@@ -0,0 +1,151 @@
+package usersync_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	usersync "github.com/Fisher-Development/woman-app-backend/internal/service/user-sync"
+	"github.com/Fisher-Development/woman-app-backend/internal/store"
+)
+
+const (
+	aliceID   = "00000000-0000-0000-0000-00000000000a"
+	bobID     = "00000000-0000-0000-0000-00000000000b"
+	carolID   = "00000000-0000-0000-0000-00000000000c"
+	daveID    = "00000000-0000-0000-0000-00000000000d"
+	serviceID = "00000000-0000-0000-0000-00000000000e"
+)
+
+type fakeKeycloak struct {
+	users []keycloakclient.UserRepresentation
+}
+
+func (f *fakeKeycloak) ListUsers(_ context.Context, first, limit int) ([]keycloakclient.UserRepresentation, error) {
+	if first >= len(f.users) {
+		return nil, nil
+	}
+	return f.users[first:min(first+limit, len(f.users))], nil
+}
+
+type fakeStorage struct {
+	users map[string]models.User
+}
+
+func (f *fakeStorage) ListUsers(_ context.Context, afterUUID string, limit int) ([]models.User, error) {
+	ids := make([]string, 0, len(f.users))
+	for id := range f.users {
+		if id > afterUUID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var page []models.User
+	for _, id := range ids[:min(limit, len(ids))] {
+		page = append(page, f.users[id])
+	}
+	return page, nil
+}
+
+func (f *fakeStorage) CreateUser(_ context.Context, user *models.User) error {
+	if _, ok := f.users[user.UUID]; ok {
+		return store.ErrUserAlreadyExists
+	}
+	f.users[user.UUID] = *user
+	return nil
+}
+
+func (f *fakeStorage) SyncUserIdentity(_ context.Context, user *models.User) error {
+	local, ok := f.users[user.UUID]
+	if !ok {
+		return store.ErrUserNotFound
+	}
+	local.Email, local.FirstName, local.LastName = user.Email, user.FirstName, user.LastName
+	f.users[user.UUID] = local
+	return nil
+}
+
+func (f *fakeStorage) MarkKeycloakMissing(_ context.Context, uuid string, missing bool) error {
+	local := f.users[uuid]
+	local.KeycloakMissingSince = nil
+	if missing {
+		now := time.Now()
+		local.KeycloakMissingSince = &now
+	}
+	f.users[uuid] = local
+	return nil
+}
+
+func newFixtures() (*fakeKeycloak, *fakeStorage) {
+	missingSince := time.Now().Add(-time.Hour)
+
+	kc := &fakeKeycloak{users: []keycloakclient.UserRepresentation{
+		// Совпадает с локальной записью
+		{ID: aliceID, Email: "alice@example.com", FirstName: "Alice"},
+		// Изменил email и фамилию в Keycloak
+		{ID: bobID, Email: "bob.new@example.com", FirstName: "Bob", LastName: "Smith"},
+		// Создан через админку Keycloak, локально отсутствует
+		{ID: carolID, Email: "carol@example.com", FirstName: "Carol"},
+		// Служебный пользователь клиента
+		{ID: serviceID, Username: "service-account-back-end"},
+	}}
+	storage := &fakeStorage{users: map[string]models.User{
+		aliceID: {UUID: aliceID, Email: "alice@example.com", FirstName: "Alice", KeycloakMissingSince: &missingSince},
+		bobID:   {UUID: bobID, Email: "bob@example.com", FirstName: "Bob"},
+		// Удален из Keycloak
+		daveID: {UUID: daveID, Email: "dave@example.com"},
+	}}
+	return kc, storage
+}
+
+func TestReconciler_Reconcile(t *testing.T) {
+	kc, storage := newFixtures()
+
+	report, err := usersync.NewReconciler(kc, storage, false).Reconcile(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, report.KeycloakUsers)
+	assert.Equal(t, 3, report.LocalUsers)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 1, report.Flagged)
+	assert.Equal(t, 1, report.Unflagged)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, 0, report.Failed)
+	assert.Len(t, report.Actions, 5)
+
+	assert.Nil(t, storage.users[aliceID].KeycloakMissingSince)
+	assert.Equal(t, "bob.new@example.com", storage.users[bobID].Email)
+	assert.Equal(t, "Smith", storage.users[bobID].LastName)
+	assert.Equal(t, "carol@example.com", storage.users[carolID].Email)
+	assert.NotNil(t, storage.users[daveID].KeycloakMissingSince)
+	assert.NotContains(t, storage.users, serviceID)
+
+	// Повторный проход ничего не меняет
+	report, err = usersync.NewReconciler(kc, storage, false).Reconcile(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Created+report.Updated+report.Flagged+report.Unflagged)
+}
+
+func TestReconciler_DryRun(t *testing.T) {
+	kc, storage := newFixtures()
+
+	report, err := usersync.NewReconciler(kc, storage, true).Reconcile(context.Background())
+	require.NoError(t, err)
+
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 1, report.Flagged)
+
+	assert.Len(t, storage.users, 3)
+	assert.Equal(t, "bob@example.com", storage.users[bobID].Email)
+	assert.Nil(t, storage.users[daveID].KeycloakMissingSince)
+}
@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+const apiKeyColumns = `
+			id,
+			user_id,
+			name,
+			prefix,
+			key_hash,
+			scopes,
+			expires_at,
+			last_used_at,
+			revoked_at,
+			created_at`
+
+// CreateAPIKey сохраняет новый API ключ.
+func (s *Storage) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	return s.conn(ctx).QueryRow(
+		ctx,
+		query,
+		key.UserUUID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		key.Scopes,
+		utcOrNil(key.ExpiresAt),
+	).Scan(&key.ID, &key.CreatedAt)
+}
+
+// GetAPIKeyByPrefix возвращает API ключ (в том числе отозванный) по открытой части.
+func (s *Storage) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	query := `
+		SELECT` + apiKeyColumns + `
+		FROM api_keys
+		WHERE prefix = $1
+	`
+	key, err := scanAPIKey(s.conn(ctx).QueryRow(ctx, query, prefix))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+// ListAPIKeys возвращает действующие (не отозванные) API ключи пользователя.
+func (s *Storage) ListAPIKeys(ctx context.Context, userUUID string) ([]models.APIKey, error) {
+	query := `
+		SELECT` + apiKeyColumns + `
+		FROM api_keys
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at
+	`
+	rows, err := s.conn(ctx).Query(ctx, query, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// UpdateAPIKeyScopes меняет роли действующего API ключа пользователя.
+func (s *Storage) UpdateAPIKeyScopes(ctx context.Context, userUUID, id string, scopes []string) (*models.APIKey, error) {
+	query := `
+		UPDATE api_keys
+		SET scopes = $1
+		WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL
+		RETURNING` + apiKeyColumns + `
+	`
+	key, err := scanAPIKey(s.conn(ctx).QueryRow(ctx, query, scopes, id, userUUID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+// RevokeAPIKey отзывает действующий API ключ пользователя.
+func (s *Storage) RevokeAPIKey(ctx context.Context, userUUID, id string, at time.Time) error {
+	query := `
+		UPDATE api_keys
+		SET revoked_at = $1
+		WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL
+	`
+	tag, err := s.conn(ctx).Exec(ctx, query, at.UTC(), id, userUUID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey записывает время последнего использования API ключа.
+func (s *Storage) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	query := `
+		UPDATE api_keys
+		SET last_used_at = $1
+		WHERE id = $2
+	`
+	_, err := s.conn(ctx).Exec(ctx, query, at.UTC(), id)
+	return err
+}
+
+func scanAPIKey(row pgx.Row) (*models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(
+		&key.ID,
+		&key.UserUUID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&key.Scopes,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func utcOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
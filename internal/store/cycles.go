@@ -0,0 +1,243 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+)
+
+var ErrCycleNotFound = errors.New("cycle not found")
+
+const cycleColumns = `
+			id,
+			user_id,
+			start_date,
+			end_date,
+			period_end_date,
+			notes,
+			is_predicted,
+			created_at,
+			updated_at`
+
+// CreateCycle сохраняет новый цикл пользователя.
+func (s *Storage) CreateCycle(ctx context.Context, cycle *models.Cycle) error {
+	query := `
+		INSERT INTO menstrual_cycles (user_id, start_date, end_date, period_end_date, notes, is_predicted)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+	return s.conn(ctx).QueryRow(
+		ctx,
+		query,
+		cycle.UserUUID,
+		cycle.StartDate.Time,
+		dateOrNil(cycle.EndDate),
+		dateOrNil(cycle.PeriodEndDate),
+		nilIfEmpty(cycle.Notes),
+		cycle.IsPredicted,
+	).Scan(&cycle.ID, &cycle.CreatedAt, &cycle.UpdatedAt)
+}
+
+// GetCycle возвращает цикл пользователя по ID.
+func (s *Storage) GetCycle(ctx context.Context, userUUID, id string) (*models.Cycle, error) {
+	query := `
+		SELECT` + cycleColumns + `
+		FROM menstrual_cycles
+		WHERE id = $1 AND user_id = $2
+	`
+	cycle, err := scanCycle(s.conn(ctx).QueryRow(ctx, query, id, userUUID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCycleNotFound
+	}
+	return cycle, err
+}
+
+// ListCycles возвращает циклы пользователя (в том числе прогнозные), пересекающиеся
+// с периодом [from, to], по возрастанию даты начала. Нулевая граница периода не ограничивает выборку.
+func (s *Storage) ListCycles(ctx context.Context, userUUID string, from, to models.Date) ([]models.Cycle, error) {
+	query := `
+		SELECT` + cycleColumns + `
+		FROM menstrual_cycles
+		WHERE user_id = $1
+			AND ($2::date IS NULL OR end_date IS NULL OR end_date >= $2)
+			AND ($3::date IS NULL OR start_date <= $3)
+		ORDER BY start_date, is_predicted
+	`
+	rows, err := s.conn(ctx).Query(ctx, query, userUUID, zeroDateOrNil(from), zeroDateOrNil(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cycles []models.Cycle
+	for rows.Next() {
+		cycle, err := scanCycle(rows)
+		if err != nil {
+			return nil, err
+		}
+		cycles = append(cycles, *cycle)
+	}
+	return cycles, rows.Err()
+}
+
+// UpdateCycle изменяет отмеченный пользователем цикл. Прогнозные циклы не изменяются.
+func (s *Storage) UpdateCycle(ctx context.Context, cycle *models.Cycle) error {
+	query := `
+		UPDATE menstrual_cycles
+		SET start_date = $1, end_date = $2, period_end_date = $3, notes = $4
+		WHERE id = $5 AND user_id = $6 AND is_predicted IS NOT TRUE
+		RETURNING updated_at
+	`
+	err := s.conn(ctx).QueryRow(
+		ctx,
+		query,
+		cycle.StartDate.Time,
+		dateOrNil(cycle.EndDate),
+		dateOrNil(cycle.PeriodEndDate),
+		nilIfEmpty(cycle.Notes),
+		cycle.ID,
+		cycle.UserUUID,
+	).Scan(&cycle.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrCycleNotFound
+	}
+	return err
+}
+
+// DeleteCycle удаляет отмеченный пользователем цикл.
+func (s *Storage) DeleteCycle(ctx context.Context, userUUID, id string) error {
+	query := `
+		DELETE FROM menstrual_cycles
+		WHERE id = $1 AND user_id = $2 AND is_predicted IS NOT TRUE
+	`
+	tag, err := s.conn(ctx).Exec(ctx, query, id, userUUID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCycleNotFound
+	}
+	return nil
+}
+
+// HasOverlappingCycle проверяет, пересекается ли период [start, end] с отмеченными циклами
+// пользователя, кроме excludeID. Пустой end означает незакрытый цикл.
+func (s *Storage) HasOverlappingCycle(
+	ctx context.Context,
+	userUUID string,
+	start models.Date,
+	end *models.Date,
+	excludeID string,
+) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM menstrual_cycles
+			WHERE user_id = $1
+				AND is_predicted IS NOT TRUE
+				AND ($4::uuid IS NULL OR id <> $4)
+				AND (end_date IS NULL OR end_date >= $2)
+				AND ($3::date IS NULL OR start_date <= $3)
+		)
+	`
+	var exists bool
+	err := s.conn(ctx).QueryRow(ctx, query, userUUID, start.Time, dateOrNil(end), nilIfEmpty(excludeID)).Scan(&exists)
+	return exists, err
+}
+
+func scanCycle(row pgx.Row) (*models.Cycle, error) {
+	var cycle models.Cycle
+	var endDate, periodEndDate *time.Time
+	var notes sql.NullString
+	var isPredicted sql.NullBool
+	err := row.Scan(
+		&cycle.ID,
+		&cycle.UserUUID,
+		&cycle.StartDate.Time,
+		&endDate,
+		&periodEndDate,
+		&notes,
+		&isPredicted,
+		&cycle.CreatedAt,
+		&cycle.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if endDate != nil {
+		cycle.EndDate = &models.Date{Time: *endDate}
+	}
+	if periodEndDate != nil {
+		cycle.PeriodEndDate = &models.Date{Time: *periodEndDate}
+	}
+	cycle.Notes = notes.String
+	cycle.IsPredicted = isPredicted.Bool
+	return &cycle, nil
+}
+
+func dateOrNil(d *models.Date) any {
+	if d == nil {
+		return nil
+	}
+	return d.Time
+}
+
+func zeroDateOrNil(d models.Date) any {
+	if d.IsZero() {
+		return nil
+	}
+	return d.Time
+}
+
+func nilIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// GetCycleAverages возвращает средние длительности цикла и менструации из профиля пользователя.
+// Если профиля нет или значения не заполнены, возвращаются нули.
+func (s *Storage) GetCycleAverages(ctx context.Context, userUUID string) (cycleLength, periodLength int, err error) {
+	query := `
+		SELECT avg_cycle_length, avg_period_length
+		FROM user_profiles
+		WHERE user_id = $1
+	`
+	var cycle, period sql.NullInt32
+	err = s.conn(ctx).QueryRow(ctx, query, userUUID).Scan(&cycle, &period)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(cycle.Int32), int(period.Int32), nil
+}
+
+// ReplacePredictedCycles заменяет прогнозные циклы пользователя новыми.
+// Вызывается в транзакции, чтобы прогноз не был виден частично.
+func (s *Storage) ReplacePredictedCycles(ctx context.Context, userUUID string, cycles []models.Cycle) error {
+	query := `
+		DELETE FROM menstrual_cycles
+		WHERE user_id = $1 AND is_predicted IS TRUE
+	`
+	if _, err := s.conn(ctx).Exec(ctx, query, userUUID); err != nil {
+		return err
+	}
+
+	for i := range cycles {
+		cycles[i].UserUUID = userUUID
+		cycles[i].IsPredicted = true
+		if err := s.CreateCycle(ctx, &cycles[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
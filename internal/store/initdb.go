@@ -1,22 +1,47 @@
 package store
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const defaultApplicationName = "woman-app-backend"
+
 //go:generate options-gen -out-filename=storage_options.gen.go -from-struct=Options
 type Options struct {
-	dbName        string `option:"mandatory"`
-	dbUser        string `option:"mandatory"`
-	dbPassword    string `option:"mandatory"`
-	dbHost        string `option:"mandatory"`
-	dbPort        string `option:"mandatory"`
-	dbSSLMode     string `option:"optional" default:"disable"`
+	dbName     string `option:"mandatory"`
+	dbUser     string `option:"mandatory"`
+	dbPassword string `option:"mandatory"`
+	dbHost     string `option:"mandatory"`
+	dbPort     string `option:"mandatory"`
+	dbSSLMode  string `option:"optional" default:"disable"`
+	// dbSSLRootCert CA для проверки сертификата сервера (sslmode verify-ca и verify-full)
 	dbSSLRootCert string
-	dbSSLKey      string
+	// dbSSLCert и dbSSLKey клиентский сертификат и ключ для аутентификации по TLS
+	dbSSLCert string
+	dbSSLKey  string
+	// applicationName имя приложения в pg_stat_activity (по умолчанию woman-app-backend)
+	applicationName string
+	// maxConns размер пула (по умолчанию pgxpool: max(4, число CPU))
+	maxConns int32
+	// minConns сколько соединений держать открытыми даже без нагрузки
+	minConns int32
+	// maxConnLifetime через сколько соединение закрывается и пересоздается (по умолчанию 1h)
+	maxConnLifetime time.Duration
+	// maxConnIdleTime через сколько закрывается простаивающее соединение (по умолчанию 30m)
+	maxConnIdleTime time.Duration
+	// healthCheckPeriod как часто проверять простаивающие соединения (по умолчанию 1m)
+	healthCheckPeriod time.Duration
+	// statementTimeout ограничение времени запроса на стороне Postgres (0 - без ограничения)
+	statementTimeout time.Duration
 }
 
 // Storage is the database connection pool.
@@ -24,21 +49,41 @@ type Storage struct {
 	db *pgxpool.Pool
 }
 
+// PoolStats метрики пула соединений.
+type PoolStats struct {
+	TotalConns    int32 `json:"totalConns"`
+	AcquiredConns int32 `json:"acquiredConns"`
+	IdleConns     int32 `json:"idleConns"`
+	MaxConns      int32 `json:"maxConns"`
+	AcquireCount  int64 `json:"acquireCount"`
+	// WaitCount сколько раз запросу пришлось ждать соединение, потому что свободных не было
+	WaitCount            int64 `json:"waitCount"`
+	WaitDurationMs       int64 `json:"waitDurationMs"`
+	AcquireDurationMs    int64 `json:"acquireDurationMs"`
+	CanceledAcquireCount int64 `json:"canceledAcquireCount"`
+	NewConnsCount        int64 `json:"newConnsCount"`
+	LifetimeDestroyCount int64 `json:"lifetimeDestroyCount"`
+	IdleDestroyCount     int64 `json:"idleDestroyCount"`
+}
+
 // NewStorage creates a new database connection pool.
 func NewStorage(ctx context.Context, opts Options) (*Storage, error) {
 	if err := opts.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid options for storage: %v", err)
 	}
 
-	dbconn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		opts.dbHost, opts.dbPort, opts.dbUser, opts.dbPassword, opts.dbName, opts.dbSSLMode)
+	cfg, err := opts.poolConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid options for storage: %v", err)
+	}
 
-	pool, err := pgxpool.New(ctx, dbconn)
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
 
 	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("failed to ping database: %v", err)
 	}
 
@@ -54,3 +99,97 @@ func (s *Storage) Close() {
 func (s *Storage) Ping(ctx context.Context) error {
 	return s.db.Ping(ctx)
 }
+
+// Stats возвращает метрики пула соединений.
+func (s *Storage) Stats() PoolStats {
+	stat := s.db.Stat()
+	return PoolStats{
+		TotalConns:           stat.TotalConns(),
+		AcquiredConns:        stat.AcquiredConns(),
+		IdleConns:            stat.IdleConns(),
+		MaxConns:             stat.MaxConns(),
+		AcquireCount:         stat.AcquireCount(),
+		WaitCount:            stat.EmptyAcquireCount(),
+		WaitDurationMs:       stat.EmptyAcquireWaitTime().Milliseconds(),
+		AcquireDurationMs:    stat.AcquireDuration().Milliseconds(),
+		CanceledAcquireCount: stat.CanceledAcquireCount(),
+		NewConnsCount:        stat.NewConnsCount(),
+		LifetimeDestroyCount: stat.MaxLifetimeDestroyCount(),
+		IdleDestroyCount:     stat.MaxIdleDestroyCount(),
+	}
+}
+
+// poolConfig собирает настройки пула. Строка подключения собирается из экранированных значений,
+// поэтому пароль и пути могут содержать пробелы и кавычки; TLS настраивает pgx по sslmode и файлам сертификатов.
+func (o Options) poolConfig() (*pgxpool.Config, error) {
+	if (o.dbSSLCert == "") != (o.dbSSLKey == "") {
+		return nil, errors.New("ssl cert and ssl key must be set together")
+	}
+	if o.minConns < 0 || o.maxConns < 0 || (o.maxConns > 0 && o.minConns > o.maxConns) {
+		return nil, fmt.Errorf("invalid pool size: min %d, max %d", o.minConns, o.maxConns)
+	}
+
+	params := [][2]string{
+		{"host", o.dbHost},
+		{"port", o.dbPort},
+		{"user", o.dbUser},
+		{"password", o.dbPassword},
+		{"dbname", o.dbName},
+		{"sslmode", o.dbSSLMode},
+		{"sslrootcert", o.dbSSLRootCert},
+		{"sslcert", o.dbSSLCert},
+		{"sslkey", o.dbSSLKey},
+	}
+	var dsn strings.Builder
+	for _, p := range params {
+		if p[1] == "" {
+			continue
+		}
+		if dsn.Len() > 0 {
+			dsn.WriteByte(' ')
+		}
+		dsn.WriteString(p[0] + "=" + quoteDSNValue(p[1]))
+	}
+
+	cfg, err := pgxpool.ParseConfig(dsn.String())
+	if err != nil {
+		// Текст ParseConfigError содержит строку подключения, а pgx не скрывает пароль
+		// с экранированными кавычками, поэтому возвращаем только причину
+		var parseErr *pgconn.ParseConfigError
+		if errors.As(err, &parseErr) {
+			if cause := parseErr.Unwrap(); cause != nil {
+				return nil, fmt.Errorf("parse connection config: %w", cause)
+			}
+			return nil, errors.New("parse connection config: invalid connection parameters")
+		}
+		return nil, fmt.Errorf("parse connection config: %w", err)
+	}
+
+	if o.maxConns > 0 {
+		cfg.MaxConns = o.maxConns
+	}
+	if o.minConns > 0 {
+		cfg.MinConns = o.minConns
+	}
+	if o.maxConnLifetime > 0 {
+		cfg.MaxConnLifetime = o.maxConnLifetime
+	}
+	if o.maxConnIdleTime > 0 {
+		cfg.MaxConnIdleTime = o.maxConnIdleTime
+	}
+	if o.healthCheckPeriod > 0 {
+		cfg.HealthCheckPeriod = o.healthCheckPeriod
+	}
+
+	cfg.ConnConfig.RuntimeParams["application_name"] = cmp.Or(o.applicationName, defaultApplicationName)
+	if o.statementTimeout > 0 {
+		cfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(o.statementTimeout.Milliseconds(), 10)
+	}
+
+	return cfg, nil
+}
+
+// quoteDSNValue экранирует значение для строки подключения в формате key=value.
+func quoteDSNValue(v string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
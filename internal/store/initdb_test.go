@@ -0,0 +1,76 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions_PoolConfig(t *testing.T) {
+	t.Run("escaping and pool settings", func(t *testing.T) {
+		password := `p@ss w'ord\ sslmode=disable`
+		cfg, err := NewOptions("app db", "app", password, "db.internal", "6432",
+			WithMaxConns(20),
+			WithMinConns(2),
+			WithMaxConnLifetime(time.Hour),
+			WithMaxConnIdleTime(10*time.Minute),
+			WithHealthCheckPeriod(30*time.Second),
+			WithStatementTimeout(5*time.Second),
+		).poolConfig()
+		require.NoError(t, err)
+
+		conn := cfg.ConnConfig
+		assert.Equal(t, password, conn.Password)
+		assert.Equal(t, "app db", conn.Database)
+		assert.Equal(t, "db.internal", conn.Host)
+		assert.Equal(t, uint16(6432), conn.Port)
+		assert.Nil(t, conn.TLSConfig)
+		assert.Equal(t, "woman-app-backend", conn.RuntimeParams["application_name"])
+		assert.Equal(t, "5000", conn.RuntimeParams["statement_timeout"])
+
+		assert.Equal(t, int32(20), cfg.MaxConns)
+		assert.Equal(t, int32(2), cfg.MinConns)
+		assert.Equal(t, time.Hour, cfg.MaxConnLifetime)
+		assert.Equal(t, 10*time.Minute, cfg.MaxConnIdleTime)
+		assert.Equal(t, 30*time.Second, cfg.HealthCheckPeriod)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		cfg, err := NewOptions("db", "user", "pass", "localhost", "5432",
+			WithApplicationName("reports"),
+		).poolConfig()
+		require.NoError(t, err)
+
+		assert.Equal(t, "reports", cfg.ConnConfig.RuntimeParams["application_name"])
+		assert.NotContains(t, cfg.ConnConfig.RuntimeParams, "statement_timeout")
+		assert.Positive(t, cfg.MaxConns)
+		assert.Equal(t, time.Hour, cfg.MaxConnLifetime)
+	})
+
+	t.Run("client certificate is used", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := NewOptions("db", "user", "pass", "localhost", "5432",
+			WithDbSSLMode("verify-full"),
+			WithDbSSLCert(filepath.Join(dir, "client.crt")),
+			WithDbSSLKey(filepath.Join(dir, "client.key")),
+		).poolConfig()
+		require.ErrorContains(t, err, "client.key", "missing client key file must not be ignored")
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := NewOptions("db", "user", "pass", "localhost", "5432", WithDbSSLKey("client.key")).poolConfig()
+		require.Error(t, err)
+
+		_, err = NewOptions("db", "user", "pass", "localhost", "5432", WithMinConns(5), WithMaxConns(2)).poolConfig()
+		require.Error(t, err)
+	})
+
+	t.Run("password is not leaked in errors", func(t *testing.T) {
+		_, err := NewOptions("db", "user", `se'cret`, "localhost", "not-a-port").poolConfig()
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "cret")
+	})
+}
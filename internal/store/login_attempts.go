@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+)
+
+// LockLoginAttempts выполняет fn в транзакции, удерживая advisory lock каждого ключа до ее конца,
+// чтобы проверка и резервирование попытки входа были атомарными между экземплярами.
+func (s *Storage) LockLoginAttempts(ctx context.Context, keys []string, fn func(ctx context.Context) error) error {
+	// Единый порядок захвата исключает взаимную блокировку
+	keys = slices.Sorted(slices.Values(keys))
+
+	return s.WithTx(ctx, TxOptions{}, func(ctx context.Context) error {
+		for _, key := range keys {
+			if _, err := s.conn(ctx).Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, key); err != nil {
+				return err
+			}
+		}
+		return fn(ctx)
+	})
+}
+
+// AddLoginFailure записывает неудачную попытку входа по ключу.
+func (s *Storage) AddLoginFailure(ctx context.Context, key string, at time.Time) error {
+	query := `
+		INSERT INTO login_failures (key, failed_at)
+		VALUES ($1, $2)
+	`
+	_, err := s.conn(ctx).Exec(ctx, query, key, at.UTC())
+	return err
+}
+
+// DeleteLoginFailure удаляет одну неудачную попытку по ключу, записанную в момент at.
+func (s *Storage) DeleteLoginFailure(ctx context.Context, key string, at time.Time) error {
+	query := `
+		DELETE FROM login_failures
+		WHERE ctid IN (
+			SELECT ctid
+			FROM login_failures
+			WHERE key = $1 AND failed_at = $2
+			LIMIT 1
+		)
+	`
+	_, err := s.conn(ctx).Exec(ctx, query, key, at.UTC())
+	return err
+}
+
+// CountLoginFailures возвращает число неудачных попыток по ключу позже since и время последней из них.
+func (s *Storage) CountLoginFailures(ctx context.Context, key string, since time.Time) (int, time.Time, error) {
+	query := `
+		SELECT count(*), max(failed_at)
+		FROM login_failures
+		WHERE key = $1 AND failed_at > $2
+	`
+	var count int
+	var last sql.NullTime
+	if err := s.conn(ctx).QueryRow(ctx, query, key, since.UTC()).Scan(&count, &last); err != nil {
+		return 0, time.Time{}, err
+	}
+	return count, last.Time, nil
+}
+
+// SetLoginLockout блокирует вход по ключу до until и сбрасывает накопленные неудачные попытки.
+func (s *Storage) SetLoginLockout(ctx context.Context, key string, until time.Time) error {
+	batch := &pgx.Batch{}
+	batch.Queue(`
+		INSERT INTO login_lockouts (key, locked_until)
+		VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE
+		SET locked_until = EXCLUDED.locked_until, created_at = CURRENT_TIMESTAMP
+	`, key, until.UTC())
+	batch.Queue(`DELETE FROM login_failures WHERE key = $1`, key)
+
+	return s.conn(ctx).SendBatch(ctx, batch).Close()
+}
+
+// GetLoginLockout возвращает время окончания блокировки по ключу (нулевое, если блокировки нет).
+func (s *Storage) GetLoginLockout(ctx context.Context, key string) (time.Time, error) {
+	query := `
+		SELECT locked_until
+		FROM login_lockouts
+		WHERE key = $1
+	`
+	var until time.Time
+	err := s.conn(ctx).QueryRow(ctx, query, key).Scan(&until)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return until, err
+}
+
+// ClearLoginAttempts удаляет неудачные попытки и блокировку по ключу.
+// Возвращает true, если ключ был заблокирован.
+func (s *Storage) ClearLoginAttempts(ctx context.Context, key string) (bool, error) {
+	batch := &pgx.Batch{}
+	batch.Queue(`DELETE FROM login_failures WHERE key = $1`, key)
+	batch.Queue(`DELETE FROM login_lockouts WHERE key = $1`, key)
+
+	results := s.conn(ctx).SendBatch(ctx, batch)
+	defer results.Close()
+
+	if _, err := results.Exec(); err != nil {
+		return false, err
+	}
+	tag, err := results.Exec()
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteExpiredLoginAttempts удаляет попытки и блокировки, закончившиеся не позже before.
+func (s *Storage) DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) error {
+	batch := &pgx.Batch{}
+	batch.Queue(`DELETE FROM login_failures WHERE failed_at <= $1`, before.UTC())
+	batch.Queue(`DELETE FROM login_lockouts WHERE locked_until <= $1`, before.UTC())
+
+	return s.conn(ctx).SendBatch(ctx, batch).Close()
+}
+
+// CreateAuditEvent записывает событие в журнал аудита.
+func (s *Storage) CreateAuditEvent(ctx context.Context, event *models.AuditEvent) error {
+	query := `
+		INSERT INTO audit_log (action, subject, actor, details)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	return s.conn(ctx).QueryRow(ctx, query, event.Action, event.Subject, event.Actor, event.Details).
+		Scan(&event.ID, &event.CreatedAt)
+}
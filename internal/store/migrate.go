@@ -0,0 +1,402 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationLockID ключ advisory lock миграций ("womanapp" в ASCII).
+const migrationLockID int64 = 0x776f6d616e617070
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+var (
+	// ErrMigrationNotFound в приложении нет миграции с запрошенной версией.
+	ErrMigrationNotFound = errors.New("migration not found")
+	// ErrMigrationChecksumMismatch примененная миграция была изменена после применения.
+	ErrMigrationChecksumMismatch = errors.New("migration checksum mismatch")
+	// ErrUnknownMigration в базе применена миграция, о которой приложение не знает
+	// (например, база уже обновлена более новой версией приложения).
+	ErrUnknownMigration = errors.New("unknown migration applied")
+	// ErrNoDownMigration у миграции нет файла отката.
+	ErrNoDownMigration = errors.New("migration has no down script")
+)
+
+// Migration версия схемы из каталога migrations: файлы NNN_name.up.sql и NNN_name.down.sql.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// Checksum sha256 скрипта up, по нему обнаруживается изменение уже примененной миграции.
+	Checksum string
+}
+
+// MigrationStatus состояние версии схемы в базе.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Modified скрипт миграции изменился после применения.
+	Modified bool
+	// Unknown миграция применена в базе, но отсутствует в приложении.
+	Unknown bool
+}
+
+// appliedMigration запись таблицы schema_migrations.
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Migrator применяет встроенные в приложение миграции. Все операции выполняются под
+// advisory lock, поэтому несколько экземпляров приложения могут запускать миграции одновременно.
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+// Migrator возвращает мигратор схемы базы данных.
+func (s *Storage) Migrator() (*Migrator, error) {
+	migrations, err := loadMigrations(migrationsFS)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: s.db, migrations: migrations}, nil
+}
+
+// Migrations возвращает известные приложению миграции по возрастанию версии.
+func (m *Migrator) Migrations() []Migration {
+	return slices.Clone(m.migrations)
+}
+
+// Up применяет все непримененные миграции и возвращает примененные.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn, applied map[int64]appliedMigration) error {
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := applyMigration(ctx, conn, mig, true); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down откатывает steps последних примененных миграций и возвращает откаченные.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn, applied map[int64]appliedMigration) error {
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+		for _, mig := range slices.Backward(m.migrations) {
+			if len(done) == steps {
+				break
+			}
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if err := applyMigration(ctx, conn, mig, false); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Goto приводит схему к версии version: откатывает более новые миграции и применяет
+// недостающие до нее включительно. Версия 0 откатывает все миграции.
+// Возвращает выполненные миграции в порядке выполнения.
+func (m *Migrator) Goto(ctx context.Context, version int64) ([]Migration, error) {
+	if version != 0 && !slices.ContainsFunc(m.migrations, func(mig Migration) bool { return mig.Version == version }) {
+		return nil, fmt.Errorf("%w: %d", ErrMigrationNotFound, version)
+	}
+
+	var done []Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn, applied map[int64]appliedMigration) error {
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+		for _, mig := range slices.Backward(m.migrations) {
+			if _, ok := applied[mig.Version]; !ok || mig.Version <= version {
+				continue
+			}
+			if err := applyMigration(ctx, conn, mig, false); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok || mig.Version > version {
+				continue
+			}
+			if err := applyMigration(ctx, conn, mig, true); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Baseline отмечает миграции до версии version включительно как примененные, не выполняя их.
+// Нужен для баз, схема которых была создана скриптами до появления мигратора.
+func (m *Migrator) Baseline(ctx context.Context, version int64) ([]Migration, error) {
+	if !slices.ContainsFunc(m.migrations, func(mig Migration) bool { return mig.Version == version }) {
+		return nil, fmt.Errorf("%w: %d", ErrMigrationNotFound, version)
+	}
+
+	var done []Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn, applied map[int64]appliedMigration) error {
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok || mig.Version > version {
+				continue
+			}
+			query := `
+				INSERT INTO schema_migrations (version, name, checksum)
+				VALUES ($1, $2, $3)
+			`
+			if _, err := conn.Exec(ctx, query, mig.Version, mig.Name, mig.Checksum); err != nil {
+				return fmt.Errorf("baseline migration %d: %w", mig.Version, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status возвращает состояние всех известных и примененных миграций по возрастанию версии.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(_ *pgxpool.Conn, applied map[int64]appliedMigration) error {
+		for _, mig := range m.migrations {
+			status := MigrationStatus{Version: mig.Version, Name: mig.Name}
+			if a, ok := applied[mig.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = a.AppliedAt
+				status.Modified = a.Checksum != mig.Checksum
+				delete(applied, mig.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		for _, a := range applied {
+			statuses = append(statuses, MigrationStatus{
+				Version:   a.Version,
+				Name:      a.Name,
+				Applied:   true,
+				AppliedAt: a.AppliedAt,
+				Unknown:   true,
+			})
+		}
+		return nil
+	})
+	slices.SortFunc(statuses, func(a, b MigrationStatus) int { return cmp.Compare(a.Version, b.Version) })
+	return statuses, err
+}
+
+// verify запрещает менять схему, если примененные миграции не совпадают с известными приложению.
+func (m *Migrator) verify(applied map[int64]appliedMigration) error {
+	for _, a := range applied {
+		i := slices.IndexFunc(m.migrations, func(mig Migration) bool { return mig.Version == a.Version })
+		if i < 0 {
+			return fmt.Errorf("%w: %d_%s", ErrUnknownMigration, a.Version, a.Name)
+		}
+		if m.migrations[i].Checksum != a.Checksum {
+			return fmt.Errorf("%w: %d_%s", ErrMigrationChecksumMismatch, a.Version, a.Name)
+		}
+	}
+	return nil
+}
+
+// withLock выполняет fn на отдельном соединении под advisory lock миграций.
+// Session lock привязан к соединению, поэтому все запросы идут через conn.
+func (m *Migrator) withLock(
+	ctx context.Context,
+	fn func(conn *pgxpool.Conn, applied map[int64]appliedMigration) error,
+) (err error) {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	// statement_timeout пула рассчитан на запросы API: ожидание lock и долгие миграции
+	// не должны в него упираться. RESET возвращает значение из параметров подключения.
+	if _, err := conn.Exec(ctx, `SET statement_timeout = 0`); err != nil {
+		return fmt.Errorf("disable statement timeout: %w", err)
+	}
+	defer func() {
+		if _, resetErr := conn.Exec(context.WithoutCancel(ctx), `RESET statement_timeout`); resetErr != nil {
+			// Закрываем соединение, чтобы в пул не вернулось соединение без таймаута
+			_ = conn.Conn().Close(context.WithoutCancel(ctx))
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		_, unlockErr := conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+		if unlockErr != nil {
+			// Закрываем соединение, чтобы lock не остался в пуле
+			_ = conn.Conn().Close(context.WithoutCancel(ctx))
+			if err == nil {
+				err = fmt.Errorf("release migration lock: %w", unlockErr)
+			}
+		}
+	}()
+
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version bigint PRIMARY KEY,
+			name varchar(255) NOT NULL,
+			checksum varchar(64) NOT NULL,
+			applied_at timestamp DEFAULT CURRENT_TIMESTAMP
+		)
+	`
+	if _, err := conn.Exec(ctx, query); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	rows, err := conn.Query(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("list applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return fmt.Errorf("scan applied migration: %w", err)
+		}
+		applied[a.Version] = a
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("list applied migrations: %w", err)
+	}
+
+	return fn(conn, applied)
+}
+
+// applyMigration выполняет скрипт миграции и запись в schema_migrations в одной транзакции.
+func applyMigration(ctx context.Context, conn *pgxpool.Conn, mig Migration, up bool) error {
+	script, direction := mig.Up, "up"
+	if !up {
+		if mig.Down == "" {
+			return fmt.Errorf("%w: %d_%s", ErrNoDownMigration, mig.Version, mig.Name)
+		}
+		script, direction = mig.Down, "down"
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin migration %d: %w", mig.Version, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Без аргументов pgx использует simple protocol, поэтому скрипт может содержать несколько команд
+	if _, err := tx.Exec(ctx, script); err != nil {
+		return fmt.Errorf("migration %d_%s %s: %w", mig.Version, mig.Name, direction, err)
+	}
+
+	if up {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO schema_migrations (version, name, checksum)
+			VALUES ($1, $2, $3)
+		`, mig.Version, mig.Name, mig.Checksum)
+	} else {
+		_, err = tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("record migration %d: %w", mig.Version, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit migration %d: %w", mig.Version, err)
+	}
+	return nil
+}
+
+// loadMigrations читает миграции из fsys и сортирует их по версии.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, file := range files {
+		base := file[len("migrations/"):]
+		match := migrationFileRe.FindStringSubmatch(base)
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", base)
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", base)
+		}
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("read migration %q: %w", base, err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has different names: %q and %q", version, mig.Name, match[2])
+		}
+		if match[3] == "up" {
+			mig.Up = string(content)
+			sum := sha256.Sum256(content)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+	return migrations, nil
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("embedded", func(t *testing.T) {
+		migrations, err := loadMigrations(migrationsFS)
+		require.NoError(t, err)
+		require.NotEmpty(t, migrations)
+
+		for i, mig := range migrations {
+			assert.Equal(t, int64(i+1), mig.Version, "versions are sequential")
+			assert.NotEmpty(t, mig.Down, "migration %d_%s has down script", mig.Version, mig.Name)
+			assert.Len(t, mig.Checksum, 64)
+		}
+	})
+
+	t.Run("sorted by version", func(t *testing.T) {
+		migrations, err := loadMigrations(fstest.MapFS{
+			"migrations/010_second.up.sql":  {Data: []byte("SELECT 10;")},
+			"migrations/002_first.up.sql":   {Data: []byte("SELECT 2;")},
+			"migrations/002_first.down.sql": {Data: []byte("SELECT -2;")},
+		})
+		require.NoError(t, err)
+		require.Len(t, migrations, 2)
+
+		assert.Equal(t, int64(2), migrations[0].Version)
+		assert.Equal(t, "first", migrations[0].Name)
+		assert.Equal(t, "SELECT -2;", migrations[0].Down)
+		assert.Equal(t, int64(10), migrations[1].Version)
+		assert.Empty(t, migrations[1].Down)
+		assert.NotEqual(t, migrations[0].Checksum, migrations[1].Checksum)
+	})
+
+	for name, files := range map[string]fstest.MapFS{
+		"bad file name":       {"migrations/first.up.sql": {}},
+		"zero version":        {"migrations/000_first.up.sql": {Data: []byte("SELECT 1;")}},
+		"down without up":     {"migrations/001_first.down.sql": {Data: []byte("SELECT 1;")}},
+		"different names":     {"migrations/001_first.up.sql": {Data: []byte("SELECT 1;")}, "migrations/001_second.down.sql": {Data: []byte("SELECT 1;")}},
+		"unsupported postfix": {"migrations/001_first.sql": {Data: []byte("SELECT 1;")}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadMigrations(files)
+			require.Error(t, err)
+		})
+	}
+}
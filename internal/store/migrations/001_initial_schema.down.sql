@@ -0,0 +1,12 @@
+-- Удаляем исходную схему целиком (все данные будут потеряны)
+DROP TABLE IF EXISTS notes;
+DROP TABLE IF EXISTS medications;
+DROP TABLE IF EXISTS user_moods;
+DROP TABLE IF EXISTS moods;
+DROP TABLE IF EXISTS user_symptoms;
+DROP TABLE IF EXISTS symptoms;
+DROP TABLE IF EXISTS menstrual_cycles;
+DROP TABLE IF EXISTS user_profiles;
+DROP TABLE IF EXISTS users;
+
+DROP FUNCTION IF EXISTS update_updated_at_column();
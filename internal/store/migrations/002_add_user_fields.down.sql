@@ -0,0 +1,6 @@
+ALTER TABLE users
+DROP COLUMN IF EXISTS last_name,
+DROP COLUMN IF EXISTS phone,
+DROP COLUMN IF EXISTS sex,
+DROP COLUMN IF EXISTS city,
+DROP COLUMN IF EXISTS country;
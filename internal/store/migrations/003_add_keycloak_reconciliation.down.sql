@@ -0,0 +1 @@
+DROP TABLE IF EXISTS keycloak_reconciliation;
@@ -0,0 +1,21 @@
+-- Журнал сверки с Keycloak: действия, которые не удалось выполнить сразу
+-- (например, удаление пользователя Keycloak после неудачной регистрации)
+CREATE TABLE IF NOT EXISTS keycloak_reconciliation (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    keycloak_user_id uuid NOT NULL,
+    email varchar(255),
+    action varchar(50) NOT NULL,
+    reason text,
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text,
+    created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(keycloak_user_id, action)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keycloak_reconciliation_action ON keycloak_reconciliation(action, created_at);
+
+CREATE TRIGGER update_keycloak_reconciliation_updated_at
+    BEFORE UPDATE ON keycloak_reconciliation
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,3 @@
+-- Отметка о том, что пользователь пропал из Keycloak (выставляется сверкой пользователей)
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS keycloak_missing_since timestamp;
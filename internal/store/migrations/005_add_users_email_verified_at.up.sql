@@ -0,0 +1,3 @@
+-- Время, когда впервые был замечен подтвержденный email пользователя (выставляется при логине)
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS email_verified_at timestamp;
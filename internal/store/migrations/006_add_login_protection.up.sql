@@ -0,0 +1,27 @@
+-- Неудачные попытки входа для защиты от перебора паролей.
+-- key - "ip:<адрес>" или "account:<sha256 email>"
+CREATE TABLE IF NOT EXISTS login_failures (
+    key varchar(255) NOT NULL,
+    failed_at timestamp NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_failures_key ON login_failures(key, failed_at);
+
+-- Временные блокировки входа
+CREATE TABLE IF NOT EXISTS login_lockouts (
+    key varchar(255) PRIMARY KEY,
+    locked_until timestamp NOT NULL,
+    created_at timestamp DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Журнал аудита событий безопасности (блокировки и снятие блокировок входа)
+CREATE TABLE IF NOT EXISTS audit_log (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    action varchar(50) NOT NULL,
+    subject varchar(255) NOT NULL,
+    actor varchar(255),
+    details text,
+    created_at timestamp DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
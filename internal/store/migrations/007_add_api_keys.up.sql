@@ -0,0 +1,16 @@
+-- Персональные API ключи пользователей для интеграций и автоматизации.
+-- Сам ключ не хранится: prefix используется для поиска, key_hash - sha256 секретной части.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name varchar(100) NOT NULL,
+    prefix varchar(32) UNIQUE NOT NULL,
+    key_hash varchar(64) NOT NULL,
+    scopes varchar[] NOT NULL DEFAULT '{}',
+    expires_at timestamp,
+    last_used_at timestamp,
+    revoked_at timestamp,
+    created_at timestamp DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
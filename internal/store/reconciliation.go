@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Fisher-Development/woman-app-backend/internal/models"
+)
+
+// CreateReconciliationRecord записывает действие, которое нужно довести до конца в Keycloak.
+// Повторная запись того же действия для пользователя обновляет причину и последнюю ошибку.
+func (s *Storage) CreateReconciliationRecord(ctx context.Context, record *models.ReconciliationRecord) error {
+	query := `
+		INSERT INTO keycloak_reconciliation (keycloak_user_id, email, action, reason, attempts, last_error)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (keycloak_user_id, action) DO UPDATE
+		SET 
+			reason = EXCLUDED.reason,
+			attempts = keycloak_reconciliation.attempts + EXCLUDED.attempts,
+			last_error = EXCLUDED.last_error
+		RETURNING id, created_at, updated_at
+	`
+	return s.conn(ctx).QueryRow(
+		ctx,
+		query,
+		record.KeycloakUserID,
+		record.Email,
+		record.Action,
+		record.Reason,
+		record.Attempts,
+		record.LastError,
+	).Scan(&record.ID, &record.CreatedAt, &record.UpdatedAt)
+}
+
+// ListReconciliationRecords возвращает самые старые записи журнала для указанного действия.
+func (s *Storage) ListReconciliationRecords(
+	ctx context.Context,
+	action string,
+	limit int,
+) ([]models.ReconciliationRecord, error) {
+	query := `
+		SELECT 
+			id, 
+			keycloak_user_id, 
+			email, 
+			action, 
+			reason, 
+			attempts, 
+			last_error, 
+			created_at, 
+			updated_at
+		FROM keycloak_reconciliation
+		WHERE action = $1
+		ORDER BY created_at
+		LIMIT $2
+	`
+	rows, err := s.conn(ctx).Query(ctx, query, action, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []models.ReconciliationRecord
+	for rows.Next() {
+		var record models.ReconciliationRecord
+		var emailStr, reasonStr, lastErrorStr sql.NullString
+		if err := rows.Scan(
+			&record.ID,
+			&record.KeycloakUserID,
+			&emailStr,
+			&record.Action,
+			&reasonStr,
+			&record.Attempts,
+			&lastErrorStr,
+			&record.CreatedAt,
+			&record.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		record.Email = emailStr.String
+		record.Reason = reasonStr.String
+		record.LastError = lastErrorStr.String
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// RecordReconciliationAttempt увеличивает счетчик попыток и сохраняет последнюю ошибку.
+func (s *Storage) RecordReconciliationAttempt(ctx context.Context, id, lastError string) error {
+	query := `
+		UPDATE keycloak_reconciliation
+		SET attempts = attempts + 1, last_error = $1
+		WHERE id = $2
+	`
+	_, err := s.conn(ctx).Exec(ctx, query, lastError, id)
+	return err
+}
+
+// DeleteReconciliationRecord удаляет выполненную запись журнала.
+func (s *Storage) DeleteReconciliationRecord(ctx context.Context, id string) error {
+	query := `DELETE FROM keycloak_reconciliation WHERE id = $1`
+	_, err := s.conn(ctx).Exec(ctx, query, id)
+	return err
+}
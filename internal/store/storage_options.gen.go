@@ -1,6 +1,10 @@
 // Code generated by options-gen. DO NOT EDIT.
 package store
 
+import (
+	"time"
+)
+
 type OptOptionsSetter func(o *Options)
 
 func NewOptions(
@@ -46,6 +50,13 @@ func WithDbSSLRootCert(opt string) OptOptionsSetter {
 	}
 }
 
+func WithDbSSLCert(opt string) OptOptionsSetter {
+	return func(o *Options) {
+		o.dbSSLCert = opt
+
+	}
+}
+
 func WithDbSSLKey(opt string) OptOptionsSetter {
 	return func(o *Options) {
 		o.dbSSLKey = opt
@@ -53,6 +64,55 @@ func WithDbSSLKey(opt string) OptOptionsSetter {
 	}
 }
 
+func WithApplicationName(opt string) OptOptionsSetter {
+	return func(o *Options) {
+		o.applicationName = opt
+
+	}
+}
+
+func WithMaxConns(opt int32) OptOptionsSetter {
+	return func(o *Options) {
+		o.maxConns = opt
+
+	}
+}
+
+func WithMinConns(opt int32) OptOptionsSetter {
+	return func(o *Options) {
+		o.minConns = opt
+
+	}
+}
+
+func WithMaxConnLifetime(opt time.Duration) OptOptionsSetter {
+	return func(o *Options) {
+		o.maxConnLifetime = opt
+
+	}
+}
+
+func WithMaxConnIdleTime(opt time.Duration) OptOptionsSetter {
+	return func(o *Options) {
+		o.maxConnIdleTime = opt
+
+	}
+}
+
+func WithHealthCheckPeriod(opt time.Duration) OptOptionsSetter {
+	return func(o *Options) {
+		o.healthCheckPeriod = opt
+
+	}
+}
+
+func WithStatementTimeout(opt time.Duration) OptOptionsSetter {
+	return func(o *Options) {
+		o.statementTimeout = opt
+
+	}
+}
+
 func (o *Options) Validate() error {
 	return nil
 }
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	defaultTxMaxAttempts = 3
+	txRetryBaseDelay     = 10 * time.Millisecond
+)
+
+// IsolationLevel уровень изоляции транзакции.
+type IsolationLevel string
+
+const (
+	// ReadCommitted уровень изоляции Postgres по умолчанию.
+	ReadCommitted IsolationLevel = IsolationLevel(pgx.ReadCommitted)
+	// RepeatableRead все запросы транзакции видят один снимок данных.
+	RepeatableRead IsolationLevel = IsolationLevel(pgx.RepeatableRead)
+	// Serializable транзакции выполняются так, как если бы шли по очереди;
+	// конфликтующая транзакция получает ошибку 40001 и повторяется WithTx.
+	Serializable IsolationLevel = IsolationLevel(pgx.Serializable)
+)
+
+// TxOptions настройки транзакции WithTx. Нулевое значение - read committed, до 3 попыток.
+type TxOptions struct {
+	Isolation IsolationLevel
+	ReadOnly  bool
+	// MaxAttempts сколько раз выполнить транзакцию при serialization failure и deadlock (по умолчанию 3).
+	MaxAttempts int
+}
+
+// querier общие методы пула и транзакции, которыми пользуются методы Storage.
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+type txKey struct{}
+
+// WithTx выполняет fn в транзакции. Транзакция передается через контекст, поэтому все методы
+// Storage, вызванные с ctx внутри fn, выполняются в ней. Если fn возвращает ошибку или паникует,
+// транзакция откатывается.
+//
+// Вложенный вызов WithTx создает savepoint: ошибка fn откатывает только изменения вложенного вызова,
+// а настройки opts берутся из внешней транзакции. Внешняя транзакция повторяется целиком при
+// serialization failure (40001) и deadlock (40P01), поэтому fn не должна иметь побочных эффектов вне базы.
+func (s *Storage) WithTx(ctx context.Context, opts TxOptions, fn func(ctx context.Context) error) error {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return runTx(ctx, tx.Begin, fn)
+	}
+
+	txOpts := pgx.TxOptions{IsoLevel: pgx.TxIsoLevel(opts.Isolation)}
+	if opts.ReadOnly {
+		txOpts.AccessMode = pgx.ReadOnly
+	}
+
+	begin := func(ctx context.Context) (pgx.Tx, error) { return s.db.BeginTx(ctx, txOpts) }
+	return retryTx(ctx, opts.MaxAttempts, begin, fn)
+}
+
+// retryTx выполняет транзакцию, повторяя ее целиком при serialization failure и deadlock
+// (не больше attempts раз, 0 - значение по умолчанию).
+func retryTx(
+	ctx context.Context,
+	attempts int,
+	begin func(ctx context.Context) (pgx.Tx, error),
+	fn func(ctx context.Context) error,
+) error {
+	if attempts <= 0 {
+		attempts = defaultTxMaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := runTx(ctx, begin, fn)
+		if err == nil || attempt >= attempts || !isRetryableTxError(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * txRetryBaseDelay):
+		}
+	}
+}
+
+// runTx выполняет одну попытку транзакции (или savepoint).
+func runTx(
+	ctx context.Context,
+	begin func(ctx context.Context) (pgx.Tx, error),
+	fn func(ctx context.Context) error,
+) (err error) {
+	tx, err := begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback(context.WithoutCancel(ctx))
+			panic(p)
+		}
+		if err != nil {
+			_ = tx.Rollback(context.WithoutCancel(ctx))
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// conn возвращает транзакцию из контекста или пул соединений, если транзакции нет.
+func (s *Storage) conn(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return s.db
+}
+
+// isRetryableTxError транзакцию можно повторить: serialization_failure или deadlock_detected.
+func isRetryableTxError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
@@ -0,0 +1,298 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRetryableTxError(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{err: &pgconn.PgError{Code: "40001"}, want: true},
+		{err: &pgconn.PgError{Code: "40P01"}, want: true},
+		{err: fmt.Errorf("commit transaction: %w", &pgconn.PgError{Code: "40001"}), want: true},
+		{err: &pgconn.PgError{Code: "23505"}, want: false},
+		{err: errors.New("connection refused"), want: false},
+		{err: ErrUserNotFound, want: false},
+	} {
+		assert.Equal(t, tc.want, isRetryableTxError(tc.err), tc.err.Error())
+	}
+}
+
+// fakeDB база, в которую fakeTx применяет изменения при коммите.
+type fakeDB struct {
+	committed []string
+	begins    int
+	// commitErrs ошибки коммита транзакций по порядку begin.
+	commitErrs []error
+	txs        []*fakeTx
+}
+
+func (db *fakeDB) begin(context.Context) (pgx.Tx, error) {
+	tx := &fakeTx{db: db}
+	if db.begins < len(db.commitErrs) {
+		tx.commitErr = db.commitErrs[db.begins]
+	}
+	db.begins++
+	db.txs = append(db.txs, tx)
+	return tx, nil
+}
+
+// fakeTx запоминает запросы Exec; savepoint (parent != nil) при коммите переносит их в родителя.
+type fakeTx struct {
+	pgx.Tx
+
+	db        *fakeDB
+	parent    *fakeTx
+	writes    []string
+	commitErr error
+
+	closed     bool
+	rolledBack bool
+}
+
+func (tx *fakeTx) Begin(context.Context) (pgx.Tx, error) {
+	return &fakeTx{db: tx.db, parent: tx}, nil
+}
+
+func (tx *fakeTx) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	tx.writes = append(tx.writes, sql)
+	return pgconn.CommandTag{}, nil
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+	if tx.commitErr != nil {
+		return tx.commitErr
+	}
+	tx.closed = true
+	if tx.parent != nil {
+		tx.parent.writes = append(tx.parent.writes, tx.writes...)
+	} else {
+		tx.db.committed = append(tx.db.committed, tx.writes...)
+	}
+	return nil
+}
+
+func (tx *fakeTx) Rollback(context.Context) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+	tx.closed = true
+	tx.rolledBack = true
+	tx.writes = nil
+	return nil
+}
+
+func exec(ctx context.Context, t *testing.T, sql string) {
+	t.Helper()
+	_, err := (&Storage{}).conn(ctx).Exec(ctx, sql)
+	require.NoError(t, err)
+}
+
+func TestRunTx(t *testing.T) {
+	errFn := errors.New("fn failed")
+
+	t.Run("commit", func(t *testing.T) {
+		db := &fakeDB{}
+		err := runTx(context.Background(), db.begin, func(ctx context.Context) error {
+			assert.Same(t, db.txs[0], (&Storage{}).conn(ctx))
+			exec(ctx, t, "insert 1")
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"insert 1"}, db.committed)
+		assert.False(t, db.txs[0].rolledBack)
+	})
+
+	t.Run("rollback on error", func(t *testing.T) {
+		db := &fakeDB{}
+		err := runTx(context.Background(), db.begin, func(ctx context.Context) error {
+			exec(ctx, t, "insert 1")
+			return errFn
+		})
+
+		assert.ErrorIs(t, err, errFn)
+		assert.Empty(t, db.committed)
+		assert.True(t, db.txs[0].rolledBack)
+	})
+
+	t.Run("rollback on commit error", func(t *testing.T) {
+		errCommit := &pgconn.PgError{Code: "40001"}
+		db := &fakeDB{commitErrs: []error{errCommit}}
+		err := runTx(context.Background(), db.begin, func(ctx context.Context) error {
+			exec(ctx, t, "insert 1")
+			return nil
+		})
+
+		assert.ErrorIs(t, err, errCommit)
+		assert.Empty(t, db.committed)
+		assert.True(t, db.txs[0].rolledBack)
+	})
+
+	t.Run("rollback and repanic on panic", func(t *testing.T) {
+		db := &fakeDB{}
+		assert.PanicsWithValue(t, "boom", func() {
+			_ = runTx(context.Background(), db.begin, func(ctx context.Context) error {
+				exec(ctx, t, "insert 1")
+				panic("boom")
+			})
+		})
+
+		assert.Empty(t, db.committed)
+		assert.True(t, db.txs[0].rolledBack)
+	})
+}
+
+func TestWithTxNested(t *testing.T) {
+	errInner := errors.New("inner failed")
+
+	t.Run("inner error rolls back only inner changes", func(t *testing.T) {
+		db := &fakeDB{}
+		err := runTx(context.Background(), db.begin, func(ctx context.Context) error {
+			exec(ctx, t, "outer before")
+			err := (&Storage{}).WithTx(ctx, TxOptions{}, func(ctx context.Context) error {
+				assert.NotSame(t, db.txs[0], (&Storage{}).conn(ctx))
+				exec(ctx, t, "inner")
+				return errInner
+			})
+			assert.ErrorIs(t, err, errInner)
+			exec(ctx, t, "outer after")
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"outer before", "outer after"}, db.committed)
+		assert.Equal(t, 1, db.begins, "nested call must use a savepoint, not a new transaction")
+	})
+
+	t.Run("inner success is committed with outer", func(t *testing.T) {
+		db := &fakeDB{}
+		err := runTx(context.Background(), db.begin, func(ctx context.Context) error {
+			exec(ctx, t, "outer")
+			return (&Storage{}).WithTx(ctx, TxOptions{}, func(ctx context.Context) error {
+				exec(ctx, t, "inner")
+				return nil
+			})
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"outer", "inner"}, db.committed)
+	})
+
+	t.Run("outer error rolls back inner changes", func(t *testing.T) {
+		errOuter := errors.New("outer failed")
+		db := &fakeDB{}
+		err := runTx(context.Background(), db.begin, func(ctx context.Context) error {
+			err := (&Storage{}).WithTx(ctx, TxOptions{}, func(ctx context.Context) error {
+				exec(ctx, t, "inner")
+				return nil
+			})
+			require.NoError(t, err)
+			return errOuter
+		})
+
+		assert.ErrorIs(t, err, errOuter)
+		assert.Empty(t, db.committed)
+	})
+}
+
+func TestRetryTx(t *testing.T) {
+	serialization := &pgconn.PgError{Code: "40001"}
+	deadlock := &pgconn.PgError{Code: "40P01"}
+
+	// failing возвращает fn, которая пишет номер попытки и падает с errs[i] на i-й попытке.
+	failing := func(t *testing.T, errs ...error) (func(ctx context.Context) error, *int) {
+		calls := 0
+		return func(ctx context.Context) error {
+			calls++
+			exec(ctx, t, fmt.Sprintf("attempt %d", calls))
+			if calls <= len(errs) {
+				return errs[calls-1]
+			}
+			return nil
+		}, &calls
+	}
+
+	t.Run("retries serialization failure and deadlock", func(t *testing.T) {
+		db := &fakeDB{}
+		fn, calls := failing(t, serialization, deadlock)
+
+		err := retryTx(context.Background(), 3, db.begin, fn)
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, *calls)
+		assert.Equal(t, 3, db.begins, "each attempt must run in a new transaction")
+		assert.Equal(t, []string{"attempt 3"}, db.committed)
+	})
+
+	t.Run("retries commit serialization failure", func(t *testing.T) {
+		db := &fakeDB{commitErrs: []error{serialization}}
+		fn, calls := failing(t)
+
+		err := retryTx(context.Background(), 3, db.begin, fn)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, *calls)
+		assert.Equal(t, []string{"attempt 2"}, db.committed)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		db := &fakeDB{}
+		fn, calls := failing(t, serialization, serialization, serialization)
+
+		err := retryTx(context.Background(), 2, db.begin, fn)
+
+		assert.ErrorIs(t, err, serialization)
+		assert.Equal(t, 2, *calls)
+		assert.Empty(t, db.committed)
+	})
+
+	t.Run("default max attempts", func(t *testing.T) {
+		db := &fakeDB{}
+		fn, calls := failing(t, deadlock, deadlock, deadlock, deadlock)
+
+		err := retryTx(context.Background(), 0, db.begin, fn)
+
+		assert.ErrorIs(t, err, deadlock)
+		assert.Equal(t, defaultTxMaxAttempts, *calls)
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		db := &fakeDB{}
+		fn, calls := failing(t, &pgconn.PgError{Code: "23505"})
+
+		err := retryTx(context.Background(), 3, db.begin, fn)
+
+		assert.Error(t, err)
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("stops when context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		db := &fakeDB{}
+		calls := 0
+		fn := func(context.Context) error {
+			calls++
+			cancel()
+			return serialization
+		}
+
+		err := retryTx(ctx, 3, db.begin, fn)
+
+		assert.ErrorIs(t, err, serialization)
+		assert.Equal(t, 1, calls)
+	})
+}
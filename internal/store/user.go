@@ -4,12 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 
 	"github.com/Fisher-Development/woman-app-backend/internal/models"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
 )
 
 var (
@@ -18,25 +18,29 @@ var (
 	ErrEmailAlreadyExists = errors.New("email already exists")
 )
 
+// Ограничения уникальности таблицы users (имена по умолчанию из 001_initial_schema).
+const (
+	usersPrimaryKey     = "users_pkey"
+	usersEmailUniqueKey = "users_email_key"
+)
+
 // CreateUser creates a new user.
 func (s *Storage) CreateUser(ctx context.Context, user *models.User) error {
 	query := `
-		INSERT INTO users (id, email, first_name)
-		VALUES ($1, $2, $3)
+		INSERT INTO users (id, email, first_name, last_name)
+		VALUES ($1, $2, $3, $4)
 	`
-	_, err := s.db.Exec(ctx, query, user.UUID, user.Email, user.FirstName)
+	_, err := s.conn(ctx).Exec(ctx, query, user.UUID, user.Email, user.FirstName, user.LastName)
 	if err != nil {
-		// Проверяем на ошибку дублирования UUID/email
+		// Проверяем на ошибку дублирования UUID/email. Detail содержит значения ключа,
+		// поэтому нарушенное ограничение определяется только по его имени
 		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			switch pgErr.Code {
-			case "23505": // unique_violation
-				if strings.Contains(pgErr.Detail, "id") {
-					return ErrUserAlreadyExists
-				}
-				if strings.Contains(pgErr.Detail, "email") {
-					return ErrEmailAlreadyExists
-				}
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			switch pgErr.ConstraintName {
+			case usersPrimaryKey:
+				return ErrUserAlreadyExists
+			case usersEmailUniqueKey:
+				return ErrEmailAlreadyExists
 			}
 		}
 		return err
@@ -56,12 +60,13 @@ func (s *Storage) GetUserByUUID(ctx context.Context, uuid string) (*models.User,
 			city, 
 			country, 
 			date_of_birth, 
+			email_verified_at, 
 			created_at, 
 			updated_at
 		FROM users
 		WHERE id = $1
 	`
-	row := s.db.QueryRow(ctx, query, uuid)
+	row := s.conn(ctx).QueryRow(ctx, query, uuid)
 	var user models.User
 	var birthDateStr, lastNameStr, sexStr, cityStr, countryStr sql.NullString
 
@@ -74,6 +79,7 @@ func (s *Storage) GetUserByUUID(ctx context.Context, uuid string) (*models.User,
 		&cityStr,
 		&countryStr,
 		&birthDateStr,
+		&user.EmailVerifiedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -105,13 +111,9 @@ func (s *Storage) GetUserByUUID(ctx context.Context, uuid string) (*models.User,
 }
 
 // UpdateUser updates a user profile.
+// Проверка существования и обновление выполняются одним запросом, чтобы пользователь
+// не мог быть удален между ними.
 func (s *Storage) UpdateUser(ctx context.Context, user *models.User) error {
-	//проверяем есть ли пользователь в базе данных
-	_, err := s.GetUserByUUID(ctx, user.UUID)
-	if err != nil {
-		return err
-	}
-
 	// Подготавливаем birthDate для БД
 	var birthDate any
 	if user.BirthDate == "" {
@@ -132,7 +134,7 @@ func (s *Storage) UpdateUser(ctx context.Context, user *models.User) error {
 			date_of_birth = $6
 		WHERE id = $7
 	`
-	_, err = s.db.Exec(
+	tag, err := s.conn(ctx).Exec(
 		ctx,
 		query,
 		user.FirstName,
@@ -143,5 +145,119 @@ func (s *Storage) UpdateUser(ctx context.Context, user *models.User) error {
 		birthDate,
 		user.UUID,
 	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// ListUsers returns a page of users ordered by UUID, starting after afterUUID.
+func (s *Storage) ListUsers(ctx context.Context, afterUUID string, limit int) ([]models.User, error) {
+	query := `
+		SELECT 
+			id, 
+			email, 
+			first_name, 
+			last_name, 
+			keycloak_missing_since
+		FROM users
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+	if afterUUID == "" {
+		afterUUID = types.UserIDNil.String()
+	}
+	rows, err := s.conn(ctx).Query(ctx, query, afterUUID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		var firstNameStr, lastNameStr sql.NullString
+		if err := rows.Scan(
+			&user.UUID,
+			&user.Email,
+			&firstNameStr,
+			&lastNameStr,
+			&user.KeycloakMissingSince,
+		); err != nil {
+			return nil, err
+		}
+		user.FirstName = firstNameStr.String
+		user.LastName = lastNameStr.String
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// SyncUserIdentity updates email and names of a user from Keycloak.
+func (s *Storage) SyncUserIdentity(ctx context.Context, user *models.User) error {
+	query := `
+		UPDATE users
+		SET 
+			email = $1, 
+			first_name = $2, 
+			last_name = $3
+		WHERE id = $4
+	`
+	tag, err := s.conn(ctx).Exec(ctx, query, user.Email, user.FirstName, user.LastName, user.UUID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrEmailAlreadyExists
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// MarkKeycloakMissing flags (or unflags) a user that has no Keycloak account.
+func (s *Storage) MarkKeycloakMissing(ctx context.Context, uuid string, missing bool) error {
+	query := `
+		UPDATE users
+		SET keycloak_missing_since = CASE
+			WHEN $1 THEN COALESCE(keycloak_missing_since, CURRENT_TIMESTAMP)
+			ELSE NULL
+		END
+		WHERE id = $2
+	`
+	_, err := s.conn(ctx).Exec(ctx, query, missing, uuid)
 	return err
 }
+
+// MarkEmailVerified records the time a user's email was first seen as verified.
+// Already verified users are not updated, so updated_at is not bumped on every login.
+func (s *Storage) MarkEmailVerified(ctx context.Context, uuid string) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND email_verified_at IS NULL
+	`
+	tag, err := s.conn(ctx).Exec(ctx, query, uuid)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	var exists bool
+	err = s.conn(ctx).QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, uuid).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+	return nil
+}
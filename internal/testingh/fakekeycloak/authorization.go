@@ -0,0 +1,90 @@
+package fakekeycloak
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+)
+
+// authCodeLifetime время жизни authorization code, как в Keycloak по умолчанию.
+const authCodeLifetime = time.Minute
+
+// authCode выданный, но еще не обмененный authorization code.
+type authCode struct {
+	userID        string
+	redirectURI   string
+	codeChallenge string
+	expiresAt     time.Time
+}
+
+// authorize заменяет форму входа Keycloak: пользователь определяется по login_hint
+// и сразу возвращается на redirect_uri с code или с error=access_denied.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Invalid parameter: redirect_uri")
+		return
+	}
+	if query.Get("client_id") != s.clientID {
+		writeOAuthError(w, http.StatusBadRequest, "unauthorized_client", "Client not found")
+		return
+	}
+	if query.Get("response_type") != "code" {
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_response_type", "Invalid parameter: response_type")
+		return
+	}
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != keycloakclient.CodeChallengeMethodS256 {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Missing parameter: code_challenge_method")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	params := url.Values{"state": {query.Get("state")}}
+	user := s.findUserByLogin(query.Get("login_hint"))
+	if user == nil || !user.Enabled {
+		params.Set("error", "access_denied")
+		params.Set("error_description", "User not found or disabled")
+	} else {
+		code := randomToken()
+		s.authCodes[code] = authCode{
+			userID:        user.ID,
+			redirectURI:   redirectURI.String(),
+			codeChallenge: query.Get("code_challenge"),
+			expiresAt:     s.now().Add(authCodeLifetime),
+		}
+		params.Set("code", code)
+	}
+
+	location := *redirectURI
+	location.RawQuery = params.Encode()
+	http.Redirect(w, r, location.String(), http.StatusFound)
+}
+
+// redeemCode проверяет authorization code, redirect_uri и PKCE. Код одноразовый. Вызывается под mu.
+func (s *Server) redeemCode(w http.ResponseWriter, r *http.Request) (*User, bool) {
+	code := r.PostForm.Get("code")
+	issued, ok := s.authCodes[code]
+	delete(s.authCodes, code)
+
+	switch {
+	case !ok || !s.now().Before(issued.expiresAt):
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Code not valid")
+	case issued.redirectURI != r.PostForm.Get("redirect_uri"):
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Incorrect redirect_uri")
+	case r.PostForm.Get("code_verifier") == "":
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "PKCE code verifier not specified")
+	case keycloakclient.CodeChallenge(r.PostForm.Get("code_verifier")) != issued.codeChallenge:
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "PKCE verification failed: Code mismatch")
+	case s.users[issued.userID] == nil:
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "User not found")
+	default:
+		return s.users[issued.userID], true
+	}
+	return nil, false
+}
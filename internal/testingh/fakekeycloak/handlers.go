@@ -0,0 +1,570 @@
+package fakekeycloak
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+)
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	oidc := "/realms/{realm}/protocol/openid-connect"
+	mux.Handle("GET /realms/{realm}/.well-known/openid-configuration", s.endpoint(EndpointDiscovery, s.discovery))
+	mux.Handle("GET "+oidc+"/certs", s.endpoint(EndpointJWKS, s.certs))
+	mux.Handle("GET "+oidc+"/auth", s.endpoint(EndpointAuthorize, s.authorize))
+	mux.Handle("POST "+oidc+"/token", s.endpoint(EndpointToken, s.token))
+	mux.Handle("POST "+oidc+"/token/introspect", s.endpoint(EndpointIntrospect, s.introspect))
+	mux.Handle("POST "+oidc+"/logout", s.endpoint(EndpointLogout, s.logout))
+	mux.Handle("POST "+oidc+"/revoke", s.endpoint(EndpointRevoke, s.revoke))
+	mux.Handle("GET "+oidc+"/userinfo", s.endpoint(EndpointUserinfo, s.userinfo))
+
+	users := "/admin/realms/{realm}/users"
+	mux.Handle("POST "+users, s.admin(s.createUser))
+	mux.Handle("GET "+users, s.admin(s.listUsers))
+	mux.Handle("GET "+users+"/{id}", s.admin(s.getUser))
+	mux.Handle("PUT "+users+"/{id}", s.admin(s.updateUser))
+	mux.Handle("DELETE "+users+"/{id}", s.admin(s.deleteUser))
+	mux.Handle("PUT "+users+"/{id}/reset-password", s.admin(s.resetPassword))
+	mux.Handle("PUT "+users+"/{id}/execute-actions-email", s.admin(s.executeActionsEmail))
+	mux.Handle("POST "+users+"/{id}/logout", s.admin(s.logoutUser))
+	mux.Handle("GET "+users+"/{id}/sessions", s.admin(s.userSessions))
+	mux.Handle("GET "+users+"/{id}/role-mappings/realm", s.admin(s.realmRoleMappings))
+	mux.Handle("POST "+users+"/{id}/role-mappings/realm", s.admin(s.realmRoleMappings))
+	mux.Handle("DELETE "+users+"/{id}/role-mappings/realm", s.admin(s.realmRoleMappings))
+	mux.Handle("GET "+users+"/{id}/role-mappings/realm/composite", s.admin(s.effectiveRealmRoles))
+	mux.Handle("GET "+users+"/{id}/role-mappings/clients/{client}", s.admin(s.clientRoleMappings))
+	mux.Handle("POST "+users+"/{id}/role-mappings/clients/{client}", s.admin(s.clientRoleMappings))
+	mux.Handle("DELETE "+users+"/{id}/role-mappings/clients/{client}", s.admin(s.clientRoleMappings))
+
+	mux.Handle("GET /admin/realms/{realm}/roles/{role}", s.admin(s.getRealmRole))
+	mux.Handle("GET /admin/realms/{realm}/clients", s.admin(s.listClients))
+	mux.Handle("GET /admin/realms/{realm}/clients/{client}/roles/{role}", s.admin(s.getClientRole))
+
+	return mux
+}
+
+// endpoint учитывает запрос, применяет задержку и внедренные ошибки.
+func (s *Server) endpoint(endpoint Endpoint, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[endpoint]++
+		latency := s.latency
+		var fault *Fault
+		if f, ok := s.faults[endpoint]; ok {
+			copied := *f
+			fault = &copied
+			if f.Times > 0 {
+				if f.Times--; f.Times == 0 {
+					delete(s.faults, endpoint)
+				}
+			}
+		}
+		s.mu.Unlock()
+
+		if latency > 0 {
+			select {
+			case <-time.After(latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if fault != nil {
+			if fault.Drop {
+				dropConnection(w)
+				return
+			}
+			if json.Valid([]byte(fault.Body)) {
+				w.Header().Set("Content-Type", "application/json")
+			}
+			w.WriteHeader(fault.Status)
+			_, _ = w.Write([]byte(fault.Body))
+			return
+		}
+
+		if r.PathValue("realm") != s.realm {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "Realm does not exist"})
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+// admin проверяет bearer токен service account перед вызовом Admin API.
+func (s *Server) admin(next http.HandlerFunc) http.Handler {
+	return s.endpoint(EndpointAdminUsers, func(w http.ResponseWriter, r *http.Request) {
+		raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		s.mu.Lock()
+		claims, err := s.parseAccessToken(raw)
+		s.mu.Unlock()
+
+		if !ok || err != nil || claims.ClientID == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "HTTP 401 Unauthorized"})
+			return
+		}
+		next(w, r)
+	})
+}
+
+func (s *Server) discovery(w http.ResponseWriter, _ *http.Request) {
+	oidc := s.Issuer() + "/protocol/openid-connect"
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                s.Issuer(),
+		"authorization_endpoint":                oidc + "/auth",
+		"token_endpoint":                        oidc + "/token",
+		"introspection_endpoint":                oidc + "/token/introspect",
+		"userinfo_endpoint":                     oidc + "/userinfo",
+		"end_session_endpoint":                  oidc + "/logout",
+		"revocation_endpoint":                   oidc + "/revoke",
+		"jwks_uri":                              oidc + "/certs",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"grant_types_supported":                 []string{"authorization_code", "refresh_token", "password", "client_credentials"},
+	})
+}
+
+func (s *Server) certs(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.JWKS())
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if !s.authenticateClient(r) {
+		writeOAuthError(w, http.StatusUnauthorized, "unauthorized_client", "Invalid client or Invalid client credentials")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var user *User
+	var sessionID string
+	switch grantType := r.PostForm.Get("grant_type"); grantType {
+	case "password":
+		user = s.findUserByLogin(r.PostForm.Get("username"))
+		if user == nil || user.Password != r.PostForm.Get("password") {
+			writeOAuthError(w, http.StatusUnauthorized, "invalid_grant", "Invalid user credentials")
+			return
+		}
+	case "authorization_code":
+		var ok bool
+		if user, ok = s.redeemCode(w, r); !ok {
+			return
+		}
+	case "refresh_token":
+		sess, ok := s.sessions[r.PostForm.Get("refresh_token")]
+		if !ok {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid refresh token")
+			return
+		}
+		// Refresh token одноразовый: старый удаляется, новый выдается в рамках той же сессии
+		delete(s.sessions, r.PostForm.Get("refresh_token"))
+		sessionID = sess.id
+		user = s.users[sess.userID]
+		if user == nil {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid refresh token")
+			return
+		}
+	case "client_credentials":
+		user = s.serviceAccount()
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "Unsupported grant_type")
+		return
+	}
+
+	if !user.Enabled {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Account disabled")
+		return
+	}
+	if user.TemporaryPassword {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Account is not fully set up")
+		return
+	}
+
+	resp, err := s.issueTokens(user, sessionID)
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) introspect(w http.ResponseWriter, r *http.Request) {
+	if !s.authenticateClient(r) {
+		writeOAuthError(w, http.StatusUnauthorized, "unauthorized_client", "Invalid client or Invalid client credentials")
+		return
+	}
+
+	s.mu.Lock()
+	claims, err := s.parseAccessToken(r.PostForm.Get("token"))
+	s.mu.Unlock()
+
+	if err != nil {
+		writeJSON(w, http.StatusOK, map[string]any{"active": false})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"active":         true,
+		"exp":            claims.ExpiresAt,
+		"iat":            claims.IssuedAt,
+		"jti":            claims.Id,
+		"iss":            claims.Issuer,
+		"aud":            claims.Audience,
+		"sub":            claims.Subject,
+		"typ":            claims.Type,
+		"azp":            claims.AuthorizedParty,
+		"client_id":      s.clientID,
+		"username":       claims.PreferredUsername,
+		"scope":          claims.Scope,
+		"session_state":  claims.SessionState,
+		"email_verified": claims.EmailVerified,
+		"token_type":     "Bearer",
+	})
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	if !s.authenticateClient(r) {
+		writeOAuthError(w, http.StatusUnauthorized, "unauthorized_client", "Invalid client or Invalid client credentials")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[r.PostForm.Get("refresh_token")]
+	if !ok {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid refresh token")
+		return
+	}
+	delete(s.sessions, r.PostForm.Get("refresh_token"))
+	s.revokedSessions[sess.id] = true
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) revoke(w http.ResponseWriter, r *http.Request) {
+	if !s.authenticateClient(r) {
+		writeOAuthError(w, http.StatusUnauthorized, "unauthorized_client", "Invalid client or Invalid client credentials")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token := r.PostForm.Get("token")
+	if sess, ok := s.sessions[token]; ok {
+		delete(s.sessions, token)
+		s.revokedSessions[sess.id] = true
+	} else if claims, err := s.parseAccessToken(token); err == nil {
+		s.revokedTokens[claims.Id] = true
+	}
+
+	// По RFC 7009 ответ не зависит от того, был ли токен действителен
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) userinfo(w http.ResponseWriter, r *http.Request) {
+	raw, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	claims, err := s.parseAccessToken(raw)
+	if err != nil {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_token", "Token verification failed")
+		return
+	}
+	user, ok := s.users[claims.Subject]
+	if !ok {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_token", "User not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, keycloakclient.UserInfo{
+		Subject:           user.ID,
+		Email:             user.Email,
+		EmailVerified:     user.EmailVerified,
+		PreferredUsername: user.Username,
+		Name:              strings.TrimSpace(user.FirstName + " " + user.LastName),
+		GivenName:         user.FirstName,
+		FamilyName:        user.LastName,
+	})
+}
+
+// userRepresentation тело запросов создания и обновления пользователя.
+type userRepresentation struct {
+	Username      *string                                       `json:"username"`
+	Email         *string                                       `json:"email"`
+	FirstName     *string                                       `json:"firstName"`
+	LastName      *string                                       `json:"lastName"`
+	Enabled       *bool                                         `json:"enabled"`
+	EmailVerified *bool                                         `json:"emailVerified"`
+	Credentials   []keycloakclient.UserCredentialRepresentation `json:"credentials"`
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	var req userRepresentation
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAdminError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := &User{ID: newID(), CreatedTimestamp: s.now().UnixMilli()}
+	applyRepresentation(user, req)
+	if user.Username == "" {
+		user.Username = strings.ToLower(user.Email)
+	}
+
+	for _, existing := range s.users {
+		if strings.EqualFold(existing.Username, user.Username) {
+			writeAdminError(w, http.StatusConflict, "User exists with same username")
+			return
+		}
+		if user.Email != "" && strings.EqualFold(existing.Email, user.Email) {
+			writeAdminError(w, http.StatusConflict, "User exists with same email")
+			return
+		}
+	}
+	s.users[user.ID] = user
+
+	w.Header().Set("Location", fmt.Sprintf("%s/admin/realms/%s/users/%s", s.BasePath(), s.realm, user.ID))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	first, _ := strconv.Atoi(query.Get("first"))
+	limit, err := strconv.Atoi(query.Get("max"))
+	if err != nil {
+		limit = 100
+	}
+	exact := query.Get("exact") == "true"
+
+	s.mu.Lock()
+	users := make([]*User, 0, len(s.users))
+	for _, user := range s.users {
+		if user.ServiceAccountClientID != "" {
+			continue
+		}
+		if !matches(user.Email, query.Get("email"), exact) || !matches(user.Username, query.Get("username"), exact) {
+			continue
+		}
+		users = append(users, user)
+	}
+	// Стабильный порядок для постраничного чтения
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].CreatedTimestamp != users[j].CreatedTimestamp {
+			return users[i].CreatedTimestamp < users[j].CreatedTimestamp
+		}
+		return users[i].ID < users[j].ID
+	})
+
+	result := make([]keycloakclient.UserRepresentation, 0, limit)
+	for i := first; i < len(users) && len(result) < limit; i++ {
+		result = append(result, representation(users[i]))
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	s.withUser(w, r, func(user *User) {
+		writeJSON(w, http.StatusOK, representation(user))
+	})
+}
+
+func (s *Server) updateUser(w http.ResponseWriter, r *http.Request) {
+	var req userRepresentation
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAdminError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	s.withUser(w, r, func(user *User) {
+		applyRepresentation(user, req)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request) {
+	s.withUser(w, r, func(user *User) {
+		s.revokeUserSessions(user.ID)
+		delete(s.users, user.ID)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (s *Server) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var credential keycloakclient.UserCredentialRepresentation
+	if err := json.NewDecoder(r.Body).Decode(&credential); err != nil || credential.Value == "" {
+		writeAdminError(w, http.StatusBadRequest, "Invalid credential")
+		return
+	}
+
+	s.withUser(w, r, func(user *User) {
+		user.Password = credential.Value
+		user.TemporaryPassword = credential.Temporary
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (s *Server) executeActionsEmail(w http.ResponseWriter, r *http.Request) {
+	var actions []string
+	if err := json.NewDecoder(r.Body).Decode(&actions); err != nil {
+		writeAdminError(w, http.StatusBadRequest, "Invalid actions")
+		return
+	}
+
+	s.withUser(w, r, func(user *User) {
+		if user.Email == "" {
+			writeAdminError(w, http.StatusBadRequest, "User email missing")
+			return
+		}
+		user.ActionEmails = append(user.ActionEmails, actions...)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (s *Server) logoutUser(w http.ResponseWriter, r *http.Request) {
+	s.withUser(w, r, func(user *User) {
+		s.revokeUserSessions(user.ID)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// withUser вызывает fn под mu для пользователя из пути или отвечает 404.
+func (s *Server) withUser(w http.ResponseWriter, r *http.Request, fn func(user *User)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[r.PathValue("id")]
+	if !ok {
+		writeAdminError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	fn(user)
+}
+
+// authenticateClient проверяет учетные данные клиента из Basic Auth или формы.
+func (s *Server) authenticateClient(r *http.Request) bool {
+	if err := r.ParseForm(); err != nil {
+		return false
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	return clientID == s.clientID && clientSecret == s.clientSecret
+}
+
+// findUserByLogin ищет пользователя по username или email. Вызывается под mu.
+func (s *Server) findUserByLogin(login string) *User {
+	for _, user := range s.users {
+		if user.ServiceAccountClientID == "" &&
+			(strings.EqualFold(user.Username, login) || strings.EqualFold(user.Email, login)) {
+			return user
+		}
+	}
+	return nil
+}
+
+// serviceAccount возвращает service account клиента. Вызывается под mu.
+func (s *Server) serviceAccount() *User {
+	for _, user := range s.users {
+		if user.ServiceAccountClientID == s.clientID {
+			return user
+		}
+	}
+	return nil
+}
+
+func applyRepresentation(user *User, req userRepresentation) {
+	if req.Username != nil {
+		user.Username = *req.Username
+	}
+	if req.Email != nil {
+		user.Email = *req.Email
+	}
+	if req.FirstName != nil {
+		user.FirstName = *req.FirstName
+	}
+	if req.LastName != nil {
+		user.LastName = *req.LastName
+	}
+	if req.Enabled != nil {
+		user.Enabled = *req.Enabled
+	}
+	if req.EmailVerified != nil {
+		user.EmailVerified = *req.EmailVerified
+	}
+	for _, credential := range req.Credentials {
+		if credential.Type == "password" {
+			user.Password = credential.Value
+			user.TemporaryPassword = credential.Temporary
+		}
+	}
+}
+
+func representation(user *User) keycloakclient.UserRepresentation {
+	return keycloakclient.UserRepresentation{
+		ID:               user.ID,
+		Username:         user.Username,
+		Email:            user.Email,
+		FirstName:        user.FirstName,
+		LastName:         user.LastName,
+		Enabled:          user.Enabled,
+		EmailVerified:    user.EmailVerified,
+		CreatedTimestamp: user.CreatedTimestamp,
+	}
+}
+
+// matches сравнивает значение с фильтром запроса: точно или по подстроке, без учета регистра.
+func matches(value, filter string, exact bool) bool {
+	if filter == "" {
+		return true
+	}
+	if exact {
+		return strings.EqualFold(value, filter)
+	}
+	return strings.Contains(strings.ToLower(value), strings.ToLower(filter))
+}
+
+func dropConnection(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	_ = conn.Close()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{"error": code, "error_description": description})
+}
+
+func writeAdminError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"errorMessage": message})
+}
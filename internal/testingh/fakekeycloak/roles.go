@@ -0,0 +1,255 @@
+package fakekeycloak
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+)
+
+// WithRealmRoles регистрирует роли realm.
+func WithRealmRoles(names ...string) Option {
+	return func(s *Server) {
+		for _, name := range names {
+			s.registerRealmRole(name)
+		}
+	}
+}
+
+// WithClientRoles регистрирует клиента clientID (если его еще нет) и его роли.
+func WithClientRoles(clientID string, names ...string) Option {
+	return func(s *Server) {
+		for _, name := range names {
+			s.registerClientRole(clientID, name)
+		}
+	}
+}
+
+// registerRealmRole добавляет роль realm, если ее еще нет. Вызывается под mu или до запуска сервера.
+func (s *Server) registerRealmRole(name string) keycloakclient.RoleRepresentation {
+	if role, ok := s.realmRoles[name]; ok {
+		return role
+	}
+	role := keycloakclient.RoleRepresentation{ID: newID(), Name: name, ContainerID: s.realm}
+	s.realmRoles[name] = role
+	return role
+}
+
+// registerClient добавляет клиента и возвращает его внутренний ID. Вызывается под mu или до запуска сервера.
+func (s *Server) registerClient(clientID string) string {
+	if id, ok := s.clients[clientID]; ok {
+		return id
+	}
+	id := newID()
+	s.clients[clientID] = id
+	s.clientRoles[id] = make(map[string]keycloakclient.RoleRepresentation)
+	return id
+}
+
+// registerClientRole добавляет роль клиента. Вызывается под mu или до запуска сервера.
+func (s *Server) registerClientRole(clientID, name string) keycloakclient.RoleRepresentation {
+	id := s.registerClient(clientID)
+	if role, ok := s.clientRoles[id][name]; ok {
+		return role
+	}
+	role := keycloakclient.RoleRepresentation{ID: newID(), Name: name, ClientRole: true, ContainerID: id}
+	s.clientRoles[id][name] = role
+	return role
+}
+
+// registerUserRoles регистрирует роли, назначенные пользователю при добавлении. Вызывается под mu.
+func (s *Server) registerUserRoles(user *User) {
+	for _, name := range user.RealmRoles {
+		s.registerRealmRole(name)
+	}
+	for clientID, names := range user.ClientRoles {
+		for _, name := range names {
+			s.registerClientRole(clientID, name)
+		}
+	}
+}
+
+// clientIDByUUID возвращает clientId по внутреннему ID клиента. Вызывается под mu.
+func (s *Server) clientIDByUUID(id string) (string, bool) {
+	for clientID, clientUUID := range s.clients {
+		if clientUUID == id {
+			return clientID, true
+		}
+	}
+	return "", false
+}
+
+func (s *Server) listClients(w http.ResponseWriter, r *http.Request) {
+	filter := r.URL.Query().Get("clientId")
+
+	s.mu.Lock()
+	clients := make([]keycloakclient.ClientRepresentation, 0, len(s.clients))
+	for clientID, id := range s.clients {
+		if filter == "" || filter == clientID {
+			clients = append(clients, keycloakclient.ClientRepresentation{ID: id, ClientID: clientID, Enabled: true})
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, clients)
+}
+
+func (s *Server) getRealmRole(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	role, ok := s.realmRoles[r.PathValue("role")]
+	s.mu.Unlock()
+
+	if !ok {
+		writeAdminError(w, http.StatusNotFound, "Could not find role")
+		return
+	}
+	writeJSON(w, http.StatusOK, role)
+}
+
+func (s *Server) getClientRole(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	role, ok := s.clientRoles[r.PathValue("client")][r.PathValue("role")]
+	s.mu.Unlock()
+
+	if !ok {
+		writeAdminError(w, http.StatusNotFound, "Could not find role")
+		return
+	}
+	writeJSON(w, http.StatusOK, role)
+}
+
+func (s *Server) realmRoleMappings(w http.ResponseWriter, r *http.Request) {
+	s.withUser(w, r, func(user *User) {
+		switch r.Method {
+		case http.MethodGet:
+			roles := make([]keycloakclient.RoleRepresentation, 0, len(user.RealmRoles))
+			for _, name := range user.RealmRoles {
+				roles = append(roles, s.registerRealmRole(name))
+			}
+			writeJSON(w, http.StatusOK, roles)
+		default:
+			names, ok := decodeRoles(w, r, func(role keycloakclient.RoleRepresentation) bool {
+				return s.realmRoles[role.Name].ID == role.ID
+			})
+			if ok {
+				user.RealmRoles = changeRoles(user.RealmRoles, names, r.Method == http.MethodPost)
+				w.WriteHeader(http.StatusNoContent)
+			}
+		}
+	})
+}
+
+// effectiveRealmRoles возвращает роли realm с ролями по умолчанию, как и в realm_access токена.
+func (s *Server) effectiveRealmRoles(w http.ResponseWriter, r *http.Request) {
+	s.withUser(w, r, func(user *User) {
+		names := append([]string{"offline_access"}, user.RealmRoles...)
+		roles := make([]keycloakclient.RoleRepresentation, 0, len(names))
+		for _, name := range names {
+			roles = append(roles, s.registerRealmRole(name))
+		}
+		writeJSON(w, http.StatusOK, roles)
+	})
+}
+
+func (s *Server) clientRoleMappings(w http.ResponseWriter, r *http.Request) {
+	s.withUser(w, r, func(user *User) {
+		clientUUID := r.PathValue("client")
+		clientID, ok := s.clientIDByUUID(clientUUID)
+		if !ok {
+			writeAdminError(w, http.StatusNotFound, "Client not found")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			roles := make([]keycloakclient.RoleRepresentation, 0, len(user.ClientRoles[clientID]))
+			for _, name := range user.ClientRoles[clientID] {
+				roles = append(roles, s.registerClientRole(clientID, name))
+			}
+			writeJSON(w, http.StatusOK, roles)
+		default:
+			names, ok := decodeRoles(w, r, func(role keycloakclient.RoleRepresentation) bool {
+				return s.clientRoles[clientUUID][role.Name].ID == role.ID
+			})
+			if ok {
+				if user.ClientRoles == nil {
+					user.ClientRoles = make(map[string][]string)
+				}
+				user.ClientRoles[clientID] = changeRoles(user.ClientRoles[clientID], names, r.Method == http.MethodPost)
+				w.WriteHeader(http.StatusNoContent)
+			}
+		}
+	})
+}
+
+func (s *Server) userSessions(w http.ResponseWriter, r *http.Request) {
+	s.withUser(w, r, func(user *User) {
+		// Один session id может встречаться у нескольких refresh token
+		byID := make(map[string]keycloakclient.UserSessionRepresentation)
+		for _, sess := range s.sessions {
+			if sess.userID != user.ID {
+				continue
+			}
+			byID[sess.id] = keycloakclient.UserSessionRepresentation{
+				ID:         sess.id,
+				Username:   user.Username,
+				UserID:     user.ID,
+				IPAddress:  "127.0.0.1",
+				Start:      sess.started.UnixMilli(),
+				LastAccess: sess.started.UnixMilli(),
+				Clients:    map[string]string{s.clients[s.clientID]: s.clientID},
+			}
+		}
+
+		sessions := make([]keycloakclient.UserSessionRepresentation, 0, len(byID))
+		for _, sess := range byID {
+			sessions = append(sessions, sess)
+		}
+		slices.SortFunc(sessions, func(a, b keycloakclient.UserSessionRepresentation) int {
+			return int(a.Start - b.Start)
+		})
+		writeJSON(w, http.StatusOK, sessions)
+	})
+}
+
+// decodeRoles читает список ролей из тела запроса; роли должны существовать с теми же ID.
+func decodeRoles(
+	w http.ResponseWriter,
+	r *http.Request,
+	known func(role keycloakclient.RoleRepresentation) bool,
+) ([]string, bool) {
+	var roles []keycloakclient.RoleRepresentation
+	if err := json.NewDecoder(r.Body).Decode(&roles); err != nil {
+		writeAdminError(w, http.StatusBadRequest, "Invalid roles")
+		return nil, false
+	}
+
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if !known(role) {
+			writeAdminError(w, http.StatusNotFound, "Role not found")
+			return nil, false
+		}
+		names = append(names, role.Name)
+	}
+	return names, true
+}
+
+// changeRoles добавляет или удаляет роли из списка без дубликатов.
+func changeRoles(current, names []string, add bool) []string {
+	result := make([]string, 0, len(current)+len(names))
+	for _, name := range current {
+		if add || !slices.Contains(names, name) {
+			result = append(result, name)
+		}
+	}
+	if add {
+		for _, name := range names {
+			if !slices.Contains(result, name) {
+				result = append(result, name)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,329 @@
+// Package fakekeycloak реализует in-process Keycloak на httptest для тестов без сети.
+//
+// Поддерживаются authorization endpoint (вход по login_hint без формы),
+// token endpoint (authorization_code с PKCE, password, refresh_token, client_credentials),
+// introspection, logout, revocation, userinfo, discovery и JWKS со сгенерированным
+// RSA ключом, а также эндпоинты Admin API для пользователей, их сессий и ролей.
+// Ошибки и задержки внедряются через Fail и SetLatency.
+package fakekeycloak
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+const (
+	DefaultRealm        = "Testing"
+	DefaultClientID     = "back-end"
+	DefaultClientSecret = "secret"
+
+	defaultTokenLifetime = 5 * time.Minute
+	signingKeyID         = "fake-rsa-key"
+)
+
+// Endpoint группа эндпоинтов, для которой можно внедрить ошибку или посчитать запросы.
+type Endpoint string
+
+const (
+	EndpointDiscovery  Endpoint = "discovery"
+	EndpointAuthorize  Endpoint = "authorize"
+	EndpointJWKS       Endpoint = "jwks"
+	EndpointToken      Endpoint = "token"
+	EndpointIntrospect Endpoint = "introspect"
+	EndpointLogout     Endpoint = "logout"
+	EndpointRevoke     Endpoint = "revoke"
+	EndpointUserinfo   Endpoint = "userinfo"
+	EndpointAdminUsers Endpoint = "admin_users"
+)
+
+// Fault ошибка, которую сервер вернет вместо обычного ответа.
+type Fault struct {
+	// Status HTTP статус ответа (по умолчанию 500).
+	Status int
+	// Body тело ответа (по умолчанию пустое).
+	Body string
+	// Drop закрывает соединение без ответа, имитируя сетевую ошибку.
+	Drop bool
+	// Times сколько запросов затронет ошибка (0 - пока не будет вызван ClearFaults).
+	Times int
+}
+
+// User пользователь realm.
+type User struct {
+	ID            string
+	Username      string
+	Email         string
+	FirstName     string
+	LastName      string
+	Password      string
+	Enabled       bool
+	EmailVerified bool
+	// TemporaryPassword пароль установлен администратором как временный.
+	TemporaryPassword bool
+	RealmRoles        []string
+	// ClientRoles роли клиентов: clientId -> имена ролей.
+	ClientRoles map[string][]string
+	// ServiceAccountClientID непустой для service account пользователя клиента.
+	ServiceAccountClientID string
+	// ActionEmails действия, отправленные пользователю через execute-actions-email.
+	ActionEmails     []string
+	CreatedTimestamp int64
+}
+
+// session сессия пользователя, созданная выдачей токена.
+type session struct {
+	id      string
+	userID  string
+	started time.Time
+}
+
+// Option настраивает Server.
+type Option func(*Server)
+
+// WithRealm задает имя realm.
+func WithRealm(realm string) Option {
+	return func(s *Server) { s.realm = realm }
+}
+
+// WithClient задает учетные данные confidential клиента.
+func WithClient(clientID, clientSecret string) Option {
+	return func(s *Server) {
+		s.clientID = clientID
+		s.clientSecret = clientSecret
+	}
+}
+
+// WithPathPrefix публикует сервер под префиксом пути, как Keycloak за reverse proxy (например, "/be").
+func WithPathPrefix(prefix string) Option {
+	return func(s *Server) { s.pathPrefix = "/" + strings.Trim(prefix, "/") }
+}
+
+// WithTokenLifetime задает время жизни access token.
+func WithTokenLifetime(lifetime time.Duration) Option {
+	return func(s *Server) { s.tokenLifetime = lifetime }
+}
+
+// Server fake Keycloak. Безопасен для конкурентного использования.
+type Server struct {
+	*httptest.Server
+
+	realm         string
+	clientID      string
+	clientSecret  string
+	tokenLifetime time.Duration
+	pathPrefix    string
+	key           *rsa.PrivateKey
+
+	mu              sync.Mutex
+	now             func() time.Time
+	latency         time.Duration
+	faults          map[Endpoint]*Fault
+	requests        map[Endpoint]int
+	users           map[string]*User
+	sessions        map[string]session // refresh token -> сессия
+	revokedSessions map[string]bool
+	revokedTokens   map[string]bool // jti отозванных access token
+	authCodes       map[string]authCode
+	realmRoles      map[string]keycloakclient.RoleRepresentation
+	clients         map[string]string // clientId -> внутренний ID
+	clientRoles     map[string]map[string]keycloakclient.RoleRepresentation
+}
+
+// New запускает fake Keycloak и останавливает его по завершении теста.
+func New(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate signing key: %v", err)
+	}
+
+	s := &Server{
+		realm:           DefaultRealm,
+		clientID:        DefaultClientID,
+		clientSecret:    DefaultClientSecret,
+		tokenLifetime:   defaultTokenLifetime,
+		key:             key,
+		now:             time.Now,
+		faults:          make(map[Endpoint]*Fault),
+		requests:        make(map[Endpoint]int),
+		users:           make(map[string]*User),
+		sessions:        make(map[string]session),
+		revokedSessions: make(map[string]bool),
+		revokedTokens:   make(map[string]bool),
+		authCodes:       make(map[string]authCode),
+		realmRoles:      make(map[string]keycloakclient.RoleRepresentation),
+		clients:         make(map[string]string),
+		clientRoles:     make(map[string]map[string]keycloakclient.RoleRepresentation),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.registerClient(s.clientID)
+	s.registerRealmRole("offline_access")
+
+	// Service account клиента, от имени которого выдаются client_credentials токены
+	s.users[newID()] = &User{
+		Username:               "service-account-" + s.clientID,
+		Enabled:                true,
+		ServiceAccountClientID: s.clientID,
+	}
+	for id, user := range s.users {
+		user.ID = id
+	}
+
+	handler := s.routes()
+	if s.pathPrefix != "" {
+		handler = http.StripPrefix(s.pathPrefix, handler)
+	}
+	s.Server = httptest.NewServer(handler)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Realm возвращает имя realm.
+func (s *Server) Realm() string { return s.realm }
+
+// ClientID возвращает идентификатор клиента.
+func (s *Server) ClientID() string { return s.clientID }
+
+// ClientSecret возвращает секрет клиента.
+func (s *Server) ClientSecret() string { return s.clientSecret }
+
+// BasePath возвращает адрес сервера с учетом префикса пути.
+func (s *Server) BasePath() string {
+	return s.URL + s.pathPrefix
+}
+
+// Issuer возвращает issuer токенов realm.
+func (s *Server) Issuer() string {
+	return fmt.Sprintf("%s/realms/%s", s.BasePath(), s.realm)
+}
+
+// NewClient создает keycloakclient.Client, настроенный на этот сервер.
+// Паузы между повторами по умолчанию сокращены, чтобы не замедлять тесты.
+func (s *Server) NewClient(t testing.TB, opts ...keycloakclient.OptOptionsSetter) *keycloakclient.Client {
+	t.Helper()
+
+	opts = append([]keycloakclient.OptOptionsSetter{
+		keycloakclient.WithRetryWaitMin(time.Millisecond),
+		keycloakclient.WithRetryWaitMax(10 * time.Millisecond),
+	}, opts...)
+	client, err := keycloakclient.New(keycloakclient.NewOptions(s.BasePath(), s.realm, s.clientID, s.clientSecret, opts...))
+	if err != nil {
+		t.Fatalf("create keycloak client: %v", err)
+	}
+	return client
+}
+
+// SetLatency задает задержку перед каждым ответом.
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency = latency
+}
+
+// Fail внедряет ошибку для группы эндпоинтов.
+func (s *Server) Fail(endpoint Endpoint, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if fault.Status == 0 {
+		fault.Status = http.StatusInternalServerError
+	}
+	s.faults[endpoint] = &fault
+}
+
+// ClearFaults убирает все внедренные ошибки.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = make(map[Endpoint]*Fault)
+}
+
+// Requests возвращает число запросов к группе эндпоинтов, включая завершившиеся ошибкой.
+func (s *Server) Requests(endpoint Endpoint) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[endpoint]
+}
+
+// AddUser добавляет пользователя и возвращает его копию с заполненным ID.
+func (s *Server) AddUser(user User) User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user.ID == "" {
+		user.ID = newID()
+	}
+	if user.Username == "" {
+		user.Username = user.Email
+		if user.ServiceAccountClientID != "" {
+			user.Username = "service-account-" + user.ServiceAccountClientID
+		}
+	}
+	if user.CreatedTimestamp == 0 {
+		user.CreatedTimestamp = s.now().UnixMilli()
+	}
+	stored := user
+	s.users[user.ID] = &stored
+	s.registerUserRoles(&stored)
+
+	return user
+}
+
+// User возвращает копию пользователя по ID.
+func (s *Server) User(id string) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return User{}, false
+	}
+	return *user, true
+}
+
+// UpdateUser изменяет пользователя под блокировкой сервера.
+func (s *Server) UpdateUser(id string, update func(user *User)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if ok {
+		update(user)
+	}
+	return ok
+}
+
+// Users возвращает копии всех пользователей, кроме service account.
+func (s *Server) Users() []User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := make([]User, 0, len(s.users))
+	for _, user := range s.users {
+		if user.ServiceAccountClientID == "" {
+			users = append(users, *user)
+		}
+	}
+	return users
+}
+
+func newID() string {
+	return types.NewUserID().String()
+}
@@ -0,0 +1,154 @@
+package fakekeycloak_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/middlewares"
+	"github.com/Fisher-Development/woman-app-backend/internal/testingh/fakekeycloak"
+	"github.com/Fisher-Development/woman-app-backend/internal/types"
+)
+
+func TestTokenGrants(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t)
+	kc.AddUser(fakekeycloak.User{Email: "jane@example.com", Password: "password123", Enabled: true})
+
+	_, err := client.LoginUser(ctx, "jane@example.com", "wrong")
+	require.Error(t, err)
+
+	tokens, err := client.LoginUser(ctx, "jane@example.com", "password123")
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	introspection, err := client.IntrospectToken(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.True(t, introspection.Active)
+
+	refreshed, err := client.RefreshAccessToken(ctx, tokens.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, tokens.SessionState, refreshed.SessionState)
+
+	// Refresh token одноразовый
+	_, err = client.RefreshAccessToken(ctx, tokens.RefreshToken)
+	require.Error(t, err)
+
+	require.NoError(t, client.Logout(ctx, refreshed.RefreshToken))
+	introspection, err = client.IntrospectToken(ctx, refreshed.AccessToken)
+	require.NoError(t, err)
+	assert.False(t, introspection.Active)
+}
+
+func TestAdminUsers(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t)
+
+	created, err := client.CreateUser(ctx, keycloakclient.CreateUserRequest{
+		Username: "jane@example.com",
+		Email:    "jane@example.com",
+		Enabled:  true,
+		Credentials: []keycloakclient.UserCredentialRepresentation{
+			{Type: "password", Value: "password123"},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = client.CreateUser(ctx, keycloakclient.CreateUserRequest{Username: "jane@example.com"})
+	require.Error(t, err, "duplicate username")
+
+	users, err := client.FindUsersByEmail(ctx, "jane@example.com")
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, created.UserID.String(), users[0].ID)
+
+	require.NoError(t, client.ResetPassword(ctx, created.UserID, "temporary1", true))
+	_, err = client.LoginUser(ctx, "jane@example.com", "temporary1")
+	require.Error(t, err, "temporary password requires update")
+
+	require.NoError(t, client.ExecuteActionsEmail(ctx, created.UserID,
+		[]string{keycloakclient.RequiredActionUpdatePassword}, time.Hour))
+	user, ok := kc.User(created.UserID.String())
+	require.True(t, ok)
+	assert.Equal(t, []string{keycloakclient.RequiredActionUpdatePassword}, user.ActionEmails)
+
+	require.NoError(t, client.DeleteUser(ctx, created.UserID))
+	require.ErrorIs(t, client.DeleteUser(ctx, created.UserID), keycloakclient.ErrUserNotFound)
+	require.ErrorIs(t, client.LogoutUser(ctx, types.NewUserID()), keycloakclient.ErrUserNotFound)
+}
+
+func TestAuthMiddlewareWithJWKS(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t)
+	user := kc.AddUser(fakekeycloak.User{Email: "jane@example.com", Enabled: true, RealmRoles: []string{"admin"}})
+
+	verifier := middlewares.NewJWKSVerifier(client, "", 0)
+	require.NoError(t, verifier.Refresh(ctx))
+	auth := middlewares.NewAuthMiddleware(client, middlewares.WithTokenVerifier(verifier))
+
+	handler := auth.RequireAuth()(middlewares.RequireRealmRole("admin")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := middlewares.GetUserFromContext(r.Context())
+			assert.True(t, ok)
+			assert.Equal(t, user.ID, userID.String())
+			w.WriteHeader(http.StatusOK)
+		}),
+	))
+
+	token, err := kc.IssueAccessToken(user.ID)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Zero(t, kc.Requests(fakekeycloak.EndpointIntrospect), "token is verified locally")
+}
+
+func TestFaultInjection(t *testing.T) {
+	ctx := context.Background()
+	kc := fakekeycloak.New(t)
+	client := kc.NewClient(t)
+	kc.AddUser(fakekeycloak.User{Email: "jane@example.com", Password: "password123", Enabled: true})
+
+	t.Run("status", func(t *testing.T) {
+		kc.Fail(fakekeycloak.EndpointToken, fakekeycloak.Fault{Status: http.StatusServiceUnavailable, Times: 1})
+
+		_, err := client.LoginUser(ctx, "jane@example.com", "password123")
+		require.Error(t, err)
+
+		_, err = client.LoginUser(ctx, "jane@example.com", "password123")
+		require.NoError(t, err)
+	})
+
+	t.Run("dropped connection", func(t *testing.T) {
+		kc.Fail(fakekeycloak.EndpointAdminUsers, fakekeycloak.Fault{Drop: true})
+		defer kc.ClearFaults()
+
+		_, err := client.ListUsers(ctx, 0, 10)
+		require.Error(t, err)
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		kc.SetLatency(time.Second)
+		defer kc.SetLatency(0)
+
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		_, err := client.LoginUser(ctx, "jane@example.com", "password123")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), context.DeadlineExceeded.Error())
+	})
+}
@@ -0,0 +1,179 @@
+package fakekeycloak
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+
+	keycloakclient "github.com/Fisher-Development/woman-app-backend/internal/clients/keycloak"
+)
+
+var (
+	errSessionRevoked = errors.New("session revoked")
+	errTokenRevoked   = errors.New("token revoked")
+)
+
+// accessTokenClaims claims access token в формате Keycloak.
+type accessTokenClaims struct {
+	jwt.StandardClaims
+	Audience          []string                       `json:"aud,omitempty"`
+	Type              string                         `json:"typ"`
+	AuthorizedParty   string                         `json:"azp"`
+	SessionState      string                         `json:"session_state,omitempty"`
+	SessionID         string                         `json:"sid,omitempty"`
+	Scope             string                         `json:"scope"`
+	RealmAccess       map[string][]string            `json:"realm_access"`
+	ResourceAccess    map[string]map[string][]string `json:"resource_access"`
+	EmailVerified     bool                           `json:"email_verified"`
+	Email             string                         `json:"email,omitempty"`
+	PreferredUsername string                         `json:"preferred_username"`
+	ClientID          string                         `json:"client_id,omitempty"`
+}
+
+// IssueAccessToken выдает подписанный access token пользователю без сессии (для тестов middleware).
+func (s *Server) IssueAccessToken(userID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return "", keycloakclient.ErrUserNotFound
+	}
+	return s.signAccessToken(user, "")
+}
+
+// JWKS возвращает публичный ключ подписи в формате JWKS.
+func (s *Server) JWKS() keycloakclient.JSONWebKeySet {
+	b64 := base64.RawURLEncoding.EncodeToString
+	return keycloakclient.JSONWebKeySet{Keys: []keycloakclient.JSONWebKey{{
+		Kid: signingKeyID,
+		Kty: "RSA",
+		Alg: "RS256",
+		Use: "sig",
+		N:   b64(s.key.N.Bytes()),
+		E:   b64(big.NewInt(int64(s.key.E)).Bytes()),
+	}}}
+}
+
+// issueTokens выдает пару токенов в рамках сессии (пустой sessionID - новая сессия). Вызывается под mu.
+func (s *Server) issueTokens(user *User, sessionID string) (*keycloakclient.TokenResponse, error) {
+	if sessionID == "" {
+		sessionID = newID()
+	}
+	accessToken, err := s.signAccessToken(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &keycloakclient.TokenResponse{
+		AccessToken:  accessToken,
+		ExpiresIn:    int(s.tokenLifetime.Seconds()),
+		TokenType:    "Bearer",
+		SessionState: sessionID,
+		Scope:        "openid profile email",
+	}
+
+	// Service account не получает refresh token, как и в Keycloak
+	if user.ServiceAccountClientID == "" {
+		resp.RefreshToken = randomToken()
+		resp.RefreshExpiresIn = int(6 * s.tokenLifetime.Seconds())
+		started := s.now()
+		if existing, ok := s.sessionStart(sessionID); ok {
+			started = existing
+		}
+		s.sessions[resp.RefreshToken] = session{id: sessionID, userID: user.ID, started: started}
+	}
+
+	return resp, nil
+}
+
+// signAccessToken подписывает access token пользователя. Вызывается под mu.
+func (s *Server) signAccessToken(user *User, sessionID string) (string, error) {
+	now := s.now()
+
+	claims := accessTokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        newID(),
+			Subject:   user.ID,
+			Issuer:    s.Issuer(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(s.tokenLifetime).Unix(),
+		},
+		Audience:        []string{"account"},
+		Type:            "Bearer",
+		AuthorizedParty: s.clientID,
+		SessionState:    sessionID,
+		SessionID:       sessionID,
+		Scope:           "openid profile email",
+		RealmAccess:     map[string][]string{"roles": append([]string{"offline_access"}, user.RealmRoles...)},
+		ResourceAccess: map[string]map[string][]string{
+			"account": {"roles": {"manage-account", "view-profile"}},
+		},
+		EmailVerified:     user.EmailVerified,
+		Email:             user.Email,
+		PreferredUsername: user.Username,
+	}
+	for clientID, roles := range user.ClientRoles {
+		claims.ResourceAccess[clientID] = map[string][]string{"roles": roles}
+	}
+	if user.ServiceAccountClientID != "" {
+		claims.AuthorizedParty = user.ServiceAccountClientID
+		claims.ClientID = user.ServiceAccountClientID
+		claims.ResourceAccess[s.clientID] = map[string][]string{"roles": {"uma_protection"}}
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = signingKeyID
+	return token.SignedString(s.key)
+}
+
+// parseAccessToken проверяет подпись и срок действия токена. Вызывается под mu.
+func (s *Server) parseAccessToken(raw string) (*accessTokenClaims, error) {
+	var claims accessTokenClaims
+	parser := jwt.Parser{ValidMethods: []string{jwt.SigningMethodRS256.Alg()}}
+	if _, err := parser.ParseWithClaims(raw, &claims, func(*jwt.Token) (any, error) {
+		return &s.key.PublicKey, nil
+	}); err != nil {
+		return nil, err
+	}
+	if claims.SessionState != "" && s.revokedSessions[claims.SessionState] {
+		return nil, errSessionRevoked
+	}
+	if s.revokedTokens[claims.Id] {
+		return nil, errTokenRevoked
+	}
+	return &claims, nil
+}
+
+// sessionStart возвращает время начала существующей сессии. Вызывается под mu.
+func (s *Server) sessionStart(sessionID string) (time.Time, bool) {
+	for _, sess := range s.sessions {
+		if sess.id == sessionID {
+			return sess.started, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// revokeUserSessions завершает все сессии пользователя. Вызывается под mu.
+func (s *Server) revokeUserSessions(userID string) {
+	for refreshToken, sess := range s.sessions {
+		if sess.userID == userID {
+			s.revokedSessions[sess.id] = true
+			delete(s.sessions, refreshToken)
+		}
+	}
+}
+
+func randomToken() string {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return strings.TrimRight(base64.RawURLEncoding.EncodeToString(b[:]), "=")
+}